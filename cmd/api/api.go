@@ -17,11 +17,16 @@ import (
 
 	"github.com/qwerqy/social-api-go/docs"
 	"github.com/qwerqy/social-api-go/internal/auth"
+	"github.com/qwerqy/social-api-go/internal/blob"
 	"github.com/qwerqy/social-api-go/internal/env"
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/linkpreview"
 	"github.com/qwerqy/social-api-go/internal/mailer"
 	"github.com/qwerqy/social-api-go/internal/ratelimiter"
 	"github.com/qwerqy/social-api-go/internal/store"
 	"github.com/qwerqy/social-api-go/internal/store/cache"
+	"github.com/qwerqy/social-api-go/internal/stream"
+	"github.com/qwerqy/social-api-go/internal/timeline"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
@@ -33,6 +38,19 @@ type application struct {
 	mailer        mailer.Client
 	authenticator auth.Authenticator
 	rateLimiter   ratelimiter.Limiter
+	blobStore     blob.Store
+	// linkPreviews queues the URLs whose previews should be fetched
+	linkPreviews   chan string
+	previewFetcher *linkpreview.Fetcher
+	// cursors signs the cursors handed out by lists paged by creation time
+	cursors *store.CursorSigner
+	// timeline keeps the home timelines, nil when Redis is disabled and the
+	// feed is always read from the database
+	timeline *timeline.Service
+	// events tells subscribers such as notifications what users did
+	events *events.Bus
+	// stream pushes what happens to the clients connected to any instance
+	stream stream.Broker
 }
 
 type config struct {
@@ -40,11 +58,37 @@ type config struct {
 	db          dbConfig
 	env         string
 	apiURL      string
+	apiScheme   string
 	mail        mailConfig
 	frontendURL string
 	auth        authConfig
 	redisCfg    redisConfig
 	rateLimiter ratelimiter.Config
+	media       mediaConfig
+	trending    trendingConfig
+	linkPreview linkPreviewConfig
+	timeline    timeline.Config
+}
+
+type mediaConfig struct {
+	dir string
+	// orphanAge is how long an upload may stay unattached before it is
+	// deleted
+	orphanAge  time.Duration
+	gcInterval time.Duration
+}
+
+type trendingConfig struct {
+	refreshInterval time.Duration
+}
+
+type linkPreviewConfig struct {
+	workers   int
+	queueSize int
+	// maxAge is how long a preview is kept before the page is fetched
+	// again, failedMaxAge the same for pages that couldn't be previewed
+	maxAge       time.Duration
+	failedMaxAge time.Duration
 }
 
 type redisConfig struct {
@@ -55,8 +99,13 @@ type redisConfig struct {
 }
 
 type authConfig struct {
-	basic basicConfig
-	token tokenConfig
+	basic  basicConfig
+	token  tokenConfig
+	cursor cursorConfig
+}
+
+type cursorConfig struct {
+	secret string
 }
 
 type tokenConfig struct {
@@ -92,11 +141,12 @@ func (app *application) mount() http.Handler {
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(app.StripAccessTokenMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:3000")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -107,54 +157,195 @@ func (app *application) mount() http.Handler {
 		r.Use(app.RateLimiterMiddleware)
 	}
 
-	r.Use(middleware.Timeout(time.Second * 60))
-
 	r.Route("/v1", func(r chi.Router) {
-		r.Get("/health", app.healthCheckHandler)
+		// Streams stay open for as long as clients listen, the timeout of
+		// the other routes would cut them off
+		r.Route("/stream", func(r chi.Router) {
+			r.Use(app.StreamAuthMiddleware)
 
-		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
-		r.Get("/swagger/*", httpSwagger.Handler(
-			httpSwagger.URL(docsURL),
-		))
+			r.Get("/", app.streamHandler)
+			r.Get("/ws", app.streamWebSocketHandler)
+		})
 
-		r.Route("/posts", func(r chi.Router) {
-			r.Use(app.AuthTokenMiddleware)
-			r.Post("/", app.createPostHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(time.Second * 60))
 
-			r.Route("/{postID}", func(r chi.Router) {
-				r.Use(app.postContextMiddleware)
+			r.Get("/health", app.healthCheckHandler)
+
+			docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
+			r.Get("/swagger/*", httpSwagger.Handler(
+				httpSwagger.URL(docsURL),
+			))
+
+			r.Route("/posts", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Post("/", app.createPostHandler)
 
-				r.Get("/", app.getPostHandler)
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
-				r.Patch("/", app.checkPostOwnership("moderator", app.patchPostHandler))
+				r.Route("/{postID}", func(r chi.Router) {
+					r.Use(app.postContextMiddleware)
 
-				r.Route("/comments", func(r chi.Router) {
-					r.Post("/", app.createCommentHandler)
+					r.Get("/", app.getPostHandler)
+					r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+					r.Patch("/", app.checkPostOwnership("moderator", app.patchPostHandler))
+
+					r.Route("/comments", func(r chi.Router) {
+						r.Get("/", app.listCommentsHandler)
+						r.Post("/", app.createCommentHandler)
+
+						r.Route("/{commentID}", func(r chi.Router) {
+							r.Use(app.commentContextMiddleware)
+
+							r.Patch("/", app.checkCommentOwnership("moderator", app.patchCommentHandler))
+							r.Delete("/", app.checkCommentOwnership("moderator", app.deleteCommentHandler))
+							r.Get("/replies", app.getCommentRepliesHandler)
+							r.Get("/edits", app.getCommentEditsHandler)
+						})
+					})
+
+					r.Post("/repost", app.repostHandler)
+					r.Delete("/repost", app.undoRepostHandler)
+					r.Post("/quote", app.quotePostHandler)
+
+					r.Put("/bookmark", app.bookmarkPostHandler)
+					r.Delete("/bookmark", app.deleteBookmarkHandler)
+
+					r.Post("/poll/votes", app.votePollHandler)
+
+					r.Put("/reaction", app.reactPostHandler)
+					r.Delete("/reaction", app.deleteReactionHandler)
+
+					r.Put("/pin", app.pinPostHandler)
+					r.Delete("/pin", app.unpinPostHandler)
 				})
+
 			})
 
-		})
+			r.Route("/tags", func(r chi.Router) {
+				// Feed readers can't authenticate, syndication feeds are public
+				r.Get("/{tag}/feed.{format}", app.getTagSyndicationHandler)
 
-		r.Route("/users", func(r chi.Router) {
-			r.Put("/activate/{token}", app.activateUserHandler)
+				r.Group(func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+
+					r.Get("/", app.searchTagsHandler)
+					r.Get("/{tag}/posts", app.getTagPostsHandler)
+				})
+			})
 
-			r.Route("/{userID}", func(r chi.Router) {
+			r.Route("/lists", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 
-				r.Get("/", app.getUserHandler)
-				r.Put("/follow", app.followUserHandler)
-				r.Put("/unfollow", app.unfollowUserHandler)
+				r.Get("/", app.getListsHandler)
+				r.Post("/", app.createListHandler)
+
+				r.Route("/{listID}", func(r chi.Router) {
+					r.Use(app.listContextMiddleware)
+
+					r.Get("/", app.getListHandler)
+					r.Patch("/", app.checkListOwnership(app.updateListHandler))
+					r.Delete("/", app.checkListOwnership(app.deleteListHandler))
+					r.Get("/feed", app.getListFeedHandler)
+					r.Get("/members", app.getListMembersHandler)
+					r.Put("/members/{userID}", app.checkListOwnership(app.addListMemberHandler))
+					r.Delete("/members/{userID}", app.checkListOwnership(app.removeListMemberHandler))
+					r.Put("/follow", app.followListHandler)
+					r.Delete("/follow", app.unfollowListHandler)
+				})
 			})
 
-			r.Group(func(r chi.Router) {
+			r.Route("/notifications", func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
-				r.Get("/feed", app.getUserFeedHandler)
+
+				r.Get("/", app.getNotificationsHandler)
+				r.Get("/unread-count", app.getUnreadNotificationCountHandler)
+				r.Put("/read-all", app.markAllNotificationsReadHandler)
+				r.Put("/{notificationID}/read", app.markNotificationReadHandler)
 			})
-		})
 
-		r.Route("/authentication", func(r chi.Router) {
-			r.Post("/user", app.registerUserHandler)
-			r.Post("/token", app.createTokenHandler)
+			r.Route("/search", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+
+				r.Get("/", app.searchHandler)
+			})
+
+			r.Route("/explore", func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+
+				r.Get("/trending", app.getTrendingHandler)
+			})
+
+			r.Route("/media", func(r chi.Router) {
+				r.Get("/files/*", app.serveMediaHandler)
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+					r.Post("/", app.uploadMediaHandler)
+				})
+			})
+
+			r.Route("/users", func(r chi.Router) {
+				r.Put("/activate/{token}", app.activateUserHandler)
+
+				r.Route("/me", func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+
+					r.Get("/mentions", app.getMentionsHandler)
+					r.Get("/settings", app.getSettingsHandler)
+					r.Patch("/settings", app.updateSettingsHandler)
+					r.Put("/pins", app.reorderPinsHandler)
+
+					r.Route("/follow-requests", func(r chi.Router) {
+						r.Get("/", app.getFollowRequestsHandler)
+						r.Put("/{userID}/approve", app.approveFollowRequestHandler)
+						r.Put("/{userID}/reject", app.rejectFollowRequestHandler)
+					})
+
+					r.Route("/saved-searches", func(r chi.Router) {
+						r.Get("/", app.getSavedSearchesHandler)
+						r.Post("/", app.createSavedSearchHandler)
+						r.Delete("/{savedSearchID}", app.deleteSavedSearchHandler)
+					})
+
+					r.Route("/muted-words", func(r chi.Router) {
+						r.Get("/", app.getMutedWordsHandler)
+						r.Post("/", app.muteWordHandler)
+						r.Delete("/{mutedWordID}", app.unmuteWordHandler)
+					})
+
+					r.Route("/bookmarks", func(r chi.Router) {
+						r.Get("/", app.getBookmarksHandler)
+						r.Get("/collections", app.getBookmarkCollectionsHandler)
+						r.Delete("/collections/{collectionID}", app.deleteBookmarkCollectionHandler)
+					})
+				})
+
+				r.Route("/{userID}", func(r chi.Router) {
+					r.Get("/feed.{format}", app.getUserSyndicationHandler)
+
+					r.Group(func(r chi.Router) {
+						r.Use(app.AuthTokenMiddleware)
+
+						r.Get("/", app.getUserHandler)
+						r.Get("/posts", app.getUserPostsHandler)
+						r.Get("/lists", app.getUserListsHandler)
+						r.Put("/follow", app.followUserHandler)
+						r.Put("/unfollow", app.unfollowUserHandler)
+						r.Put("/block", app.blockUserHandler)
+						r.Put("/unblock", app.unblockUserHandler)
+					})
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+					r.Get("/feed", app.getUserFeedHandler)
+					r.Get("/feed/ranked", app.getRankedFeedHandler)
+				})
+			})
+
+			r.Route("/authentication", func(r chi.Router) {
+				r.Post("/user", app.registerUserHandler)
+				r.Post("/token", app.createTokenHandler)
+			})
 		})
 	})
 
@@ -205,6 +396,9 @@ func (app *application) run(mux http.Handler) error {
 		return err
 	}
 
+	// Let the subscribers finish with what the last requests published
+	app.events.Wait()
+
 	app.logger.Infow("server has stopped", "addr", app.config.addr, "env", app.config.env)
 
 	return nil
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type BookmarkPostPayload struct {
+	Collection string `json:"collection" validate:"max=100"`
+}
+
+// BookmarkPost godoc
+//
+//	@Summary		Bookmarks a post
+//	@Description	Saves a post for later, optionally into a named collection
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		BookmarkPostPayload	false	"Bookmark Post Payload"
+//	@Success		200		{object}	store.Bookmark
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [put]
+func (app *application) bookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BookmarkPostPayload
+
+	// The payload is optional, an empty body bookmarks without a collection
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	// Bookmarking a repost saves the post it shares
+	post, err := app.resolveOriginal(ctx, getPostFromCtx(r), user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	bookmark := &store.Bookmark{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+
+	if err := app.store.Bookmarks.Save(ctx, bookmark, payload.Collection); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, bookmark); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteBookmark godoc
+//
+//	@Summary		Removes a bookmark
+//	@Description	Removes a post from the current user's bookmarks
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	string
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [delete]
+func (app *application) deleteBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	postID := post.ID
+	if post.Kind == store.PostKindRepost && post.OriginalID != nil {
+		postID = *post.OriginalID
+	}
+
+	if err := app.store.Bookmarks.Delete(r.Context(), user.ID, postID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetBookmarks godoc
+//
+//	@Summary		Lists bookmarks
+//	@Description	Lists the current user's bookmarks, most recently saved first
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			cursor		query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Param			tags		query		string	false	"Tags"
+//	@Param			search		query		string	false	"Full-text search, in web search syntax"
+//	@Param			collection	query		string	false	"Collection"
+//	@Success		200			{object}	[]store.Bookmark
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmarks [get]
+func (app *application) getBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	bq := store.BookmarkQuery{
+		KeysetPaginatedQuery: store.KeysetPaginatedQuery{
+			Limit: 20,
+		},
+	}
+
+	bq, err := bq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(bq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	bookmarks, err := app.store.Bookmarks.GetByUserID(r.Context(), user.ID, bq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := keysetCursors(app, bookmarks, bq.Cursor, bq.Limit, func(b *store.Bookmark) (int64, string) {
+		return b.ID, b.CreatedAt
+	})
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, bookmarks, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetBookmarkCollections godoc
+//
+//	@Summary		Lists bookmark collections
+//	@Description	Lists the current user's bookmark collections
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]store.BookmarkCollection
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmarks/collections [get]
+func (app *application) getBookmarkCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	collections, err := app.store.Bookmarks.GetCollections(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, collections); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteBookmarkCollection godoc
+//
+//	@Summary		Deletes a bookmark collection
+//	@Description	Deletes a collection, its bookmarks are kept without a collection
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			collectionID	path		int	true	"Collection ID"
+//	@Success		204				{object}	string
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/bookmarks/collections/{collectionID} [delete]
+func (app *application) deleteBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	collectionID, err := strconv.ParseInt(chi.URLParam(r, "collectionID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Bookmarks.DeleteCollection(r.Context(), user.ID, collectionID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestBookmarkThroughRepost(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	originalID := int64(1)
+	app.store.Posts.(*store.MockPostStore).Posts = map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Kind: store.PostKindPost, Visibility: store.VisibilityPublic},
+		2: {ID: 2, UserID: 3, Kind: store.PostKindRepost, Visibility: store.VisibilityPublic, OriginalID: &originalID},
+	}
+
+	bookmarked := func(t *testing.T, postID string) bool {
+		t.Helper()
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/posts/"+postID, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		return body.Data.Bookmarked
+	}
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPut, "/v1/posts/2/bookmark", ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	if !app.store.Bookmarks.(*store.MockBookmarkStore).Bookmarks[1][1] {
+		t.Fatal("bookmarking a repost should save the post it shares")
+	}
+
+	if !bookmarked(t, "2") {
+		t.Error("the repost should be flagged as bookmarked")
+	}
+
+	if !bookmarked(t, "1") {
+		t.Error("the original should be flagged as bookmarked")
+	}
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodDelete, "/v1/posts/2/bookmark", ""), mux)
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	if bookmarked(t, "2") {
+		t.Error("the repost shouldn't be flagged once the bookmark is removed")
+	}
+}
@@ -1,21 +1,47 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/events"
 	"github.com/qwerqy/social-api-go/internal/store"
 )
 
+type commentKey string
+
+const commentCtx commentKey = "comment"
+
+var errCommentChanged = errors.New("comment was changed since, edit the latest version")
+
+const (
+	defaultInlineReplies = 3
+	maxInlineReplies     = 10
+
+	// inlineComments is how many comments come embedded in a post
+	inlineComments = 20
+)
+
 type CreateCommentPayload struct {
+	Content  string `json:"content" validate:"required,max=100"`
+	ParentID *int64 `json:"parent_id" validate:"omitempty,gt=0"`
+}
+
+type UpdateCommentPayload struct {
 	Content string `json:"content" validate:"required,max=100"`
+	// Version is the version the edit was made from, the edit is rejected
+	// when the comment changed since
+	Version *int64 `json:"version"`
 }
 
 // CreateComment godoc
 //
 //	@Summary		Creates a comment
-//	@Description	Creates a comment on a post
+//	@Description	Creates a comment on a post, or a reply to a comment when parent_id is set
 //	@Tags			comments
 //	@Accept			json
 //	@Produce		json
@@ -33,6 +59,7 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
 	if err != nil {
 		app.badRequestError(w, r, err)
+		return
 	}
 
 	if err := readJSON(w, r, &payload); err != nil {
@@ -46,20 +73,329 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	comment := &store.Comment{
-		Content: payload.Content,
-		UserID:  user.ID,
-		PostID:  postID,
+		Content:  payload.Content,
+		UserID:   user.ID,
+		PostID:   postID,
+		Entities: parseEntities(payload.Content),
 	}
 
 	ctx := r.Context()
 
+	if payload.ParentID != nil {
+		parent, err := app.store.Comments.GetByID(ctx, *payload.ParentID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				app.badRequestError(w, r, fmt.Errorf("parent comment does not exist"))
+				return
+			}
+
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if parent.PostID != postID {
+			app.badRequestError(w, r, fmt.Errorf("parent comment belongs to another post"))
+			return
+		}
+
+		if parent.Deleted {
+			app.badRequestError(w, r, fmt.Errorf("cannot reply to a deleted comment"))
+			return
+		}
+
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
 	if err := app.store.Comments.CreateByPostID(ctx, comment); err != nil {
+		if errors.Is(err, store.ErrCommentTooDeep) {
+			app.badRequestError(w, r, err)
+			return
+		}
+
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	app.events.Publish(ctx, events.Event{
+		Type:      events.CommentCreated,
+		ActorID:   user.ID,
+		PostID:    postID,
+		CommentID: comment.ID,
+	})
+
 	if err := app.jsonResponse(w, http.StatusCreated, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// ListComments godoc
+//
+//	@Summary		Lists comments of a post
+//	@Description	Lists top-level comments of a post, newest first, with their first replies inlined
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Param			replies	query		int		false	"Inline replies per comment"
+//	@Success		200		{object}	[]store.Comment
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments [get]
+func (app *application) listCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	kq := store.KeysetPaginatedQuery{
+		Limit: 20,
+	}
+
+	kq, err := kq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(kq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	replies := defaultInlineReplies
+	if param := r.URL.Query().Get("replies"); param != "" {
+		replies, err = strconv.Atoi(param)
+		if err != nil || replies < 0 || replies > maxInlineReplies {
+			app.badRequestError(w, r, fmt.Errorf("replies must be between 0 and %d", maxInlineReplies))
+			return
+		}
+	}
+
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, kq, replies)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.loadCommentEntities(r.Context(), comments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.muteComments(r.Context(), getUserFromCtx(r).ID, comments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := keysetCursors(app, comments, kq.Cursor, kq.Limit, func(c *store.Comment) (int64, string) {
+		return c.ID, c.CreatedAt
+	})
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, comments, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetCommentReplies godoc
+//
+//	@Summary		Gets the replies of a comment
+//	@Description	Gets the whole reply subtree below a comment
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		200			{object}	[]store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/replies [get]
+func (app *application) getCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	replies, err := app.store.Comments.GetReplies(r.Context(), comment.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.loadCommentEntities(r.Context(), replies); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.muteComments(r.Context(), getUserFromCtx(r).ID, replies); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, replies); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateComment godoc
+//
+//	@Summary		Updates a comment
+//	@Description	Updates a comment by ID, the previous content is kept in the edit history
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int						true	"Post ID"
+//	@Param			commentID	path		int						true	"Comment ID"
+//	@Param			payload		body		UpdateCommentPayload	true	"Update Comment Payload"
+//	@Success		200			{object}	store.Comment
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [patch]
+func (app *application) patchCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+	user := getUserFromCtx(r)
+
+	var payload UpdateCommentPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if comment.Deleted {
+		app.notFoundError(w, r, store.ErrNotFound)
+		return
+	}
+
+	if payload.Version != nil && *payload.Version != comment.Version {
+		app.conflictError(w, r, errCommentChanged)
+		return
+	}
+
+	comment.Content = payload.Content
+	comment.Entities = parseEntities(payload.Content)
+
+	if err := app.store.Comments.PatchByID(r.Context(), comment, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errCommentChanged)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteComment godoc
+//
+//	@Summary		Deletes a comment
+//	@Description	Deletes a comment by ID, comments with replies are left as a tombstone
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		204			{object}	string
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	if err := app.store.Comments.DeleteByID(r.Context(), comment.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetCommentEdits godoc
+//
+//	@Summary		Gets the edit history of a comment
+//	@Description	Gets the previous versions of a comment, newest first
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int	true	"Post ID"
+//	@Param			commentID	path		int	true	"Comment ID"
+//	@Success		200			{object}	[]store.CommentEdit
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/edits [get]
+func (app *application) getCommentEditsHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	edits, err := app.store.Comments.GetEdits(r.Context(), comment.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, edits); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) commentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		comment, err := app.store.Comments.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				app.notFoundError(w, r, err)
+				return
+			}
+
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// Comments are only reachable through the post they belong to
+		if post := getPostFromCtx(r); post == nil || comment.PostID != post.ID {
+			app.notFoundError(w, r, store.ErrNotFound)
+			return
+		}
+
+		ctx = context.WithValue(ctx, commentCtx, comment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCommentFromCtx(r *http.Request) *store.Comment {
+	comment, _ := r.Context().Value(commentCtx).(*store.Comment)
+	return comment
+}
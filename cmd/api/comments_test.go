@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// newCommentTestApp returns an app whose post 1 has n top-level comments,
+// IDs 1 to n created a second apart, and post 2 has none.
+func newCommentTestApp(t *testing.T, n int) (*application, http.Handler, *store.MockCommentStore) {
+	t.Helper()
+
+	app := newTestApplication(t, config{})
+	app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Kind: store.PostKindPost},
+		2: {ID: 2, UserID: 2, Kind: store.PostKindPost},
+	}}
+
+	comments := &store.MockCommentStore{Comments: map[int64]*store.Comment{}}
+	for id := int64(1); id <= int64(n); id++ {
+		comments.Comments[id] = &store.Comment{
+			ID:        id,
+			PostID:    1,
+			UserID:    2,
+			Content:   fmt.Sprint("comment ", id),
+			CreatedAt: time.Date(2026, 1, 1, 0, 0, int(id), 0, time.UTC).Format(time.RFC3339Nano),
+		}
+	}
+	app.store.Comments = comments
+
+	return app, app.mount(), comments
+}
+
+type commentPage struct {
+	Data       []*store.Comment `json:"data"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+func getCommentPage(t *testing.T, app *application, mux http.Handler, url string) commentPage {
+	t.Helper()
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, url, ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	var page commentPage
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+
+	return page
+}
+
+func TestListCommentsPages(t *testing.T) {
+	app, mux, _ := newCommentTestApp(t, 25)
+
+	var ids []int64
+	url := "/v1/posts/1/comments?limit=10"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("cursor never runs out")
+		}
+
+		page := getCommentPage(t, app, mux, url)
+		for _, c := range page.Data {
+			ids = append(ids, c.ID)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+
+		url = "/v1/posts/1/comments?limit=10&cursor=" + page.NextCursor
+	}
+
+	if len(ids) != 25 {
+		t.Fatalf("got %d comments across pages, want 25", len(ids))
+	}
+
+	for i, id := range ids {
+		if want := int64(25 - i); id != want {
+			t.Fatalf("got comment %d at position %d, want %d", id, i, want)
+		}
+	}
+}
+
+func TestListCommentsInlinesReplies(t *testing.T) {
+	app, mux, comments := newCommentTestApp(t, 1)
+	parentID := int64(1)
+	for id := int64(2); id <= 6; id++ {
+		comments.Comments[id] = &store.Comment{ID: id, PostID: 1, ParentID: &parentID, Depth: 1}
+	}
+
+	page := getCommentPage(t, app, mux, "/v1/posts/1/comments")
+	if len(page.Data) != 1 || len(page.Data[0].Replies) != defaultInlineReplies {
+		t.Fatalf("got %d top-level comments with %d replies, want 1 with %d", len(page.Data), len(page.Data[0].Replies), defaultInlineReplies)
+	}
+
+	if page.Data[0].Replies[0].ID != 2 {
+		t.Errorf("got first reply %d, want the oldest one, 2", page.Data[0].Replies[0].ID)
+	}
+
+	page = getCommentPage(t, app, mux, "/v1/posts/1/comments?replies=0")
+	if len(page.Data[0].Replies) != 0 {
+		t.Errorf("got %d replies with replies=0, want none", len(page.Data[0].Replies))
+	}
+
+	for _, query := range []string{"replies=11", "replies=-1", "limit=0", "limit=51", "cursor=abc"} {
+		rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/posts/1/comments?"+query, ""), mux)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want %d", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestPostCommentsNextCursor(t *testing.T) {
+	app, mux, _ := newCommentTestApp(t, inlineComments+5)
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/posts/1", ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	post := decodePost(t, rr.Body.Bytes())
+	if len(post.Comments) != inlineComments || post.CommentsNextCursor == "" {
+		t.Fatalf("got %d comments and cursor %q, want %d and a cursor", len(post.Comments), post.CommentsNextCursor, inlineComments)
+	}
+
+	page := getCommentPage(t, app, mux, "/v1/posts/1/comments?cursor="+post.CommentsNextCursor)
+	if len(page.Data) != 5 || page.NextCursor != "" {
+		t.Errorf("got %d more comments and cursor %q, want the last 5 and no cursor", len(page.Data), page.NextCursor)
+	}
+
+	app, mux, _ = newCommentTestApp(t, 3)
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/posts/1", ""), mux)
+	if post := decodePost(t, rr.Body.Bytes()); post.CommentsNextCursor != "" {
+		t.Errorf("got cursor %q for a post with every comment inlined, want none", post.CommentsNextCursor)
+	}
+}
+
+func TestCreateReply(t *testing.T) {
+	app, mux, comments := newCommentTestApp(t, 1)
+	parentID := int64(1)
+	comments.Comments[2] = &store.Comment{ID: 2, PostID: 1, ParentID: &parentID, Depth: store.MaxCommentDepth}
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+		want int
+	}{
+		{"reply", "/v1/posts/1/comments", `{"content":"hi","parent_id":1}`, http.StatusCreated},
+		{"missing parent", "/v1/posts/1/comments", `{"content":"hi","parent_id":9}`, http.StatusBadRequest},
+		{"parent on another post", "/v1/posts/2/comments", `{"content":"hi","parent_id":1}`, http.StatusBadRequest},
+		{"too deep", "/v1/posts/1/comments", `{"content":"hi","parent_id":2}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := executeRequest(newAuthRequest(t, app, http.MethodPost, tt.url, tt.body), mux)
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+
+	var res struct {
+		Data store.Comment `json:"data"`
+	}
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/comments", `{"content":"hi","parent_id":1}`), mux)
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Data.Depth != 1 || res.Data.ParentID == nil || *res.Data.ParentID != 1 {
+		t.Errorf("got depth %d and parent %v, want a depth 1 reply to comment 1", res.Data.Depth, res.Data.ParentID)
+	}
+}
+
+func TestGetCommentReplies(t *testing.T) {
+	app, mux, comments := newCommentTestApp(t, 1)
+	parentID, replyID := int64(1), int64(2)
+	comments.Comments[2] = &store.Comment{ID: 2, PostID: 1, ParentID: &parentID, Depth: 1}
+	comments.Comments[3] = &store.Comment{ID: 3, PostID: 1, ParentID: &replyID, Depth: 2}
+
+	page := getCommentPage(t, app, mux, "/v1/posts/1/comments/1/replies")
+	if len(page.Data) != 1 || len(page.Data[0].Replies) != 1 || page.Data[0].Replies[0].ID != 3 {
+		t.Errorf("got %+v, want reply 2 with reply 3 nested below it", page.Data)
+	}
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/posts/2/comments/1/replies", ""), mux)
+	checkResponseCode(t, http.StatusNotFound, rr.Code)
+}
+
+// newCommentOwnershipApp serves post 1 by postAuthor with comment 1 by
+// commentAuthor, to a test user with the given role level.
+func newCommentOwnershipApp(t *testing.T, postAuthor, commentAuthor, roleLevel int64) *application {
+	t.Helper()
+
+	app := newTestApplication(t, config{})
+
+	app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{
+		1: {ID: 1, Role: store.Role{Level: roleLevel}},
+	}
+	app.store.Posts.(*store.MockPostStore).Posts = map[int64]*store.Post{
+		1: {ID: 1, UserID: postAuthor},
+	}
+	app.store.Comments.(*store.MockCommentStore).Comments = map[int64]*store.Comment{
+		1: {ID: 1, PostID: 1, UserID: commentAuthor, Content: "first", Version: 3},
+	}
+
+	return app
+}
+
+func TestCommentOwnership(t *testing.T) {
+	const (
+		user      = 1
+		moderator = 2
+	)
+
+	tests := []struct {
+		name          string
+		postAuthor    int64
+		commentAuthor int64
+		roleLevel     int64
+		patchCode     int
+		deleteCode    int
+	}{
+		{"comment author", 2, 1, user, http.StatusOK, http.StatusNoContent},
+		{"post author", 1, 3, user, http.StatusOK, http.StatusNoContent},
+		{"moderator", 2, 3, moderator, http.StatusOK, http.StatusNoContent},
+		{"anyone else", 2, 3, user, http.StatusForbidden, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newCommentOwnershipApp(t, tt.postAuthor, tt.commentAuthor, tt.roleLevel)
+			mux := app.mount()
+
+			req := newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1/comments/1", `{"content":"edited"}`)
+			checkResponseCode(t, tt.patchCode, executeRequest(req, mux).Code)
+
+			req = newAuthRequest(t, app, http.MethodDelete, "/v1/posts/1/comments/1", "")
+			checkResponseCode(t, tt.deleteCode, executeRequest(req, mux).Code)
+		})
+	}
+}
+
+func TestPatchCommentVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"without a version", `{"content":"edited"}`, http.StatusOK},
+		{"from the current version", `{"content":"edited","version":3}`, http.StatusOK},
+		{"from an older version", `{"content":"edited","version":2}`, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newCommentOwnershipApp(t, 2, 1, 1)
+
+			req := newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1/comments/1", tt.body)
+			checkResponseCode(t, tt.code, executeRequest(req, app.mount()).Code)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// pageCursors returns the cursors of the pages after and before posts, a
+// page read with limit from cursor. Either is empty when there is no page
+// that way.
+func (app *application) pageCursors(posts []*store.PostWithMetadata, cursor *store.Cursor, limit int) (next, prev string) {
+	return keysetCursors(app, posts, cursor, limit, func(p *store.PostWithMetadata) (int64, string) {
+		return p.ID, p.CreatedAt
+	})
+}
+
+// keysetCursors is pageCursors for any list read with a KeysetPaginatedQuery.
+// position returns the ID and creation time the list is ordered by.
+func keysetCursors[T any](app *application, items []T, cursor *store.Cursor, limit int, position func(T) (int64, string)) (next, prev string) {
+	if len(items) == 0 {
+		return "", ""
+	}
+
+	full := len(items) == limit
+	backward := cursor != nil && cursor.Backward
+
+	// Coming back from a later page, there is always one after
+	if full || backward {
+		id, createdAt := position(items[len(items)-1])
+		next = app.encodeCursor(id, createdAt, false)
+	}
+
+	if cursor != nil && (full || !backward) {
+		id, createdAt := position(items[0])
+		prev = app.encodeCursor(id, createdAt, true)
+	}
+
+	return next, prev
+}
+
+func (app *application) encodeCursor(id int64, createdAt string, backward bool) string {
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		app.logger.Errorw("list item has a malformed creation time", "id", id, "created_at", createdAt)
+		return ""
+	}
+
+	return app.cursors.Encode(store.Cursor{CreatedAt: t, ID: id, Backward: backward})
+}
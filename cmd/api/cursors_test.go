@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestKeysetCursors(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	mentions := []*store.Mention{
+		{ID: 3, CreatedAt: "2024-05-01T12:30:03Z"},
+		{ID: 2, CreatedAt: "2024-05-01T12:30:02Z"},
+	}
+	position := func(m *store.Mention) (int64, string) { return m.ID, m.CreatedAt }
+	forward := &store.Cursor{ID: 4}
+	backward := &store.Cursor{ID: 1, Backward: true}
+
+	tests := []struct {
+		name       string
+		cursor     *store.Cursor
+		limit      int
+		next, prev int64
+	}{
+		{name: "first page", limit: 2, next: 2},
+		{name: "only page", limit: 20},
+		{name: "middle page", cursor: forward, limit: 2, next: 2, prev: 3},
+		{name: "last page", cursor: forward, limit: 20, prev: 3},
+		{name: "back to a middle page", cursor: backward, limit: 2, next: 2, prev: 3},
+		{name: "back to the first page", cursor: backward, limit: 20, next: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, prev := keysetCursors(app, mentions, tt.cursor, tt.limit, position)
+
+			for _, c := range []struct {
+				token    string
+				id       int64
+				backward bool
+			}{{next, tt.next, false}, {prev, tt.prev, true}} {
+				if c.id == 0 {
+					if c.token != "" {
+						t.Errorf("expected no cursor, got %q", c.token)
+					}
+					continue
+				}
+
+				cursor, err := app.cursors.Decode(c.token)
+				if err != nil {
+					t.Fatalf("cursor %q: %v", c.token, err)
+				}
+
+				if cursor.ID != c.id || cursor.Backward != c.backward {
+					t.Errorf("got cursor %+v, want ID %d backward %v", *cursor, c.id, c.backward)
+				}
+			}
+		})
+	}
+}
+
+func TestListsRejectUnsignedCursors(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Posts.(*store.MockPostStore).Posts = map[int64]*store.Post{1: {ID: 1, UserID: 1}}
+	mux := app.mount()
+
+	for _, path := range []string{
+		"/v1/users/me/bookmarks",
+		"/v1/users/me/mentions",
+		"/v1/posts/1/comments",
+		"/v1/tags/go/posts",
+	} {
+		req := newAuthRequest(t, app, http.MethodGet, path, "")
+		checkResponseCode(t, http.StatusOK, executeRequest(req, mux).Code)
+
+		req = newAuthRequest(t, app, http.MethodGet, path+"?cursor=42", "")
+		if rr := executeRequest(req, mux); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected response code %d, got %d", path, http.StatusBadRequest, rr.Code)
+		}
+	}
+}
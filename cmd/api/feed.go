@@ -1,24 +1,29 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/qwerqy/social-api-go/internal/store"
 )
 
 // GetFeed godoc
 //	@Summary		Gets user feed
-//	@Description	Gets user feed by ID
+//	@Description	Gets the current user's feed: their posts and those of the users they follow
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
-//	@Param			since	query		string	false	"Since"
-//	@Param			until	query		string	false	"Until"
+//	@Param			since	query		string	false	"Since, RFC 3339 or YYYY-MM-DD HH:MM:SS in UTC"
+//	@Param			until	query		string	false	"Until, RFC 3339 or YYYY-MM-DD HH:MM:SS in UTC"
 //	@Param			limit	query		int		false	"Limit"
-//	@Param			offset	query		int		false	"Offset"
-//	@Param			sort	query		string	false	"Sort"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Param			offset	query		int		false	"Offset, deprecated in favour of cursor"
+//	@Param			sort	query		string	false	"Sort: asc or desc. ranked answers like /users/feed/ranked"
 //	@Param			tags	query		string	false	"Tags"
-//	@Param			search	query		string	false	"Search"
+//	@Param			search	query		string	false	"Full-text search, in web search syntax"
+//	@Param			saved_search	query		int		false	"ID of a saved search whose filters to use"
 //	@Success		200		{object}	[]store.PostWithMetadata
 //	@Failure		400		{object}	error
 //	@Failure		404		{object}	error
@@ -33,7 +38,32 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		Sort: "desc",
 	}
 
-	fq, err := fq.Parse(r)
+	ctx := r.Context()
+	user := getUserFromCtx(r)
+
+	// A saved search sets the filters, the request can still override them
+	if param := r.URL.Query().Get("saved_search"); param != "" {
+		searchID, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, errors.New("saved_search must be a number"))
+			return
+		}
+
+		search, err := app.store.SavedSearches.GetByID(ctx, user.ID, searchID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				app.notFoundError(w, r, err)
+				return
+			}
+
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		fq = search.Query.Apply(fq)
+	}
+
+	fq, err := fq.Parse(r, app.cursors)
 	if err != nil {
 		app.badRequestError(w,r,err)
 		return
@@ -44,16 +74,49 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	ctx := r.Context()
-
-	feed, err := app.store.Posts.GetUserFeed(ctx, int64(97), fq)
+	if fq.Sort == "ranked" {
+		app.writeRankedFeed(w, r, user.ID, fq)
+		return
+	}
 
+	mutes, err := app.feedMutes(ctx, user.ID, &fq)
 	if err != nil {
-		app.internalServerError(w,r,err)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var page *feedPage
+	if app.fromTimeline(fq) {
+		page, err = app.timelineFeed(ctx, user.ID, fq)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if page == nil {
+		feed, err := app.store.Posts.GetUserFeed(ctx, user.ID, fq)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		page = &feedPage{posts: feed}
+		page.nextCursor, page.prevCursor = app.pageCursors(feed, fq.Cursor, fq.Limit)
+	}
+
+	// Posts muted by regexes are left out once the cursors are known, so
+	// pages may come up short but paging still goes through every post
+	page.posts = slices.DeleteFunc(page.posts, func(p *store.PostWithMetadata) bool {
+		return mutes.HidesPost(&p.Post)
+	})
+
+	if err := app.hydratePostList(ctx, page.posts); err != nil {
+		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w,http.StatusOK,feed); err != nil {
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, page.posts, page.nextCursor, page.prevCursor); err != nil {
 		app.internalServerError(w,r,err)
 		return
 	}
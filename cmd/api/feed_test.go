@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestFeedRejectsMalformedQueries(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	for _, query := range []string{
+		"since=yesterday",
+		"until=2024-05-01",
+		"since=2024-05-02+00:00:00&until=2024-05-01T23:59:59Z",
+		"limit=ten",
+		"limit=100",
+		"cursor=abc",
+		"sort=oldest",
+	} {
+		req := newAuthRequest(t, app, http.MethodGet, "/v1/users/feed?"+query, "")
+		if rr := executeRequest(req, mux); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected response code %d, got %d", query, http.StatusBadRequest, rr.Code)
+		}
+	}
+}
+
+func TestFeedMutes(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	mutedWords := app.store.MutedWords.(*store.MockMutedWordStore)
+	for _, w := range []*store.MutedWord{
+		{UserID: 1, Kind: store.MuteKindKeyword, Pattern: "finale", Scopes: []string{store.MuteScopeFeed}},
+		{UserID: 1, Kind: store.MuteKindHashtag, Pattern: "spoilers", Scopes: []string{store.MuteScopeFeed}},
+		{UserID: 1, Kind: store.MuteKindRegex, Pattern: `(?i)s\d+e\d+`, Scopes: []string{store.MuteScopeFeed}},
+		{UserID: 1, Kind: store.MuteKindKeyword, Pattern: "dog", Scopes: []string{store.MuteScopeComments}},
+		{UserID: 2, Kind: store.MuteKindKeyword, Pattern: "cat", Scopes: []string{store.MuteScopeFeed}},
+	} {
+		if err := mutedWords.Create(context.Background(), w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The store leaves out keywords and hashtags, the feed gets what's left
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.Feed = []*store.PostWithMetadata{
+		{Post: store.Post{ID: 3, UserID: 2, Content: "just watched S03E09", CreatedAt: "2026-01-01T00:03:00Z"}},
+		{Post: store.Post{ID: 2, UserID: 2, Content: "my dog and cat", CreatedAt: "2026-01-01T00:02:00Z"}},
+		{Post: store.Post{ID: 1, UserID: 1, Content: "s01e01 again", CreatedAt: "2026-01-01T00:01:00Z"}},
+	}
+
+	req := newAuthRequest(t, app, http.MethodGet, "/v1/users/feed", "")
+	rr := executeRequest(req, mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	mq := posts.FeedQuery.Mutes
+	if !strings.Contains(mq.Words, "finale") || strings.Contains(mq.Words, "dog") || strings.Contains(mq.Words, "cat") {
+		t.Errorf("muted words pattern = %q", mq.Words)
+	}
+
+	if !slices.Equal(mq.Tags, []string{"spoilers"}) {
+		t.Errorf("muted tags = %v", mq.Tags)
+	}
+
+	var res struct {
+		Data []store.PostWithMetadata `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, p := range res.Data {
+		ids = append(ids, p.ID)
+	}
+
+	if want := []int64{2, 1}; !slices.Equal(ids, want) {
+		t.Errorf("feed = %v, want %v", ids, want)
+	}
+}
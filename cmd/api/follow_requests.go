@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// GetFollowRequests godoc
+//
+//	@Summary		Lists follow requests
+//	@Description	Lists the users waiting for the current user to approve their follow request
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Success		200		{object}	[]store.Follower
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests [get]
+func (app *application) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	kq := store.KeysetPaginatedQuery{
+		Limit: 20,
+	}
+
+	kq, err := kq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(kq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	requests, err := app.store.Followers.GetRequests(r.Context(), user.ID, kq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := keysetCursors(app, requests, kq.Cursor, kq.Limit, func(f *store.Follower) (int64, string) {
+		return f.FollowerID, f.CreatedAt
+	})
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, requests, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ApproveFollowRequest godoc
+//
+//	@Summary		Approves a follow request
+//	@Description	Lets the user who asked follow the current user
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"ID of the user who asked"
+//	@Success		204	{object}	string
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{id}/approve [put]
+func (app *application) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.answerFollowRequest(w, r, func(ctx context.Context, userID, followerID int64) error {
+		if err := app.store.Followers.Approve(ctx, userID, followerID); err != nil {
+			return err
+		}
+
+		app.invalidateTimeline(ctx, followerID)
+		app.events.Publish(ctx, events.Event{
+			Type:    events.UserFollowed,
+			ActorID: followerID,
+			UserID:  userID,
+		})
+
+		return nil
+	})
+}
+
+// RejectFollowRequest godoc
+//
+//	@Summary		Rejects a follow request
+//	@Description	Drops a follow request to the current user, the user who asked can ask again
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"ID of the user who asked"
+//	@Success		204	{object}	string
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/follow-requests/{id}/reject [put]
+func (app *application) rejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.answerFollowRequest(w, r, app.store.Followers.Reject)
+}
+
+// answerFollowRequest applies answer to the current user's follow request
+// from the user in the URL.
+func (app *application) answerFollowRequest(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, userID, followerID int64) error) {
+	user := getUserFromCtx(r)
+	followerID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := answer(r.Context(), user.ID, followerID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
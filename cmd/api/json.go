@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -45,4 +48,56 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 	}
 
 	return writeJSON(w, status, &envelope{Data: data})
-}
\ No newline at end of file
+}
+
+// keysetJSONResponse writes a page of a list paged with signed cursors. The
+// pages around it are linked from the Link header too, an empty cursor means
+// there is no page that way.
+func (app *application) keysetJSONResponse(w http.ResponseWriter, r *http.Request, status int, data any, nextCursor, prevCursor string) error {
+	type envelope struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		PrevCursor string `json:"prev_cursor,omitempty"`
+	}
+
+	var links []string
+	for _, link := range []struct{ rel, cursor string }{{"next", nextCursor}, {"prev", prevCursor}} {
+		if link.cursor == "" {
+			continue
+		}
+
+		u := *r.URL
+		qs := u.Query()
+		qs.Set("cursor", link.cursor)
+		qs.Del("offset")
+		u.RawQuery = qs.Encode()
+
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), link.rel))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: nextCursor, PrevCursor: prevCursor})
+}
+
+// offsetJSONResponse writes a page of a list paged by offset. The next page
+// is linked from the Link header too, a zero nextOffset means there is none.
+func (app *application) offsetJSONResponse(w http.ResponseWriter, r *http.Request, status int, data any, nextOffset int) error {
+	type envelope struct {
+		Data       any `json:"data"`
+		NextOffset int `json:"next_offset,omitempty"`
+	}
+
+	if nextOffset > 0 {
+		u := *r.URL
+		qs := u.Query()
+		qs.Set("offset", strconv.Itoa(nextOffset))
+		u.RawQuery = qs.Encode()
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextOffset: nextOffset})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+
+	"github.com/qwerqy/social-api-go/internal/linkpreview"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// queueLinkPreview asks for the preview of the first link in content to be
+// fetched in the background. The post is returned without it, it shows up
+// on later reads once it's ready.
+func (app *application) queueLinkPreview(content string) {
+	url := linkpreview.FirstURL(content)
+	if url == "" {
+		return
+	}
+
+	select {
+	case app.linkPreviews <- url:
+	default:
+		// The post still works without a preview, better to skip it than
+		// hold the request up
+		app.logger.Warnw("link preview queue is full", "url", url)
+	}
+}
+
+// fetchLinkPreviews fetches the queued link previews until ctx is cancelled.
+// Several can run side by side.
+func (app *application) fetchLinkPreviews(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case url := <-app.linkPreviews:
+			if err := app.fetchLinkPreview(ctx, url); err != nil {
+				app.logger.Errorw("failed to fetch link preview", "url", url, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (app *application) fetchLinkPreview(ctx context.Context, url string) error {
+	cfg := app.config.linkPreview
+
+	needed, err := app.store.LinkPreviews.NeedsFetch(ctx, url, cfg.maxAge, cfg.failedMaxAge)
+	if err != nil || !needed {
+		return err
+	}
+
+	card, err := app.previewFetcher.Fetch(ctx, url)
+	if err != nil {
+		// Remember the failure, pages that can't be previewed are common
+		// and not worth an error
+		app.logger.Infow("no link preview", "url", url, "reason", err.Error())
+		return app.store.LinkPreviews.Save(ctx, url, nil)
+	}
+
+	return app.store.LinkPreviews.Save(ctx, url, &store.LinkPreview{
+		URL:         url,
+		Title:       card.Title,
+		Description: card.Description,
+		ImageURL:    card.ImageURL,
+		SiteName:    card.SiteName,
+	})
+}
+
+// loadLinkPreviews attaches the previews of the first link of each post that
+// are ready.
+func (app *application) loadLinkPreviews(ctx context.Context, posts ...*store.Post) error {
+	urls := make(map[*store.Post]string)
+	var list []string
+
+	for _, p := range posts {
+		if url := linkpreview.FirstURL(p.Content); url != "" {
+			urls[p] = url
+			list = append(list, url)
+		}
+	}
+
+	if len(list) == 0 {
+		return nil
+	}
+
+	previews, err := app.store.LinkPreviews.GetByURLs(ctx, list)
+	if err != nil {
+		return err
+	}
+
+	for p, url := range urls {
+		p.Preview = previews[url]
+	}
+
+	return nil
+}
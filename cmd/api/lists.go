@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type listKey string
+
+const listCtx listKey = "list"
+
+type CreateListPayload struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=300"`
+	IsPrivate   bool   `json:"is_private"`
+}
+
+type UpdateListPayload struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=300"`
+	IsPrivate   *bool   `json:"is_private"`
+}
+
+// UserLists are the lists a user made and the ones they follow.
+type UserLists struct {
+	Owned    []*store.List `json:"owned"`
+	Followed []*store.List `json:"followed"`
+}
+
+// CreateList godoc
+//
+//	@Summary		Creates a list
+//	@Description	Creates a list of accounts with a feed of their own. Public lists can be seen and followed by other users.
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateListPayload	true	"List payload"
+//	@Success		201		{object}	store.List
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists [post]
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateListPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+
+	list := &store.List{
+		UserID:      user.ID,
+		Name:        payload.Name,
+		Description: payload.Description,
+		IsPrivate:   payload.IsPrivate,
+		User:        store.User{ID: user.ID, Username: user.Username},
+	}
+
+	if err := app.store.Lists.Create(r.Context(), list); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictError(w, r, errors.New("a list with that name already exists"))
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, list); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetLists godoc
+//
+//	@Summary		Lists the user's lists
+//	@Description	Gets the lists the current user made, by name, and the ones they follow, most recently followed first
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	UserLists
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists [get]
+func (app *application) getListsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	owned, err := app.store.Lists.GetByUserID(ctx, user.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	followed, err := app.store.Lists.GetFollowed(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, UserLists{Owned: owned, Followed: followed}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUserLists godoc
+//
+//	@Summary		Lists a user's lists
+//	@Description	Gets the lists a user made that the current user can see, by name
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	[]store.List
+//	@Failure		400	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/lists [get]
+func (app *application) getUserListsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	lists, err := app.store.Lists.GetByUserID(r.Context(), userID, getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, lists); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetList godoc
+//
+//	@Summary		Gets a list
+//	@Description	Gets a list by ID. Private lists are only found by their owner.
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int	true	"List ID"
+//	@Success		200		{object}	store.List
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID} [get]
+func (app *application) getListHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getListFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateList godoc
+//
+//	@Summary		Updates a list
+//	@Description	Updates the name, description or privacy of a list, the fields left out are kept. Making a list private drops its followers.
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int					true	"List ID"
+//	@Param			payload	body		UpdateListPayload	true	"List payload"
+//	@Success		200		{object}	store.List
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID} [patch]
+func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+
+	var payload UpdateListPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		list.Name = *payload.Name
+	}
+
+	if payload.Description != nil {
+		list.Description = *payload.Description
+	}
+
+	if payload.IsPrivate != nil {
+		list.IsPrivate = *payload.IsPrivate
+	}
+
+	if err := app.store.Lists.Update(r.Context(), list); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("a list with that name already exists"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, list); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteList godoc
+//
+//	@Summary		Deletes a list
+//	@Description	Deletes a list, its members and followers
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int	true	"List ID"
+//	@Success		204		{object}	string
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID} [delete]
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+
+	if err := app.store.Lists.Delete(r.Context(), list.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetListMembers godoc
+//
+//	@Summary		Lists the members of a list
+//	@Description	Gets a page of the accounts in a list, oldest accounts first
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int		true	"List ID"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Success		200		{object}	[]store.User
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID}/members [get]
+func (app *application) getListMembersHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+
+	kq := store.KeysetPaginatedQuery{
+		Limit: 50,
+	}
+
+	kq, err := kq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(kq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	members, err := app.store.Lists.GetMembers(r.Context(), list.ID, kq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := keysetCursors(app, members, kq.Cursor, kq.Limit, func(u *store.User) (int64, string) {
+		return u.ID, u.CreatedAt
+	})
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, members, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// AddListMember godoc
+//
+//	@Summary		Adds a member to a list
+//	@Description	Adds an account to a list. Adding a member twice does nothing.
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int	true	"List ID"
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		204		{object}	string
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID}/members/{userID} [put]
+func (app *application) addListMemberHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Lists.AddMember(r.Context(), list.ID, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		case errors.Is(err, store.ErrListMemberLimit):
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RemoveListMember godoc
+//
+//	@Summary		Removes a member from a list
+//	@Description	Removes an account from a list
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int	true	"List ID"
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		204		{object}	string
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID}/members/{userID} [delete]
+func (app *application) removeListMemberHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Lists.RemoveMember(r.Context(), list.ID, userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// FollowList godoc
+//
+//	@Summary		Follows a list
+//	@Description	Follows another user's public list, so it shows up with the current user's lists
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int	true	"List ID"
+//	@Success		204		{object}	string
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID}/follow [put]
+func (app *application) followListHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+	user := getUserFromCtx(r)
+
+	if err := app.store.Lists.Follow(r.Context(), list.ID, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UnfollowList godoc
+//
+//	@Summary		Unfollows a list
+//	@Description	Stops following a list
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int	true	"List ID"
+//	@Success		204		{object}	string
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID}/follow [delete]
+func (app *application) unfollowListHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+	user := getUserFromCtx(r)
+
+	if err := app.store.Lists.Unfollow(r.Context(), list.ID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetListFeed godoc
+//
+//	@Summary		Gets the feed of a list
+//	@Description	Gets the posts of a list's members, filtered and paged like the user's feed. List feeds aren't ranked.
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			listID	path		int		true	"List ID"
+//	@Param			since	query		string	false	"Since, RFC 3339 or YYYY-MM-DD HH:MM:SS in UTC"
+//	@Param			until	query		string	false	"Until, RFC 3339 or YYYY-MM-DD HH:MM:SS in UTC"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Param			sort	query		string	false	"Sort: asc or desc"
+//	@Param			tags	query		string	false	"Tags"
+//	@Param			search	query		string	false	"Full-text search, in web search syntax"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lists/{listID}/feed [get]
+func (app *application) getListFeedHandler(w http.ResponseWriter, r *http.Request) {
+	list := getListFromCtx(r)
+
+	fq := store.PaginatedFeedQuery{
+		Limit: 20,
+		Sort:  "desc",
+	}
+
+	fq, err := fq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if fq.Sort == "ranked" {
+		app.badRequestError(w, r, errors.New("list feeds can't be ranked"))
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromCtx(r)
+
+	mutes, err := app.feedMutes(ctx, user.ID, &fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Posts.GetListFeed(ctx, list.ID, user.ID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := app.pageCursors(posts, fq.Cursor, fq.Limit)
+
+	posts = slices.DeleteFunc(posts, func(p *store.PostWithMetadata) bool {
+		return mutes.HidesPost(&p.Post)
+	})
+
+	if err := app.hydratePostList(ctx, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, posts, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) listContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listID, err := strconv.ParseInt(chi.URLParam(r, "listID"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		list, err := app.store.Lists.GetByID(ctx, listID, getUserFromCtx(r).ID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				app.notFoundError(w, r, err)
+				return
+			}
+
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, listCtx, list)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkListOwnership only lets the list's owner through. Lists are personal,
+// moderators have no reason to edit them.
+func (app *application) checkListOwnership(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if getListFromCtx(r).UserID != getUserFromCtx(r).ID {
+			app.forbiddenError(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+func getListFromCtx(r *http.Request) *store.List {
+	list, _ := r.Context().Value(listCtx).(*store.List)
+	return list
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// newListTestApp serves list 1, owned by owner, to the test user.
+func newListTestApp(t *testing.T, owner int64, private, blocked bool) *application {
+	t.Helper()
+
+	app := newTestApplication(t, config{})
+
+	lists := app.store.Lists.(*store.MockListStore)
+	lists.Lists = map[int64]*store.List{
+		1: {ID: 1, UserID: owner, Name: "gophers", IsPrivate: private},
+	}
+	lists.Blocked = func(ownerID, viewerID int64) bool { return blocked }
+
+	return app
+}
+
+func TestListVisibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   int64
+		private bool
+		blocked bool
+		getCode int
+		// followCode is the response to following the list once, then
+		// again
+		followCode, refollowCode int
+		addMemberCode            int
+	}{
+		{"own public list", 1, false, false, http.StatusOK, http.StatusNotFound, http.StatusNotFound, http.StatusNoContent},
+		{"own private list", 1, true, false, http.StatusOK, http.StatusNotFound, http.StatusNotFound, http.StatusNoContent},
+		{"someone's public list", 2, false, false, http.StatusOK, http.StatusNoContent, http.StatusConflict, http.StatusForbidden},
+		{"someone's private list", 2, true, false, http.StatusNotFound, http.StatusNotFound, http.StatusNotFound, http.StatusNotFound},
+		{"public list of a blocked user", 2, false, true, http.StatusNotFound, http.StatusNotFound, http.StatusNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newListTestApp(t, tt.owner, tt.private, tt.blocked)
+			mux := app.mount()
+
+			for _, c := range []struct {
+				method, path string
+				code         int
+			}{
+				{http.MethodGet, "/v1/lists/1", tt.getCode},
+				{http.MethodGet, "/v1/lists/1/members", tt.getCode},
+				{http.MethodGet, "/v1/lists/1/feed", tt.getCode},
+				{http.MethodPut, "/v1/lists/1/follow", tt.followCode},
+				{http.MethodPut, "/v1/lists/1/follow", tt.refollowCode},
+				{http.MethodPut, "/v1/lists/1/members/3", tt.addMemberCode},
+			} {
+				req := newAuthRequest(t, app, c.method, c.path, "")
+				if rr := executeRequest(req, mux); rr.Code != c.code {
+					t.Errorf("%s %s: expected response code %d, got %d", c.method, c.path, c.code, rr.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestUnfollowList(t *testing.T) {
+	app := newListTestApp(t, 2, false, false)
+	app.store.Lists.(*store.MockListStore).Followers = map[int64]map[int64]bool{1: {1: true}}
+	mux := app.mount()
+
+	req := newAuthRequest(t, app, http.MethodDelete, "/v1/lists/1/follow", "")
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req, mux).Code)
+
+	req = newAuthRequest(t, app, http.MethodDelete, "/v1/lists/1/follow", "")
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req, mux).Code)
+}
+
+func TestListMembersPages(t *testing.T) {
+	app := newListTestApp(t, 1, false, false)
+	app.store.Lists.(*store.MockListStore).Members = map[int64][]*store.User{
+		1: {
+			{ID: 2, Username: "ana", CreatedAt: "2024-05-01T12:30:00Z"},
+			{ID: 3, Username: "bo", CreatedAt: "2024-05-02T12:30:00Z"},
+		},
+	}
+	mux := app.mount()
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/lists/1/members?limit=2", ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	var body struct {
+		Data       []*store.User `json:"data"`
+		NextCursor string        `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := app.cursors.Decode(body.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cursor.ID != 3 {
+		t.Errorf("next page starts after member %d, want 3", cursor.ID)
+	}
+
+	if rr.Header().Get("Link") == "" {
+		t.Error("expected a Link header")
+	}
+
+	// Cursors are signed, member IDs aren't accepted anymore
+	rr = executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/lists/1/members?cursor=3", ""), mux)
+	checkResponseCode(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/blob"
+	"github.com/qwerqy/social-api-go/internal/media"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+const (
+	mediaFilesPath = "/v1/media/files/"
+	// orphanBatchSize bounds how many uploads one garbage collection pass
+	// deletes
+	orphanBatchSize = 100
+)
+
+type UploadMediaPayload struct {
+	AltText   string `validate:"max=1500"`
+	Sensitive bool
+}
+
+// UploadMedia godoc
+//
+//	@Summary		Uploads media
+//	@Description	Uploads an image (jpeg, png, gif) or a short mp4 video. The returned ID is attached to a post by passing it in media_ids when creating the post, uploads left unattached are deleted after a day.
+//	@Tags			media
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file		formData	file	true	"Image or video"
+//	@Param			alt_text	formData	string	false	"Alt text"
+//	@Param			sensitive	formData	bool	false	"Blur until the viewer expands it"
+//	@Success		201			{object}	store.Media
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media [post]
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	// Leave room for the other multipart fields on top of the largest file
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxVideoSize+1<<20)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	payload := UploadMediaPayload{
+		AltText: r.FormValue("alt_text"),
+	}
+
+	if param := r.FormValue("sensitive"); param != "" {
+		sensitive, err := strconv.ParseBool(param)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		payload.Sensitive = sensitive
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	processed, err := media.Process(data)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType),
+			errors.Is(err, media.ErrTooLarge),
+			errors.Is(err, media.ErrTooLong),
+			errors.Is(err, media.ErrInvalid):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	key, err := newMediaKey(processed.Extension)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.blobStore.Put(ctx, key, bytes.NewReader(processed.Data)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+
+	m := &store.Media{
+		UserID:      user.ID,
+		Kind:        processed.Kind,
+		ContentType: processed.ContentType,
+		StorageKey:  key,
+		Width:       processed.Width,
+		Height:      processed.Height,
+		AltText:     payload.AltText,
+		Blurhash:    processed.Blurhash,
+		Sensitive:   payload.Sensitive,
+		SizeBytes:   int64(len(processed.Data)),
+	}
+
+	if err := app.store.Media.Create(ctx, m); err != nil {
+		// Don't leave a file behind that no row points to
+		if err := app.blobStore.Delete(ctx, key); err != nil {
+			app.logger.Errorw("failed to delete media file", "key", key, "error", err.Error())
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	m.URL = mediaURL(key)
+
+	if err := app.jsonResponse(w, http.StatusCreated, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// serveMediaHandler serves uploaded files without authentication, so that
+// they work as plain img and video sources.
+//
+// This is an accepted risk: the visibility of the post a file is attached to
+// is not checked. Keys are 128 random bits and only handed out in post
+// responses, which are filtered by visibility, so the URL itself is the
+// capability. Anyone a viewer shares it with, and any shared cache on the way
+// given the public Cache-Control, can fetch the file, even after the post
+// is deleted or made followers only. Location metadata is always stripped on
+// upload.
+func (app *application) serveMediaHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+
+	f, err := app.blobStore.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.badRequestError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// A key always points to the same content
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	// Seekable blobs support range requests, which video players rely on
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, rs)
+		return
+	}
+
+	if _, err := io.Copy(w, f); err != nil {
+		app.logger.Errorw("failed to serve media file", "key", key, "error", err.Error())
+	}
+}
+
+// loadMedia fills in the attachments of the posts, and of the posts they
+// embed, with a single query.
+func (app *application) loadMedia(ctx context.Context, posts ...*store.Post) error {
+	var ids []int64
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+		if p.Original != nil {
+			ids = append(ids, p.Original.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	media, err := app.store.Media.GetByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		p.Media = withMediaURLs(media[p.ID])
+		if p.Original != nil {
+			p.Original.Media = withMediaURLs(media[p.Original.ID])
+		}
+	}
+
+	return nil
+}
+
+// collectOrphanedMedia periodically deletes uploads that were never attached
+// to a post, or whose post was deleted, until ctx is cancelled.
+func (app *application) collectOrphanedMedia(ctx context.Context) {
+	ticker := time.NewTicker(app.config.media.gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.deleteOrphanedMedia(ctx); err != nil {
+				app.logger.Errorw("failed to collect orphaned media", "error", err.Error())
+			}
+		}
+	}
+}
+
+func (app *application) deleteOrphanedMedia(ctx context.Context) error {
+	orphans, err := app.store.Media.GetOrphaned(ctx, app.config.media.orphanAge, orphanBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range orphans {
+		// Remove the row first, a file without a row is harmless but a row
+		// without a file would be served as a broken attachment
+		if err := app.store.Media.Delete(ctx, m.ID); err != nil {
+			// Attached in the meantime
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return err
+		}
+
+		if err := app.blobStore.Delete(ctx, m.StorageKey); err != nil {
+			app.logger.Errorw("failed to delete media file", "key", m.StorageKey, "error", err.Error())
+		}
+	}
+
+	if len(orphans) > 0 {
+		app.logger.Infow("deleted orphaned media", "count", len(orphans))
+	}
+
+	return nil
+}
+
+func withMediaURLs(media []*store.Media) []*store.Media {
+	for _, m := range media {
+		m.URL = mediaURL(m.StorageKey)
+	}
+
+	return media
+}
+
+func mediaURL(key string) string {
+	return mediaFilesPath + key
+}
+
+func newMediaKey(extension string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	name := hex.EncodeToString(b)
+
+	// Spread files over directories so none grows too large
+	return fmt.Sprintf("%s/%s/%s.%s", name[:2], name[2:4], name, extension), nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/qwerqy/social-api-go/internal/entities"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// GetMentions godoc
+//
+//	@Summary		Lists mentions
+//	@Description	Lists the posts and comments mentioning the current user, newest first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Success		200		{object}	[]store.Mention
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/mentions [get]
+func (app *application) getMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	kq := store.KeysetPaginatedQuery{
+		Limit: 20,
+	}
+
+	kq, err := kq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(kq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	mentions, err := app.store.Mentions.GetByUserID(r.Context(), user.ID, kq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := keysetCursors(app, mentions, kq.Cursor, kq.Limit, func(m *store.Mention) (int64, string) {
+		return m.ID, m.CreatedAt
+	})
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, mentions, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// parseEntities finds the mentions and hashtags in content. Mentions are
+// resolved to users, or dropped, when the post or comment is saved.
+func parseEntities(content string) []store.Entity {
+	var parsed []store.Entity
+
+	for _, m := range entities.Mentions(content) {
+		parsed = append(parsed, store.Entity{
+			Type:     store.EntityMention,
+			Offset:   m.Offset,
+			Length:   m.Length,
+			Username: m.Username,
+		})
+	}
+
+	return withHashtags(parsed, content)
+}
+
+// withHashtags adds the hashtags of content to its mention entities. Unlike
+// mentions they need no lookup so they aren't stored.
+func withHashtags(mentions []store.Entity, content string) []store.Entity {
+	all := mentions
+
+	for _, h := range entities.Hashtags(content) {
+		all = append(all, store.Entity{
+			Type:   store.EntityHashtag,
+			Offset: h.Offset,
+			Length: h.Length,
+			Tag:    h.Tag,
+		})
+	}
+
+	slices.SortFunc(all, func(a, b store.Entity) int {
+		return a.Offset - b.Offset
+	})
+
+	return all
+}
+
+// loadPostEntities fills in the entities of the posts and of the posts they
+// embed with a single query.
+func (app *application) loadPostEntities(ctx context.Context, posts ...*store.Post) error {
+	var ids []int64
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+		if p.Original != nil {
+			ids = append(ids, p.Original.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	mentions, err := app.store.Mentions.GetByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		p.Entities = withHashtags(mentions[p.ID], p.Content)
+		if p.Original != nil {
+			p.Original.Entities = withHashtags(mentions[p.Original.ID], p.Original.Content)
+		}
+	}
+
+	return nil
+}
+
+// loadCommentEntities fills in the entities of the comments and all of their
+// nested replies with a single query.
+func (app *application) loadCommentEntities(ctx context.Context, comments []*store.Comment) error {
+	var ids []int64
+	walkComments(comments, func(c *store.Comment) {
+		ids = append(ids, c.ID)
+	})
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	mentions, err := app.store.Mentions.GetByCommentIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	walkComments(comments, func(c *store.Comment) {
+		c.Entities = withHashtags(mentions[c.ID], c.Content)
+	})
+
+	return nil
+}
+
+func walkComments(comments []*store.Comment, fn func(*store.Comment)) {
+	for _, c := range comments {
+		fn(c)
+		walkComments(c.Replies, fn)
+	}
+}
@@ -26,35 +26,85 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		token := parts[1]
-		jwtToken, err := app.authenticator.ValidateToken(token)
-		if err != nil {
-			app.unauthorizedError(w, r, err)
-			return
-		}
+		app.authenticateToken(w, r, next, parts[1])
+	})
+}
 
-		claims := jwtToken.Claims.(jwt.MapClaims)
+type accessTokenKey string
 
-		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
-		if err != nil {
-			app.unauthorizedError(w, r, err)
+const accessTokenCtx accessTokenKey = "access_token"
+
+// StripAccessTokenMiddleware takes the access_token parameter out of the URL
+// and keeps it in the context for StreamAuthMiddleware. It runs before the
+// request is logged, so tokens don't end up in the access logs.
+func (app *application) StripAccessTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qs := r.URL.Query()
+		if !qs.Has("access_token") {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		ctx := r.Context()
+		token := qs.Get("access_token")
+		qs.Del("access_token")
 
-		user, err := app.getUser(ctx, userID)
+		r = r.WithContext(context.WithValue(r.Context(), accessTokenCtx, token))
 
-		if err != nil {
-			app.unauthorizedError(w, r, err)
+		// WithContext shares the URL with the original request
+		u := *r.URL
+		u.RawQuery = qs.Encode()
+		r.URL = &u
+		r.RequestURI = u.RequestURI()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StreamAuthMiddleware authenticates like AuthTokenMiddleware, or with the
+// token in the access_token parameter. Browsers can't set headers when they
+// open an EventSource or a WebSocket.
+func (app *application) StreamAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := r.Context().Value(accessTokenCtx).(string)
+		if token == "" {
+			app.AuthTokenMiddleware(next).ServeHTTP(w, r)
 			return
 		}
 
-		ctx = context.WithValue(ctx, userCtx, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		app.authenticateToken(w, r, next, token)
 	})
 }
 
+// authenticateToken serves the request with next as the user token was
+// issued to.
+func (app *application) authenticateToken(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	jwtToken, err := app.authenticator.ValidateToken(token)
+	if err != nil {
+		app.unauthorizedError(w, r, err)
+		return
+	}
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+
+	userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
+	if err != nil {
+		app.unauthorizedError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.getUser(ctx, userID)
+
+	if err != nil {
+		app.unauthorizedError(w, r, err)
+		return
+	}
+
+	ctx = context.WithValue(ctx, userCtx, user)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -122,6 +172,40 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 	})
 }
 
+// checkCommentOwnership lets the comment author through, as well as the author
+// of the post, so people can moderate the threads under their own posts.
+// Anyone else needs at least requiredRole.
+func (app *application) checkCommentOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromCtx(r)
+		comment := getCommentFromCtx(r)
+
+		if comment.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if post := getPostFromCtx(r); post.UserID == user.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenError(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/mute"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type MuteWordPayload struct {
+	Kind    string `json:"kind" validate:"required,oneof=keyword regex hashtag"`
+	Pattern string `json:"pattern" validate:"required,max=100"`
+	// Scopes are where the word is muted, all of them when left out
+	Scopes []string `json:"scopes" validate:"max=3,dive,oneof=feed notifications comments"`
+	// ExpiresAt unmutes the word at that time, it stays muted without one
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetMutedWords godoc
+//
+//	@Summary		Lists muted words
+//	@Description	Lists the current user's muted words that haven't expired, newest first
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]store.MutedWord
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/muted-words [get]
+func (app *application) getMutedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	words, err := app.store.MutedWords.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, words); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// MuteWord godoc
+//
+//	@Summary		Mutes a word
+//	@Description	Hides the posts, comments and notifications matching a keyword or phrase, a regular expression or a hashtag from the current user, without blocking anyone. Keywords match whole words regardless of case.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		MuteWordPayload	true	"Mute Word Payload"
+//	@Success		201		{object}	store.MutedWord
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/muted-words [post]
+func (app *application) muteWordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MuteWordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	pattern, err := mute.Normalize(payload.Kind, payload.Pattern)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	scopes := payload.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{store.MuteScopeFeed, store.MuteScopeNotifications, store.MuteScopeComments}
+	}
+
+	user := getUserFromCtx(r)
+
+	word := &store.MutedWord{
+		UserID:  user.ID,
+		Kind:    payload.Kind,
+		Pattern: pattern,
+		Scopes:  scopes,
+	}
+
+	if payload.ExpiresAt != nil {
+		if !payload.ExpiresAt.After(time.Now()) {
+			app.badRequestError(w, r, errors.New("expires_at must be in the future"))
+			return
+		}
+
+		expiresAt := payload.ExpiresAt.UTC().Format(time.RFC3339)
+		word.ExpiresAt = &expiresAt
+	}
+
+	if err := app.store.MutedWords.Create(r.Context(), word); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, errors.New("that pattern is already muted"))
+		case errors.Is(err, store.ErrMutedWordLimit):
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, word); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UnmuteWord godoc
+//
+//	@Summary		Unmutes a word
+//	@Description	Deletes one of the current user's muted words
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			mutedWordID	path		int	true	"Muted word ID"
+//	@Success		204			{object}	string
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/muted-words/{mutedWordID} [delete]
+func (app *application) unmuteWordHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	wordID, err := strconv.ParseInt(chi.URLParam(r, "mutedWordID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.MutedWords.Delete(r.Context(), user.ID, wordID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// muteFilter returns what the words the user muted in scope hide, nil when
+// they hide nothing.
+func (app *application) muteFilter(ctx context.Context, userID int64, scope string) (*mute.Filter, error) {
+	words, err := app.store.MutedWords.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return mute.New(userID, words, scope)
+}
+
+// feedMutes has fq leave out the posts the user muted in their feed, and
+// returns the filter of the mutes the query can't apply, which is nil when
+// there are none.
+func (app *application) feedMutes(ctx context.Context, userID int64, fq *store.PaginatedFeedQuery) (*mute.Filter, error) {
+	filter, err := app.muteFilter(ctx, userID, store.MuteScopeFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	var rest *mute.Filter
+	fq.Mutes, rest = filter.Query()
+
+	return rest, nil
+}
+
+// muteComments empties the comments, replies included, matching the words
+// the user muted. They keep their place so threads still read in order.
+func (app *application) muteComments(ctx context.Context, userID int64, comments []*store.Comment) error {
+	filter, err := app.muteFilter(ctx, userID, store.MuteScopeComments)
+	if err != nil || filter == nil {
+		return err
+	}
+
+	walkComments(comments, func(c *store.Comment) {
+		if filter.HidesComment(c) {
+			c.Muted = true
+			c.Content = ""
+			c.Entities = nil
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type UnreadCount struct {
+	Count int `json:"count"`
+}
+
+// GetNotifications godoc
+//
+//	@Summary		Lists notifications
+//	@Description	Lists the current user's notifications, newest first. Similar notifications from the same day are grouped
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Param			unread	query		bool	false	"Only unread notifications"
+//	@Success		200		{object}	[]store.Notification
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications [get]
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	nq := store.NotificationQuery{
+		KeysetPaginatedQuery: store.KeysetPaginatedQuery{
+			Limit: 20,
+		},
+	}
+
+	nq, err := nq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(nq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	notifications, err := app.store.Notifications.GetByUserID(r.Context(), user.ID, nq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := keysetCursors(app, notifications, nq.Cursor, nq.Limit, func(n *store.Notification) (int64, string) {
+		return n.ID, n.CreatedAt
+	})
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, notifications, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUnreadNotificationCount godoc
+//
+//	@Summary		Counts unread notifications
+//	@Description	Counts the current user's unread notifications, grouped ones counting once
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	UnreadCount
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/unread-count [get]
+func (app *application) getUnreadNotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	count, err := app.store.Notifications.CountUnread(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, UnreadCount{Count: count}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// MarkNotificationRead godoc
+//
+//	@Summary		Marks a notification as read
+//	@Description	Marks a notification, and the ones grouped with it, as read
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			notificationID	path	int	true	"Notification ID"
+//	@Success		204				{object}	string
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/{notificationID}/read [put]
+func (app *application) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	notificationID, err := strconv.ParseInt(chi.URLParam(r, "notificationID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Notifications.MarkRead(r.Context(), user.ID, notificationID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// MarkAllNotificationsRead godoc
+//
+//	@Summary		Marks all notifications as read
+//	@Description	Marks all of the current user's notifications as read
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Success		204	{object}	string
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/read-all [put]
+func (app *application) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	if err := app.store.Notifications.MarkAllRead(r.Context(), user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// subscribeNotifications creates the notifications of the events published
+// on bus.
+func (app *application) subscribeNotifications(bus *events.Bus) {
+	bus.Subscribe(app.notifyFollow, events.UserFollowed)
+	bus.Subscribe(app.notifyPost, events.PostCreated)
+	bus.Subscribe(app.notifyComment, events.CommentCreated)
+	bus.Subscribe(app.notifyReaction, events.PostReacted)
+}
+
+// notify creates a notification and pushes it to the user's streams.
+func (app *application) notify(ctx context.Context, n *store.Notification) error {
+	if err := app.store.Notifications.Create(ctx, n); err != nil {
+		return err
+	}
+
+	// Grouped with one the user was already notified of
+	if n.ID == 0 {
+		return nil
+	}
+
+	return app.streamNotification(ctx, n)
+}
+
+func (app *application) notifyFollow(ctx context.Context, e events.Event) error {
+	return app.notify(ctx, &store.Notification{
+		Type:    store.NotificationFollow,
+		UserID:  e.UserID,
+		ActorID: e.ActorID,
+	})
+}
+
+func (app *application) notifyReaction(ctx context.Context, e events.Event) error {
+	return app.notify(ctx, &store.Notification{
+		Type:    store.NotificationReaction,
+		UserID:  e.UserID,
+		ActorID: e.ActorID,
+		PostID:  &e.PostID,
+	})
+}
+
+// notifyPost tells the author of a reposted or quoted post, and the users
+// mentioned in a new post.
+func (app *application) notifyPost(ctx context.Context, e events.Event) error {
+	post, err := app.store.Posts.GetByID(ctx, e.PostID)
+	if err != nil {
+		return err
+	}
+
+	var notifications []*store.Notification
+
+	if post.OriginalID != nil {
+		original, err := app.store.Posts.GetByID(ctx, *post.OriginalID)
+		if err != nil {
+			return err
+		}
+
+		switch post.Kind {
+		case store.PostKindRepost:
+			// Reposts have no content of their own to mention anyone
+			return app.notify(ctx, &store.Notification{
+				Type:    store.NotificationRepost,
+				UserID:  original.UserID,
+				ActorID: post.UserID,
+				PostID:  &original.ID,
+			})
+		case store.PostKindQuote:
+			notifications = append(notifications, &store.Notification{
+				Type:    store.NotificationQuote,
+				UserID:  original.UserID,
+				ActorID: post.UserID,
+				PostID:  &post.ID,
+			})
+		}
+	}
+
+	mentions, err := app.store.Mentions.GetByPostIDs(ctx, []int64{post.ID})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mentions[post.ID] {
+		notifications = append(notifications, &store.Notification{
+			Type:    store.NotificationMention,
+			UserID:  m.UserID,
+			ActorID: post.UserID,
+			PostID:  &post.ID,
+		})
+	}
+
+	return app.createNotifications(ctx, notifications, post.Title+" "+post.Content, post.Tags)
+}
+
+// notifyComment tells the author of the post and of the comment replied to,
+// and the users mentioned in a new comment.
+func (app *application) notifyComment(ctx context.Context, e events.Event) error {
+	comment, err := app.store.Comments.GetByID(ctx, e.CommentID)
+	if err != nil {
+		return err
+	}
+
+	post, err := app.store.Posts.GetByID(ctx, comment.PostID)
+	if err != nil {
+		return err
+	}
+
+	var notifications []*store.Notification
+
+	// A reply to the post's author is notified as a reply
+	if comment.ParentID != nil {
+		parent, err := app.store.Comments.GetByID(ctx, *comment.ParentID)
+		if err != nil {
+			return err
+		}
+
+		notifications = append(notifications, &store.Notification{
+			Type:      store.NotificationReply,
+			UserID:    parent.UserID,
+			ActorID:   comment.UserID,
+			PostID:    &post.ID,
+			CommentID: &comment.ID,
+		})
+	}
+
+	notifications = append(notifications, &store.Notification{
+		Type:      store.NotificationComment,
+		UserID:    post.UserID,
+		ActorID:   comment.UserID,
+		PostID:    &post.ID,
+		CommentID: &comment.ID,
+	})
+
+	mentions, err := app.store.Mentions.GetByCommentIDs(ctx, []int64{comment.ID})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mentions[comment.ID] {
+		notifications = append(notifications, &store.Notification{
+			Type:      store.NotificationMention,
+			UserID:    m.UserID,
+			ActorID:   comment.UserID,
+			PostID:    &post.ID,
+			CommentID: &comment.ID,
+		})
+	}
+
+	return app.createNotifications(ctx, notifications, comment.Content, nil)
+}
+
+// createNotifications creates notifications about the same content, only
+// the first one for each user. Users who muted a word of the content in
+// notifications aren't notified.
+func (app *application) createNotifications(ctx context.Context, notifications []*store.Notification, text string, tags []string) error {
+	notified := make(map[int64]bool)
+
+	var errs []error
+	for _, n := range notifications {
+		if notified[n.UserID] || n.UserID == n.ActorID {
+			continue
+		}
+
+		notified[n.UserID] = true
+
+		filter, err := app.muteFilter(ctx, n.UserID, store.MuteScopeNotifications)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if filter.Matches(text, tags) {
+			continue
+		}
+
+		if err := app.notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
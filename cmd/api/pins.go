@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type ReorderPinsPayload struct {
+	// PostIDs are all of the pinned posts in their new order
+	PostIDs []int64 `json:"post_ids" validate:"max=3,unique,dive,gt=0"`
+}
+
+// PinPost godoc
+//
+//	@Summary		Pins a post
+//	@Description	Pins one of the current user's posts to the top of their profile, after the posts already pinned. Up to 3 posts can be pinned.
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	string
+//	@Failure		400	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/pin [put]
+func (app *application) pinPostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenError(w, r)
+		return
+	}
+
+	if post.Kind == store.PostKindRepost {
+		app.badRequestError(w, r, errors.New("reposts can't be pinned"))
+		return
+	}
+
+	if err := app.store.Pins.Pin(r.Context(), user.ID, post.ID); err != nil {
+		if errors.Is(err, store.ErrPinLimit) {
+			app.conflictError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UnpinPost godoc
+//
+//	@Summary		Unpins a post
+//	@Description	Removes a post from the top of the current user's profile
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	string
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/pin [delete]
+func (app *application) unpinPostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	if err := app.store.Pins.Unpin(r.Context(), user.ID, post.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ReorderPins godoc
+//
+//	@Summary		Reorders pinned posts
+//	@Description	Sets the order of the current user's pinned posts, every pinned post must be listed once
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ReorderPinsPayload	true	"Reorder Pins Payload"
+//	@Success		204		{object}	string
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/pins [put]
+func (app *application) reorderPinsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ReorderPinsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+
+	if err := app.store.Pins.Reorder(r.Context(), user.ID, payload.PostIDs); err != nil {
+		if errors.Is(err, store.ErrInvalidPins) {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func newPinTestApp(t *testing.T) (*application, http.Handler, *store.MockPinStore) {
+	t.Helper()
+
+	app := newTestApplication(t, config{})
+
+	posts := map[int64]*store.Post{}
+	for id := int64(1); id <= 5; id++ {
+		posts[id] = &store.Post{ID: id, UserID: 1, Kind: store.PostKindPost}
+	}
+	posts[6] = &store.Post{ID: 6, UserID: 2, Kind: store.PostKindPost}
+	app.store.Posts = &store.MockPostStore{Posts: posts}
+
+	return app, app.mount(), app.store.Pins.(*store.MockPinStore)
+}
+
+func TestPinPost(t *testing.T) {
+	app, mux, pins := newPinTestApp(t)
+
+	tests := []struct {
+		method string
+		postID int64
+		code   int
+	}{
+		{http.MethodPut, 1, http.StatusNoContent},
+		{http.MethodPut, 1, http.StatusNoContent},
+		{http.MethodPut, 2, http.StatusNoContent},
+		{http.MethodPut, 3, http.StatusNoContent},
+		{http.MethodPut, 4, http.StatusConflict},
+		{http.MethodPut, 6, http.StatusForbidden},
+		{http.MethodDelete, 4, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rr := executeRequest(newAuthRequest(t, app, tt.method, fmt.Sprintf("/v1/posts/%d/pin", tt.postID), ""), mux)
+		if rr.Code != tt.code {
+			t.Errorf("%s post %d: got %d, want %d", tt.method, tt.postID, rr.Code, tt.code)
+		}
+	}
+
+	if want := []int64{1, 2, 3}; !slices.Equal(pins.Pinned(1), want) {
+		t.Errorf("got pins %v, want %v", pins.Pinned(1), want)
+	}
+}
+
+func TestPinAfterUnpin(t *testing.T) {
+	app, mux, pins := newPinTestApp(t)
+
+	for _, id := range []int64{1, 2, 3} {
+		executeRequest(newAuthRequest(t, app, http.MethodPut, fmt.Sprintf("/v1/posts/%d/pin", id), ""), mux)
+	}
+
+	// Unpinning the first post leaves positions 1 and 2 taken, the next pin
+	// has to go after them rather than share a position with post 3
+	rr := executeRequest(newAuthRequest(t, app, http.MethodDelete, "/v1/posts/1/pin", ""), mux)
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodPut, "/v1/posts/4/pin", ""), mux)
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	if want := []int64{2, 3, 4}; !slices.Equal(pins.Pinned(1), want) {
+		t.Errorf("got pins %v, want %v", pins.Pinned(1), want)
+	}
+
+	positions := pins.Positions[1]
+	if positions[3] == positions[4] {
+		t.Errorf("posts 3 and 4 share position %d", positions[3])
+	}
+}
+
+func TestReorderPins(t *testing.T) {
+	app, mux, pins := newPinTestApp(t)
+
+	for _, id := range []int64{1, 2, 3} {
+		executeRequest(newAuthRequest(t, app, http.MethodPut, fmt.Sprintf("/v1/posts/%d/pin", id), ""), mux)
+	}
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"post_ids":[3,1]}`, http.StatusBadRequest},
+		{`{"post_ids":[3,1,1]}`, http.StatusBadRequest},
+		{`{"post_ids":[3,1,4]}`, http.StatusBadRequest},
+		{`{"post_ids":[3,1,2]}`, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPut, "/v1/users/me/pins", tt.body), mux)
+		if rr.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.body, rr.Code, tt.code)
+		}
+	}
+
+	if want := []int64{3, 1, 2}; !slices.Equal(pins.Pinned(1), want) {
+		t.Errorf("got pins %v, want %v", pins.Pinned(1), want)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+const maxPollDuration = time.Hour * 24 * 30 // 30 days
+
+type CreatePollPayload struct {
+	Options        []string  `json:"options" validate:"min=2,max=10,dive,required,max=100"`
+	MultipleChoice bool      `json:"multiple_choice"`
+	HideResults    bool      `json:"hide_results"`
+	ClosesAt       time.Time `json:"closes_at" validate:"required"`
+}
+
+type VotePollPayload struct {
+	OptionIDs []int64 `json:"option_ids" validate:"min=1,max=10,unique,dive,gt=0"`
+}
+
+// VotePoll godoc
+//
+//	@Summary		Votes on a poll
+//	@Description	Casts the current user's vote on the poll of a post, a user can only vote once
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Post ID"
+//	@Param			payload	body		VotePollPayload	true	"Vote Poll Payload"
+//	@Success		200		{object}	store.Poll
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/poll/votes [post]
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	var payload VotePollPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+	ctx := r.Context()
+
+	poll, err := app.store.Polls.GetByPostID(ctx, post.ID, user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !poll.MultipleChoice && len(payload.OptionIDs) > 1 {
+		app.badRequestError(w, r, fmt.Errorf("poll only allows a single choice"))
+		return
+	}
+
+	if err := app.store.Polls.Vote(ctx, poll.ID, user.ID, payload.OptionIDs); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictError(w, r, fmt.Errorf("already voted on this poll"))
+		case errors.Is(err, store.ErrPollClosed), errors.Is(err, store.ErrInvalidPollOption):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	poll, err = app.getPoll(ctx, post, user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, poll); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPoll loads the poll of a post as the user should see it. Results the
+// author chose to hide stay hidden from everyone else until the poll closes.
+func (app *application) getPoll(ctx context.Context, post *store.Post, user *store.User) (*store.Poll, error) {
+	poll, err := app.store.Polls.GetByPostID(ctx, post.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if poll.HideResults && !poll.Closed && post.UserID != user.ID {
+		poll.HideTallies()
+	}
+
+	return poll, nil
+}
+
+func newPoll(payload *CreatePollPayload) (*store.Poll, error) {
+	until := time.Until(payload.ClosesAt)
+	if until <= 0 {
+		return nil, fmt.Errorf("poll must close in the future")
+	}
+
+	if until > maxPollDuration {
+		return nil, fmt.Errorf("poll can't be open for longer than %v", maxPollDuration)
+	}
+
+	poll := &store.Poll{
+		MultipleChoice: payload.MultipleChoice,
+		HideResults:    payload.HideResults,
+		ClosesAt:       payload.ClosesAt,
+	}
+
+	for _, text := range payload.Options {
+		poll.Options = append(poll.Options, &store.PollOption{Text: text})
+	}
+
+	return poll, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestCreatePollPayload(t *testing.T) {
+	options := func(n int) []string {
+		opts := make([]string, n)
+		for i := range opts {
+			opts[i] = "option"
+		}
+		return opts
+	}
+
+	tests := []struct {
+		name     string
+		options  []string
+		closesAt time.Time
+		valid    bool
+	}{
+		{"two options", options(2), time.Now().Add(time.Hour), true},
+		{"ten options", options(10), time.Now().Add(time.Hour), true},
+		{"a single option", options(1), time.Now().Add(time.Hour), false},
+		{"eleven options", options(11), time.Now().Add(time.Hour), false},
+		{"an empty option", []string{"yes", ""}, time.Now().Add(time.Hour), false},
+		{"a long option", []string{"yes", strings.Repeat("a", 101)}, time.Now().Add(time.Hour), false},
+		{"closed already", options(2), time.Now().Add(-time.Hour), false},
+		{"open for too long", options(2), time.Now().Add(maxPollDuration + time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := &CreatePollPayload{Options: tt.options, ClosesAt: tt.closesAt}
+
+			err := Validate.Struct(payload)
+			if err == nil {
+				_, err = newPoll(payload)
+			}
+
+			if tt.valid && err != nil {
+				t.Errorf("expected a valid poll, got %v", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Error("expected the poll to be rejected")
+			}
+		})
+	}
+}
+
+func newPollTestApp(t *testing.T, poll *store.Poll) *application {
+	t.Helper()
+
+	app := newTestApplication(t, config{})
+
+	app.store.Posts.(*store.MockPostStore).Posts = map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Kind: store.PostKindPost, Visibility: store.VisibilityPublic},
+	}
+
+	if poll != nil {
+		app.store.Polls.(*store.MockPollStore).Polls = map[int64]*store.Poll{1: poll}
+	}
+
+	return app
+}
+
+func testPoll(multipleChoice, hideResults, closed bool) *store.Poll {
+	votes := func(n int) *int { return &n }
+	total := 3
+
+	return &store.Poll{
+		ID:             1,
+		PostID:         1,
+		MultipleChoice: multipleChoice,
+		HideResults:    hideResults,
+		Closed:         closed,
+		TotalVoters:    &total,
+		Options: []*store.PollOption{
+			{ID: 1, Text: "yes", Votes: votes(2)},
+			{ID: 2, Text: "no", Votes: votes(1)},
+		},
+	}
+}
+
+func TestVotePoll(t *testing.T) {
+	tests := []struct {
+		name    string
+		poll    *store.Poll
+		voteErr error
+		body    string
+		code    int
+	}{
+		{"single choice", testPoll(false, false, false), nil, `{"option_ids":[1]}`, http.StatusOK},
+		{"several choices on a single choice poll", testPoll(false, false, false), nil, `{"option_ids":[1,2]}`, http.StatusBadRequest},
+		{"several choices", testPoll(true, false, false), nil, `{"option_ids":[1,2]}`, http.StatusOK},
+		{"no choice", testPoll(false, false, false), nil, `{"option_ids":[]}`, http.StatusBadRequest},
+		{"the same choice twice", testPoll(true, false, false), nil, `{"option_ids":[1,1]}`, http.StatusBadRequest},
+		{"after close", testPoll(false, false, true), store.ErrPollClosed, `{"option_ids":[1]}`, http.StatusBadRequest},
+		{"an option of another poll", testPoll(false, false, false), store.ErrInvalidPollOption, `{"option_ids":[9]}`, http.StatusBadRequest},
+		{"a second time", testPoll(false, false, false), store.ErrConflict, `{"option_ids":[1]}`, http.StatusConflict},
+		{"a post without a poll", nil, nil, `{"option_ids":[1]}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newPollTestApp(t, tt.poll)
+			app.store.Polls.(*store.MockPollStore).VoteErr = tt.voteErr
+
+			req := newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/poll/votes", tt.body)
+			checkResponseCode(t, tt.code, executeRequest(req, app.mount()).Code)
+		})
+	}
+}
+
+func TestHiddenPollResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		poll   *store.Poll
+		author int64
+		hidden bool
+	}{
+		{"shown", testPoll(false, false, false), 2, false},
+		{"hidden until close", testPoll(false, true, false), 2, true},
+		{"hidden but closed", testPoll(false, true, true), 2, false},
+		{"hidden but to the author", testPoll(false, true, false), 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newPollTestApp(t, tt.poll)
+			app.store.Posts.(*store.MockPostStore).Posts[1].UserID = tt.author
+			mux := app.mount()
+
+			for _, req := range []*http.Request{
+				newAuthRequest(t, app, http.MethodGet, "/v1/posts/1", ""),
+				newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/poll/votes", `{"option_ids":[1]}`),
+			} {
+				rr := executeRequest(req, mux)
+				checkResponseCode(t, http.StatusOK, rr.Code)
+
+				var body struct {
+					Data json.RawMessage `json:"data"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+
+				poll := &store.Poll{}
+				if req.Method == http.MethodGet {
+					var post store.Post
+					if err := json.Unmarshal(body.Data, &post); err != nil {
+						t.Fatal(err)
+					}
+					poll = post.Poll
+				} else if err := json.Unmarshal(body.Data, poll); err != nil {
+					t.Fatal(err)
+				}
+
+				if poll == nil {
+					t.Fatalf("%s %s: expected a poll", req.Method, req.URL.Path)
+				}
+
+				hidden := poll.TotalVoters == nil && poll.Options[0].Votes == nil
+				if hidden != tt.hidden {
+					t.Errorf("%s %s: expected hidden results to be %v", req.Method, req.URL.Path, tt.hidden)
+				}
+			}
+		})
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/events"
 	"github.com/qwerqy/social-api-go/internal/store"
 )
 
@@ -16,14 +18,32 @@ type postKey string
 const postCtx postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title   string             `json:"title" validate:"required,max=100"`
+	Content string             `json:"content" validate:"required,max=1000"`
+	Tags    []string           `json:"tags" validate:"max=20,dive,max=100"`
+	Poll    *CreatePollPayload `json:"poll" validate:"omitempty"`
+	// ContentFormat is plain or markdown, defaults to plain
+	ContentFormat string `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	// A content warning always makes the post sensitive
+	ContentWarning string `json:"content_warning" validate:"max=200"`
+	Sensitive      bool   `json:"sensitive"`
+	// Visibility defaults to public
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public followers mentioned"`
+	// MediaIDs are uploads from POST /media, in display order
+	MediaIDs []int64 `json:"media_ids" validate:"max=4,unique,dive,gt=0"`
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=100"`
-	Content *string `json:"content" validate:"omitempty,max=1000"`
+	Title         *string `json:"title" validate:"omitempty,max=100"`
+	Content       *string `json:"content" validate:"omitempty,max=1000"`
+	ContentFormat *string `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	Visibility    *string `json:"visibility" validate:"omitempty,oneof=public followers mentioned"`
+	// ContentWarning and Sensitive are how moderators flag other users'
+	// posts
+	ContentWarning *string `json:"content_warning" validate:"omitempty,max=200"`
+	Sensitive      *bool   `json:"sensitive"`
+	// Tags replaces the tags of the post, hashtags in the content are kept
+	Tags *[]string `json:"tags" validate:"omitempty,max=20,dive,max=100"`
 }
 
 // CreatePost godoc
@@ -57,19 +77,51 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	fmt.Printf("%v", user)
 
 	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  user.ID,
+		Title:         payload.Title,
+		Content:       payload.Content,
+		ContentFormat: payload.ContentFormat,
+		UserID:        user.ID,
+		Visibility:    payload.Visibility,
+		MediaIDs:      payload.MediaIDs,
+		Entities:      parseEntities(payload.Content),
+	}
+
+	setPostTags(post, payload.Tags)
+	setContentWarning(post, payload.ContentWarning, payload.Sensitive, user.ID)
+
+	if payload.Poll != nil {
+		poll, err := newPoll(payload.Poll)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		post.Poll = poll
 	}
 
 	ctx := r.Context()
 
 	if err := app.store.Posts.Create(ctx, post); err != nil {
+		if errors.Is(err, store.ErrInvalidMedia) {
+			app.badRequestError(w, r, err)
+			return
+		}
+
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	withMediaURLs(post.Media)
+	app.saveRendered(ctx, post)
+	app.queueLinkPreview(post.Content)
+	app.pushToTimelines(ctx, post)
+
+	app.events.Publish(ctx, events.Event{
+		Type:    events.PostCreated,
+		ActorID: user.ID,
+		PostID:  post.ID,
+	})
+
 	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -79,7 +131,7 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // GetPost godoc
 //
 //	@Summary		Gets a post
-//	@Description	Gets a post
+//	@Description	Gets a post with its newest comments, comments_next_cursor continues them from /posts/{id}/comments
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -93,7 +145,12 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	comments, err := app.store.Comments.GetByPostID(
+		r.Context(),
+		post.ID,
+		store.KeysetPaginatedQuery{Limit: inlineComments},
+		defaultInlineReplies,
+	)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -101,6 +158,55 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post.Comments = comments
 
+	// The rest of the comments are listed from /posts/{id}/comments
+	post.CommentsNextCursor, _ = keysetCursors(app, comments, nil, inlineComments, func(c *store.Comment) (int64, string) {
+		return c.ID, c.CreatedAt
+	})
+
+	if err := app.loadCommentEntities(r.Context(), comments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+
+	if err := app.muteComments(r.Context(), user.ID, comments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.loadOriginal(r.Context(), post, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Bookmarking a repost saves the post it shares
+	bookmarkID := post.ID
+	if post.Kind == store.PostKindRepost && post.OriginalID != nil {
+		bookmarkID = *post.OriginalID
+	}
+
+	bookmarked, err := app.store.Bookmarks.Exists(r.Context(), user.ID, bookmarkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post.Bookmarked = bookmarked
+
+	poll, err := app.getPoll(r.Context(), post, user)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post.Poll = poll
+
+	if err := app.hydratePosts(r.Context(), post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -133,12 +239,21 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	app.removeFromTimelines(r.Context(), post)
+
+	app.events.Publish(r.Context(), events.Event{
+		Type:    events.PostDeleted,
+		ActorID: getUserFromCtx(r).ID,
+		PostID:  post.ID,
+		UserID:  post.UserID,
+	})
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Updates a post
-//	@Description	Updates a post by ID
+//	@Description	Updates a post by ID. Moderators can update other users' posts, to add a content warning for instance, which only a moderator can then change.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -146,6 +261,7 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 //	@Param			payload	body		UpdatePostPayload	true	"Update Post Payload"
 //	@Success		201		{object}	store.Post
 //	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
 //	@Failure		404		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
@@ -162,23 +278,84 @@ func (app *application) patchPostHandler(w http.ResponseWriter, r *http.Request)
 
 	if err := Validate.Struct(payload); err != nil {
 		app.badRequestError(w, r, err)
+		return
+	}
+
+	tags := post.ExplicitTags
+	if payload.Tags != nil {
+		tags = *payload.Tags
 	}
 
 	if payload.Content != nil {
 		post.Content = *payload.Content
 	}
 
+	setPostTags(post, tags)
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
 
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+
+	if payload.ContentFormat != nil {
+		post.ContentFormat = *payload.ContentFormat
+	}
+
 	ctx := r.Context()
 
+	warning, sensitive := post.ContentWarning, post.Sensitive
+	if payload.ContentWarning != nil {
+		warning = strings.TrimSpace(*payload.ContentWarning)
+	}
+	if payload.Sensitive != nil {
+		sensitive = *payload.Sensitive
+	}
+
+	if warning != post.ContentWarning || sensitive != post.Sensitive {
+		user := getUserFromCtx(r)
+
+		// A warning a moderator put on someone's post stays until a
+		// moderator takes it off
+		if post.WarnedBy != nil && *post.WarnedBy != post.UserID {
+			moderator, err := app.checkRolePrecedence(ctx, user, "moderator")
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !moderator {
+				app.forbiddenError(w, r)
+				return
+			}
+		}
+
+		setContentWarning(post, warning, sensitive, user.ID)
+	}
+
+	// Mentions are stored again from the content as it is now
+	post.Entities = parseEntities(post.Content)
+
 	if err := app.updatePost(ctx, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	app.saveRendered(ctx, post)
+
+	if payload.Content != nil {
+		app.queueLinkPreview(post.Content)
+	}
+
+	app.events.Publish(ctx, events.Event{
+		Type:    events.PostUpdated,
+		ActorID: getUserFromCtx(r).ID,
+		PostID:  post.ID,
+		UserID:  post.UserID,
+	})
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -197,7 +374,7 @@ func (app *application) postContextMiddleware(next http.Handler) http.Handler {
 
 		ctx := r.Context()
 
-		post, err := app.store.Posts.GetByID(ctx, id)
+		post, err := app.getVisiblePost(ctx, id, getUserFromCtx(r))
 
 		if err != nil {
 			if errors.Is(err, store.ErrNotFound) {
@@ -214,6 +391,80 @@ func (app *application) postContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// getVisiblePost loads a post the user is allowed to see. Posts hidden from
+// the user are reported as not found so their existence isn't revealed.
+// Moderators can see every post so they can act on it.
+func (app *application) getVisiblePost(ctx context.Context, id int64, user *store.User) (*store.Post, error) {
+	post, err := app.store.Posts.GetVisibleByID(ctx, id, user.ID)
+	if !errors.Is(err, store.ErrNotFound) {
+		return post, err
+	}
+
+	moderator, roleErr := app.checkRolePrecedence(ctx, user, "moderator")
+	if roleErr != nil {
+		return nil, roleErr
+	}
+
+	if !moderator {
+		return nil, err
+	}
+
+	return app.store.Posts.GetByID(ctx, id)
+}
+
+// hydratePosts loads what is stored alongside the posts, and the posts they
+// embed, for a response.
+func (app *application) hydratePosts(ctx context.Context, posts ...*store.Post) error {
+	if err := app.loadMedia(ctx, posts...); err != nil {
+		return err
+	}
+
+	if err := app.loadReactions(ctx, posts...); err != nil {
+		return err
+	}
+
+	if err := app.loadPostEntities(ctx, posts...); err != nil {
+		return err
+	}
+
+	var all []*store.Post
+	for _, p := range posts {
+		all = append(all, p)
+		if p.Original != nil {
+			all = append(all, p.Original)
+		}
+	}
+
+	// Rendering links the mentions, so it has to wait for the entities
+	for _, p := range all {
+		app.renderStale(p)
+	}
+
+	return app.loadLinkPreviews(ctx, all...)
+}
+
+func (app *application) hydratePostList(ctx context.Context, posts []*store.PostWithMetadata) error {
+	list := make([]*store.Post, len(posts))
+	for i, p := range posts {
+		list[i] = &p.Post
+	}
+
+	return app.hydratePosts(ctx, list...)
+}
+
+// setContentWarning flags a post, a post behind a content warning is always
+// sensitive. The flags are recorded as set by setterID, or by no one when
+// they're cleared.
+func setContentWarning(post *store.Post, warning string, sensitive bool, setterID int64) {
+	post.ContentWarning = strings.TrimSpace(warning)
+	post.Sensitive = sensitive || post.ContentWarning != ""
+
+	post.WarnedBy = nil
+	if post.Sensitive {
+		post.WarnedBy = &setterID
+	}
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	post, _ := r.Context().Value(postCtx).(*store.Post)
 	return post
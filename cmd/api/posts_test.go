@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestHiddenPostsAreNotFound(t *testing.T) {
+	const (
+		user      = 1
+		moderator = 2
+	)
+
+	tests := []struct {
+		name       string
+		visibility string
+		follows    bool
+		mentioned  bool
+		roleLevel  int64
+		code       int
+	}{
+		{"public", store.VisibilityPublic, false, false, user, http.StatusOK},
+		{"followers only to a follower", store.VisibilityFollowers, true, false, user, http.StatusOK},
+		{"followers only to anyone else", store.VisibilityFollowers, false, false, user, http.StatusNotFound},
+		{"followers only to a mentioned user", store.VisibilityFollowers, false, true, user, http.StatusNotFound},
+		{"mentioned to a mentioned user", store.VisibilityMentioned, false, true, user, http.StatusOK},
+		{"mentioned to a follower", store.VisibilityMentioned, true, false, user, http.StatusNotFound},
+		{"mentioned to anyone else", store.VisibilityMentioned, false, false, user, http.StatusNotFound},
+		{"followers only to a moderator", store.VisibilityFollowers, false, false, moderator, http.StatusOK},
+		{"mentioned to a moderator", store.VisibilityMentioned, false, false, moderator, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+
+			app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{
+				1: {ID: 1, Role: store.Role{Level: tt.roleLevel}},
+			}
+
+			posts := app.store.Posts.(*store.MockPostStore)
+			posts.Posts = map[int64]*store.Post{
+				1: {ID: 1, UserID: 2, Kind: store.PostKindPost, Visibility: tt.visibility},
+			}
+			// The rules of visibleTo, for a viewer that may follow the author
+			// or be mentioned in the post
+			posts.Visible = func(post *store.Post, viewerID int64) bool {
+				switch {
+				case post.UserID == viewerID, post.Visibility == store.VisibilityPublic:
+					return true
+				case post.Visibility == store.VisibilityFollowers:
+					return tt.follows
+				default:
+					return tt.mentioned
+				}
+			}
+
+			mux := app.mount()
+
+			// Every route under the post goes through postContextMiddleware,
+			// a hidden post must not be told apart from a missing one
+			for _, req := range []*http.Request{
+				newAuthRequest(t, app, http.MethodGet, "/v1/posts/1", ""),
+				newAuthRequest(t, app, http.MethodGet, "/v1/posts/1/comments", ""),
+				newAuthRequest(t, app, http.MethodPut, "/v1/posts/1/bookmark", ""),
+			} {
+				rr := executeRequest(req, mux)
+				if rr.Code != tt.code {
+					t.Errorf("%s %s: expected response code %d, got %d", req.Method, req.URL.Path, tt.code, rr.Code)
+				}
+			}
+
+			// Editing someone else's post is forbidden, but only once the
+			// post is known to be visible
+			if tt.roleLevel == user {
+				want := http.StatusForbidden
+				if tt.code == http.StatusNotFound {
+					want = http.StatusNotFound
+				}
+
+				req := newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1", `{"content":"edited"}`)
+				checkResponseCode(t, want, executeRequest(req, mux).Code)
+			}
+		})
+	}
+}
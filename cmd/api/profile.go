@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// GetUserPosts godoc
+//
+//	@Summary		Lists a user's posts
+//	@Description	Lists the posts of a user that the current user can see, newest first. Private accounts only list their posts to their approved followers, and the profile of a user who blocked the current user, or was blocked by them, isn't found. The first page starts with the posts the user pinned, in their order and marked as pinned; filters don't apply to them. Pinned posts aren't repeated further down that page, which can hold up to limit plus 3 posts.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"User ID"
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			cursor			query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Param			tags			query		string	false	"Comma separated tags the posts must all have"
+//	@Param			search			query		string	false	"Full-text search, in web search syntax"
+//	@Param			exclude_replies	query		bool	false	"Leave out posts starting with a mention"
+//	@Param			exclude_reposts	query		bool	false	"Leave out reposts"
+//	@Param			only_media		query		bool	false	"Only posts with media"
+//	@Success		200				{object}	[]store.PostWithMetadata
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	uq := store.UserPostsQuery{
+		KeysetPaginatedQuery: store.KeysetPaginatedQuery{Limit: 20},
+	}
+
+	uq, err = uq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(uq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.getUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	viewer := getUserFromCtx(r)
+
+	if viewer.ID != userID {
+		blocked, err := app.store.Blocks.IsBlocked(ctx, viewer.ID, userID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if blocked {
+			app.notFoundError(w, r, store.ErrNotFound)
+			return
+		}
+
+		if user.IsPrivate {
+			following, err := app.store.Followers.IsFollowing(ctx, viewer.ID, userID)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !following {
+				if err := app.keysetJSONResponse(w, r, http.StatusOK, []*store.PostWithMetadata{}, "", ""); err != nil {
+					app.internalServerError(w, r, err)
+				}
+				return
+			}
+		}
+	}
+
+	posts, err := app.store.Posts.GetByUserID(ctx, userID, viewer.ID, uq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := app.pageCursors(posts, uq.Cursor, uq.Limit)
+
+	// Paging back to the start lands on the first page too
+	if uq.Cursor == nil || (uq.Cursor.Backward && prevCursor == "") {
+		pinned, err := app.store.Posts.GetPinned(ctx, userID, viewer.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		// The cursors are already worked out, dropping the pinned posts from
+		// the page doesn't move them
+		posts = append(pinned, withoutPosts(posts, pinned)...)
+	}
+
+	if err := app.hydratePostList(ctx, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, posts, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// withoutPosts returns posts without the ones also in others.
+func withoutPosts(posts, others []*store.PostWithMetadata) []*store.PostWithMetadata {
+	if len(others) == 0 {
+		return posts
+	}
+
+	skip := make(map[int64]bool, len(others))
+	for _, p := range others {
+		skip[p.ID] = true
+	}
+
+	kept := make([]*store.PostWithMetadata, 0, len(posts))
+	for _, p := range posts {
+		if !skip[p.ID] {
+			kept = append(kept, p)
+		}
+	}
+
+	return kept
+}
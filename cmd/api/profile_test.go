@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestPinnedPostsOnFirstPage(t *testing.T) {
+	post := func(id int64, pinned bool) *store.PostWithMetadata {
+		createdAt := time.Date(2026, 1, 1, 0, 0, int(id), 0, time.UTC).Format(time.RFC3339Nano)
+		return &store.PostWithMetadata{Post: store.Post{ID: id, UserID: 2, Pinned: pinned, CreatedAt: createdAt}}
+	}
+
+	app := newTestApplication(t, config{})
+	posts := app.store.Posts.(*store.MockPostStore)
+	// Post 4 is pinned and also recent enough to be on the first page
+	posts.UserPosts = []*store.PostWithMetadata{post(5, false), post(4, false), post(3, false)}
+	posts.Pinned = []*store.PostWithMetadata{post(4, true), post(1, true)}
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/users/2/posts?limit=3", ""), app.mount())
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	var body struct {
+		Data       []*store.PostWithMetadata `json:"data"`
+		NextCursor string                    `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, p := range body.Data {
+		ids = append(ids, p.ID)
+	}
+
+	if want := []int64{4, 1, 5, 3}; !slices.Equal(ids, want) {
+		t.Errorf("got posts %v, want %v", ids, want)
+	}
+
+	// The next page still starts after the last post read, post 3
+	cursor, err := app.cursors.Decode(body.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cursor.ID != 3 {
+		t.Errorf("next page starts after post %d, want 3", cursor.ID)
+	}
+}
+
+func TestUserPostsFilters(t *testing.T) {
+	tests := []struct {
+		query string
+		code  int
+		want  store.UserPostsQuery
+	}{
+		{"", http.StatusOK, store.UserPostsQuery{}},
+		{"tags=%23Go,rust,go", http.StatusOK, store.UserPostsQuery{Tags: []string{"go", "rust"}}},
+		{"search=gophers", http.StatusOK, store.UserPostsQuery{Search: "gophers"}},
+		{"exclude_replies=true", http.StatusOK, store.UserPostsQuery{ExcludeReplies: true}},
+		{"exclude_reposts=1", http.StatusOK, store.UserPostsQuery{ExcludeReposts: true}},
+		{"only_media=true&exclude_replies=false", http.StatusOK, store.UserPostsQuery{OnlyMedia: true}},
+		{"only_media=yes", http.StatusBadRequest, store.UserPostsQuery{}},
+		{"tags=a,b,c,d,e,f", http.StatusBadRequest, store.UserPostsQuery{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+			posts := app.store.Posts.(*store.MockPostStore)
+
+			rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/users/2/posts?"+tt.query, ""), app.mount())
+			checkResponseCode(t, tt.code, rr.Code)
+
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			got := posts.UserPostsQuery
+			if !slices.Equal(got.Tags, tt.want.Tags) || got.Search != tt.want.Search ||
+				got.ExcludeReplies != tt.want.ExcludeReplies || got.ExcludeReposts != tt.want.ExcludeReposts ||
+				got.OnlyMedia != tt.want.OnlyMedia {
+				t.Errorf("got filters %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserPostsOfPrivateAndBlockedUsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		following bool
+		blocked   bool
+		code      int
+		posts     int
+	}{
+		{"private account, not following", "2", false, false, http.StatusOK, 0},
+		{"private account, following", "2", true, false, http.StatusOK, 1},
+		{"own private account", "1", false, false, http.StatusOK, 1},
+		{"blocked user", "3", false, true, http.StatusNotFound, 0},
+		{"blocked user, following", "3", true, true, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+			app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{
+				1: {ID: 1, IsPrivate: true},
+				2: {ID: 2, IsPrivate: true},
+			}
+			app.store.Posts.(*store.MockPostStore).UserPosts = []*store.PostWithMetadata{
+				{Post: store.Post{ID: 1, UserID: 2}},
+			}
+
+			if tt.following {
+				app.store.Followers.(*store.MockFollowerStore).Following = map[int64]map[int64]bool{1: {2: true, 3: true}}
+			}
+
+			if tt.blocked {
+				app.store.Blocks.(*store.MockBlockStore).Blocks = map[int64]map[int64]bool{3: {1: true}}
+			}
+
+			rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/users/"+tt.userID+"/posts", ""), app.mount())
+			checkResponseCode(t, tt.code, rr.Code)
+
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Data []*store.PostWithMetadata `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(body.Data) != tt.posts {
+				t.Errorf("got %d posts, want %d", len(body.Data), tt.posts)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/ranking"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+const (
+	// rankingWindow is how old the posts of a ranked feed can be
+	rankingWindow = time.Hour * 72
+	// rankingCandidates is how many of the newest posts are ranked, the
+	// ranked feed ends after them
+	rankingCandidates = 500
+)
+
+// RankedPost is a post of a ranked feed. In debug mode it says how it was
+// scored.
+type RankedPost struct {
+	*store.PostWithMetadata
+	Ranking *ranking.Explanation `json:"ranking,omitempty"`
+}
+
+// GetRankedFeed godoc
+//
+//	@Summary		Gets the ranked user feed
+//	@Description	Gets the current user's feed ranked by what they are likely to care about rather than by time: recent posts of the users they follow and of the users those follow. Rankings change between requests, so the feed is paged by offset, next_offset continues it and is left out on the last page.
+//	@Tags			feed
+//	@Accept			json
+//	@Produce		json
+//	@Param			since	query		string	false	"Since, RFC 3339 or YYYY-MM-DD HH:MM:SS in UTC"
+//	@Param			until	query		string	false	"Until, RFC 3339 or YYYY-MM-DD HH:MM:SS in UTC"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset from next_offset"
+//	@Param			debug	query		bool	false	"Explain how each post was scored"
+//	@Param			tags	query		string	false	"Tags"
+//	@Param			search	query		string	false	"Search"
+//	@Success		200		{object}	[]RankedPost
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/feed/ranked [get]
+func (app *application) getRankedFeedHandler(w http.ResponseWriter, r *http.Request) {
+	fq := store.PaginatedFeedQuery{
+		Limit: 20,
+		Sort:  "ranked",
+	}
+
+	fq, err := fq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if fq.Sort != "ranked" {
+		app.badRequestError(w, r, errors.New("ranked feeds can't be sorted"))
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	app.writeRankedFeed(w, r, getUserFromCtx(r).ID, fq)
+}
+
+func (app *application) writeRankedFeed(w http.ResponseWriter, r *http.Request, userID int64, fq store.PaginatedFeedQuery) {
+	feed, nextOffset, err := app.rankedFeed(r.Context(), userID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.offsetJSONResponse(w, r, http.StatusOK, feed, nextOffset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// rankedFeed returns a page of the user's feed ranked by what they are
+// likely to care about, rather than by time, and the offset of the next
+// page, 0 when it is the last.
+func (app *application) rankedFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]RankedPost, int, error) {
+	mutes, err := app.feedMutes(ctx, userID, &fq)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	candidates, err := app.store.Posts.GetRankingCandidates(ctx, userID, fq, rankingWindow, rankingCandidates)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ranked := ranking.Rank(time.Now(), candidates, ranking.DefaultWeights)
+
+	start := min(fq.Offset, len(ranked))
+	end := min(start+fq.Limit, len(ranked))
+
+	var nextOffset int
+	if end < len(ranked) {
+		nextOffset = end
+	}
+
+	ranked = ranked[start:end]
+
+	ids := make([]int64, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.PostID
+	}
+
+	// Candidates are already unmuted
+	posts, err := app.store.Posts.GetVisibleByIDs(ctx, ids, userID, store.MuteQuery{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := app.hydratePostList(ctx, posts); err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[int64]*store.PostWithMetadata, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	feed := make([]RankedPost, 0, len(ranked))
+	for _, r := range ranked {
+		p, ok := byID[r.PostID]
+		if !ok || mutes.HidesPost(&p.Post) {
+			continue
+		}
+
+		item := RankedPost{PostWithMetadata: p}
+		if fq.Debug {
+			item.Ranking = &r.Explanation
+		}
+
+		feed = append(feed, item)
+	}
+
+	return feed, nextOffset, nil
+}
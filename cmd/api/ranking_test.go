@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/ranking"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type rankedPage struct {
+	Data []struct {
+		ID      int64                `json:"id"`
+		Ranking *ranking.Explanation `json:"ranking"`
+	} `json:"data"`
+	NextOffset int `json:"next_offset"`
+}
+
+func TestRankedFeedPages(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	now := time.Now()
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.Posts = map[int64]*store.Post{}
+	for id := int64(1); id <= 3; id++ {
+		posts.Posts[id] = &store.Post{ID: id, UserID: id + 1, Kind: store.PostKindPost, Visibility: store.VisibilityPublic}
+		posts.Candidates = append(posts.Candidates, ranking.Candidate{
+			PostID:    id,
+			AuthorID:  id + 1,
+			CreatedAt: now.Add(-time.Duration(id) * time.Hour),
+			Followed:  true,
+		})
+	}
+
+	getPage := func(url string) (rankedPage, http.Header) {
+		t.Helper()
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodGet, url, ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var page rankedPage
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+
+		return page, rr.Header()
+	}
+
+	for _, url := range []string{"/v1/users/feed/ranked?limit=2", "/v1/users/feed?sort=ranked&limit=2"} {
+		page, header := getPage(url)
+		if len(page.Data) != 2 || page.NextOffset != 2 {
+			t.Errorf("%s: got %d posts and next offset %d, want 2 and 2", url, len(page.Data), page.NextOffset)
+		}
+
+		if header.Get("Link") == "" {
+			t.Errorf("%s: got no Link to the next page", url)
+		}
+
+		if page.Data[0].Ranking != nil {
+			t.Errorf("%s: got a ranking explanation outside debug mode", url)
+		}
+	}
+
+	page, header := getPage("/v1/users/feed/ranked?limit=2&offset=2&debug=true")
+	if len(page.Data) != 1 || page.NextOffset != 0 || header.Get("Link") != "" {
+		t.Errorf("got %d posts, next offset %d and Link %q on the last page, want 1 post and no next page", len(page.Data), page.NextOffset, header.Get("Link"))
+	}
+
+	if page.Data[0].Ranking == nil {
+		t.Error("got no ranking explanation in debug mode")
+	}
+
+	cursor := app.cursors.Encode(store.Cursor{CreatedAt: now, ID: 3})
+	for _, query := range []string{"sort=desc", "cursor=" + cursor, "debug=maybe"} {
+		rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/users/feed/ranked?"+query, ""), mux)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want %d", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
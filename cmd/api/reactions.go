@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type ReactPostPayload struct {
+	Kind string `json:"kind" validate:"required,oneof=like love laugh wow sad angry"`
+}
+
+// ReactPost godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Leaves a reaction on a post, replacing the current user's previous one
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		ReactPostPayload	true	"React Post Payload"
+//	@Success		200		{object}	store.Reaction
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reaction [put]
+func (app *application) reactPostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ReactPostPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	// Reacting to a repost reacts to the post it shares
+	post, err := app.resolveOriginal(ctx, getPostFromCtx(r), user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	reaction := &store.Reaction{
+		PostID: post.ID,
+		UserID: user.ID,
+		Kind:   payload.Kind,
+	}
+
+	if err := app.store.Reactions.Set(ctx, reaction); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.events.Publish(ctx, events.Event{
+		Type:     events.PostReacted,
+		ActorID:  user.ID,
+		PostID:   post.ID,
+		UserID:   post.UserID,
+		Reaction: reaction.Kind,
+	})
+
+	if err := app.jsonResponse(w, http.StatusOK, reaction); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteReaction godoc
+//
+//	@Summary		Removes a reaction
+//	@Description	Removes the current user's reaction from a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	string
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reaction [delete]
+func (app *application) deleteReactionHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	postID := post.ID
+	if post.Kind == store.PostKindRepost && post.OriginalID != nil {
+		postID = *post.OriginalID
+	}
+
+	if err := app.store.Reactions.Delete(r.Context(), postID, user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// loadReactions fills in the reaction counts of the posts and of the posts
+// they embed with a single query.
+func (app *application) loadReactions(ctx context.Context, posts ...*store.Post) error {
+	var ids []int64
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+		if p.Original != nil {
+			ids = append(ids, p.Original.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	counts, err := app.store.Reactions.GetCountsByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		p.Reactions = counts[p.ID]
+		if p.Original != nil {
+			p.Original.Reactions = counts[p.Original.ID]
+		}
+	}
+
+	return nil
+}
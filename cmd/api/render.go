@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/qwerqy/social-api-go/internal/markdown"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+const renderBatchSize = 100
+
+// renderPost renders the content of the post as of its current version. Its
+// entities must be loaded for the mentions to be linked.
+func (app *application) renderPost(post *store.Post) {
+	mentioned := make(map[string]int64)
+	for _, e := range post.Entities {
+		if e.Type == store.EntityMention && e.UserID != 0 {
+			mentioned[e.Username] = e.UserID
+		}
+	}
+
+	opts := markdown.Options{
+		MentionURL: func(username string) string {
+			if id, ok := mentioned[username]; ok {
+				return fmt.Sprintf("%s/users/%d", app.config.frontendURL, id)
+			}
+			return ""
+		},
+		HashtagURL: func(tag string) string {
+			return app.config.frontendURL + "/tags/" + url.PathEscape(tag)
+		},
+	}
+
+	if post.ContentFormat == store.ContentFormatMarkdown {
+		post.ContentHTML = markdown.Render(post.Content, opts)
+	} else {
+		post.ContentHTML = markdown.RenderText(post.Content, opts)
+	}
+
+	post.RenderedVersion = post.Version
+}
+
+// saveRendered renders posts that were just written and stores the result.
+// It runs after the write so the mentions are resolved, a failure only leaves
+// the post to be rendered on read until the backfill gets to it.
+func (app *application) saveRendered(ctx context.Context, posts ...*store.Post) {
+	var rendered []*store.Post
+	for _, p := range posts {
+		if p.Content == "" {
+			continue
+		}
+
+		app.renderPost(p)
+		rendered = append(rendered, p)
+	}
+
+	if len(rendered) == 0 {
+		return
+	}
+
+	if err := app.store.Posts.SaveRendered(ctx, rendered); err != nil {
+		app.logger.Errorw("failed to save rendered posts", "error", err.Error())
+	}
+}
+
+// renderStale renders a post being read whose stored rendering is missing or
+// older than its current version, without saving it.
+func (app *application) renderStale(post *store.Post) {
+	if post.Content == "" || post.RenderedVersion == post.Version {
+		return
+	}
+
+	app.renderPost(post)
+}
+
+// backfillRendered renders and stores every post whose current version hasn't
+// been rendered, a batch at a time, until there are none left or ctx is
+// cancelled.
+func (app *application) backfillRendered(ctx context.Context) error {
+	var afterID int64
+	total := 0
+
+	for ctx.Err() == nil {
+		posts, err := app.store.Posts.GetUnrendered(ctx, afterID, renderBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(posts) == 0 {
+			break
+		}
+
+		if err := app.loadPostEntities(ctx, posts...); err != nil {
+			return err
+		}
+
+		for _, p := range posts {
+			app.renderPost(p)
+		}
+
+		if err := app.store.Posts.SaveRendered(ctx, posts); err != nil {
+			return err
+		}
+
+		afterID = posts[len(posts)-1].ID
+		total += len(posts)
+	}
+
+	if total > 0 {
+		app.logger.Infow("rendered posts", "count", total)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/qwerqy/social-api-go/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePostSavesRendering(t *testing.T) {
+	app := newTestApplication(t, config{})
+	posts := app.store.Posts.(*store.MockPostStore)
+
+	body := `{"title":"t","content":"**bold** #go","content_format":"markdown"}`
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts", body), app.mount())
+	checkResponseCode(t, http.StatusCreated, rr.Code)
+
+	post := decodePost(t, rr.Body.Bytes())
+	if !strings.Contains(post.ContentHTML, "<strong>bold</strong>") {
+		t.Errorf("got content_html %q, want the markdown rendered", post.ContentHTML)
+	}
+
+	if !slices.Equal(posts.Rendered, []int64{post.ID}) {
+		t.Errorf("got renderings saved for %v, want post %d", posts.Rendered, post.ID)
+	}
+}
+
+func TestPatchPostSavesRendering(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.cacheStorage.Users.(*cache.MockUserStore).On("Delete", mock.Anything)
+
+	posts := &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 1, Content: "old", ContentFormat: store.ContentFormatPlain, ContentHTML: "<p>old</p>"},
+	}}
+	app.store.Posts = posts
+
+	body := `{"content":"*new*","content_format":"markdown"}`
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1", body), app.mount())
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	if post := decodePost(t, rr.Body.Bytes()); !strings.Contains(post.ContentHTML, "<em>new</em>") {
+		t.Errorf("got content_html %q, want the edited content rendered", post.ContentHTML)
+	}
+
+	if !slices.Equal(posts.Rendered, []int64{1}) {
+		t.Errorf("got renderings saved for %v, want post 1", posts.Rendered)
+	}
+}
+
+func TestGetPostDoesNotSaveRendering(t *testing.T) {
+	tests := []struct {
+		name            string
+		renderedVersion int64
+		want            string
+	}{
+		{"rendered", 2, "<p>stored</p>"},
+		{"rendered from an older version", 1, "<strong>bold</strong>"},
+		{"never rendered", -1, "<strong>bold</strong>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+			posts := &store.MockPostStore{Posts: map[int64]*store.Post{
+				1: {
+					ID:              1,
+					UserID:          2,
+					Content:         "**bold**",
+					ContentFormat:   store.ContentFormatMarkdown,
+					ContentHTML:     "<p>stored</p>",
+					Version:         2,
+					RenderedVersion: tt.renderedVersion,
+				},
+			}}
+			app.store.Posts = posts
+
+			rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/posts/1", ""), app.mount())
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if post := decodePost(t, rr.Body.Bytes()); !strings.Contains(post.ContentHTML, tt.want) {
+				t.Errorf("got content_html %q, want %q", post.ContentHTML, tt.want)
+			}
+
+			if len(posts.Rendered) != 0 {
+				t.Errorf("got renderings saved for %v on a read, want none", posts.Rendered)
+			}
+		})
+	}
+}
+
+func TestBackfillRendered(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	// More than a batch, with every third post rendered already and a repost
+	// with nothing to render
+	posts := &store.MockPostStore{Posts: map[int64]*store.Post{}}
+	for id := int64(1); id <= renderBatchSize*2+10; id++ {
+		post := &store.Post{ID: id, Content: fmt.Sprint("post ", id), Version: 1, RenderedVersion: -1}
+		if id%3 == 0 {
+			post.ContentHTML, post.RenderedVersion = "<p>done</p>", 1
+		}
+		posts.Posts[id] = post
+	}
+	posts.Posts[1].Content, posts.Posts[1].Kind = "", store.PostKindRepost
+	app.store.Posts = posts
+
+	if err := app.backfillRendered(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, post := range posts.Posts {
+		switch {
+		case id == 1:
+			if post.ContentHTML != "" {
+				t.Errorf("rendered the repost as %q", post.ContentHTML)
+			}
+		case id%3 == 0:
+			if post.ContentHTML != "<p>done</p>" {
+				t.Errorf("rendered post %d again", id)
+			}
+		case post.RenderedVersion != post.Version || !strings.Contains(post.ContentHTML, post.Content):
+			t.Errorf("got post %d rendered as %q from version %d, want its current version", id, post.ContentHTML, post.RenderedVersion)
+		}
+	}
+
+	if want := renderBatchSize*2 + 10 - 1 - (renderBatchSize*2+10)/3; len(posts.Rendered) != want {
+		t.Errorf("saved %d renderings, want %d", len(posts.Rendered), want)
+	}
+}
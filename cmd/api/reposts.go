@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+var errNotShareable = errors.New("only public posts of public accounts can be shared")
+
+type CreateQuotePayload struct {
+	Title         string   `json:"title" validate:"max=100"`
+	Content       string   `json:"content" validate:"required,max=1000"`
+	ContentFormat string   `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	Tags          []string `json:"tags" validate:"max=20,dive,max=100"`
+	Visibility    string   `json:"visibility" validate:"omitempty,oneof=public followers mentioned"`
+	// A content warning always makes the post sensitive
+	ContentWarning string `json:"content_warning" validate:"max=200"`
+	Sensitive      bool   `json:"sensitive"`
+}
+
+// RepostPost godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a post into the followers' feeds of the current user
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		201	{object}	store.Post
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [post]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	original, err := app.resolveOriginal(ctx, getPostFromCtx(r), user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.checkShareable(ctx, original); err != nil {
+		if errors.Is(err, errNotShareable) {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post := &store.Post{
+		UserID:     user.ID,
+		Kind:       store.PostKindRepost,
+		OriginalID: &original.ID,
+		Original:   original,
+	}
+
+	if err := app.store.Posts.Create(ctx, post); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.pushToTimelines(ctx, post)
+
+	app.events.Publish(ctx, events.Event{
+		Type:    events.PostCreated,
+		ActorID: user.ID,
+		PostID:  post.ID,
+	})
+
+	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// UndoRepost godoc
+//
+//	@Summary		Undoes a repost
+//	@Description	Removes the current user's repost of a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	string
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [delete]
+func (app *application) undoRepostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	post := getPostFromCtx(r)
+
+	originalID := post.ID
+	if post.Kind == store.PostKindRepost && post.OriginalID != nil {
+		originalID = *post.OriginalID
+	}
+
+	if err := app.store.Posts.DeleteRepost(r.Context(), user.ID, originalID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// QuotePost godoc
+//
+//	@Summary		Quotes a post
+//	@Description	Shares a post with the current user's own text
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		CreateQuotePayload	true	"Create Quote Payload"
+//	@Success		201		{object}	store.Post
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/quote [post]
+func (app *application) quotePostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateQuotePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	original, err := app.resolveOriginal(ctx, getPostFromCtx(r), user.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.checkShareable(ctx, original); err != nil {
+		if errors.Is(err, errNotShareable) {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	post := &store.Post{
+		Title:         payload.Title,
+		Content:       payload.Content,
+		ContentFormat: payload.ContentFormat,
+		UserID:        user.ID,
+		Visibility:    payload.Visibility,
+		Entities:      parseEntities(payload.Content),
+		Kind:          store.PostKindQuote,
+		OriginalID:    &original.ID,
+		Original:      original,
+	}
+
+	setPostTags(post, payload.Tags)
+	setContentWarning(post, payload.ContentWarning, payload.Sensitive, user.ID)
+
+	if err := app.store.Posts.Create(ctx, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.saveRendered(ctx, post)
+	app.queueLinkPreview(post.Content)
+	app.pushToTimelines(ctx, post)
+
+	app.events.Publish(ctx, events.Event{
+		Type:    events.PostCreated,
+		ActorID: user.ID,
+		PostID:  post.ID,
+	})
+
+	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// checkShareable returns errNotShareable when sharing the post would show it
+// to people its author didn't choose: when it isn't public, or its author's
+// account is private.
+func (app *application) checkShareable(ctx context.Context, post *store.Post) error {
+	if post.Visibility != store.VisibilityPublic {
+		return errNotShareable
+	}
+
+	author, err := app.getUser(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+
+	if author.IsPrivate {
+		return errNotShareable
+	}
+
+	return nil
+}
+
+// resolveOriginal returns the post that should be shared: reposting a repost
+// shares the post it points to instead of nesting reposts, provided the
+// viewer can see it.
+func (app *application) resolveOriginal(ctx context.Context, post *store.Post, viewerID int64) (*store.Post, error) {
+	if post.Kind != store.PostKindRepost {
+		return post, nil
+	}
+
+	if post.OriginalID == nil {
+		return nil, store.ErrNotFound
+	}
+
+	return app.store.Posts.GetVisibleByID(ctx, *post.OriginalID, viewerID)
+}
+
+// loadOriginal embeds the shared post into a repost or quote. An original
+// that no longer exists, or that the viewer can't see, is left out rather
+// than failing the request.
+func (app *application) loadOriginal(ctx context.Context, post *store.Post, viewerID int64) error {
+	if post.OriginalID == nil {
+		return nil
+	}
+
+	original, err := app.store.Posts.GetVisibleByID(ctx, *post.OriginalID, viewerID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	post.Original = original
+	return nil
+}
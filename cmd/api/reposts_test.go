@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func newRepostTestApp(t *testing.T) (*application, http.Handler, *store.MockPostStore) {
+	t.Helper()
+
+	app := newTestApplication(t, config{})
+	posts := &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 2, Title: "original", Content: "original", Kind: store.PostKindPost, Visibility: store.VisibilityPublic},
+	}}
+	app.store.Posts = posts
+
+	return app, app.mount(), posts
+}
+
+func decodePost(t *testing.T, body []byte) store.Post {
+	t.Helper()
+
+	var res struct {
+		Data store.Post `json:"data"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return res.Data
+}
+
+func TestRepost(t *testing.T) {
+	t.Run("shares the post", func(t *testing.T) {
+		app, mux, _ := newRepostTestApp(t)
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/repost", ""), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		post := decodePost(t, rr.Body.Bytes())
+		if post.Kind != store.PostKindRepost || post.OriginalID == nil || *post.OriginalID != 1 {
+			t.Errorf("got kind %q and original %v, want a repost of post 1", post.Kind, post.OriginalID)
+		}
+	})
+
+	t.Run("reposting a repost shares the original", func(t *testing.T) {
+		app, mux, posts := newRepostTestApp(t)
+		originalID := int64(1)
+		posts.Posts[2] = &store.Post{ID: 2, UserID: 3, Kind: store.PostKindRepost, Visibility: store.VisibilityPublic, OriginalID: &originalID}
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/2/repost", ""), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		post := decodePost(t, rr.Body.Bytes())
+		if post.OriginalID == nil || *post.OriginalID != 1 {
+			t.Errorf("got original %v, want 1", post.OriginalID)
+		}
+	})
+
+	t.Run("rejects a second repost", func(t *testing.T) {
+		app, mux, _ := newRepostTestApp(t)
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/repost", ""), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		rr = executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/repost", ""), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("rejects posts that aren't public", func(t *testing.T) {
+		app, mux, posts := newRepostTestApp(t)
+		posts.Posts[1].Visibility = store.VisibilityFollowers
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/repost", ""), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+
+		rr = executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/quote", `{"content":"so true"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("rejects public posts of private accounts", func(t *testing.T) {
+		app, mux, posts := newRepostTestApp(t)
+		app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{2: {ID: 2, IsPrivate: true}}
+		originalID := int64(1)
+		posts.Posts[2] = &store.Post{ID: 2, UserID: 3, Kind: store.PostKindRepost, Visibility: store.VisibilityPublic, OriginalID: &originalID}
+
+		for _, url := range []string{"/v1/posts/1/repost", "/v1/posts/2/repost"} {
+			rr := executeRequest(newAuthRequest(t, app, http.MethodPost, url, ""), mux)
+			checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		}
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/quote", `{"content":"so true"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		app, mux, _ := newRepostTestApp(t)
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/9/repost", ""), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestUndoRepost(t *testing.T) {
+	app, mux, posts := newRepostTestApp(t)
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/repost", ""), mux)
+	checkResponseCode(t, http.StatusCreated, rr.Code)
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodDelete, "/v1/posts/1/repost", ""), mux)
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	if len(posts.Posts) != 1 {
+		t.Errorf("got %d posts after undoing the repost, want 1", len(posts.Posts))
+	}
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodDelete, "/v1/posts/1/repost", ""), mux)
+	checkResponseCode(t, http.StatusNotFound, rr.Code)
+}
+
+func TestQuotePost(t *testing.T) {
+	t.Run("shares the post with text", func(t *testing.T) {
+		app, mux, _ := newRepostTestApp(t)
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/quote", `{"content":"so true"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+
+		post := decodePost(t, rr.Body.Bytes())
+		if post.Kind != store.PostKindQuote || post.Content != "so true" {
+			t.Errorf("got kind %q and content %q, want a quote saying %q", post.Kind, post.Content, "so true")
+		}
+
+		if post.Original == nil || post.Original.ID != 1 {
+			t.Errorf("got original %v, want post 1 embedded", post.Original)
+		}
+	})
+
+	t.Run("requires content", func(t *testing.T) {
+		app, mux, _ := newRepostTestApp(t)
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/1/quote", `{"title":"hm"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		app, mux, _ := newRepostTestApp(t)
+
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts/9/quote", `{"content":"so true"}`), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
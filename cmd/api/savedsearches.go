@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type CreateSavedSearchPayload struct {
+	Name  string            `json:"name" validate:"required,max=100"`
+	Query store.FeedFilters `json:"query"`
+}
+
+// GetSavedSearches godoc
+//
+//	@Summary		Lists saved searches
+//	@Description	Lists the current user's saved feed searches, by name
+//	@Tags			feed
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]store.SavedSearch
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/saved-searches [get]
+func (app *application) getSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	searches, err := app.store.SavedSearches.GetByUserID(r.Context(), getUserFromCtx(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, searches); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateSavedSearch godoc
+//
+//	@Summary		Saves a search
+//	@Description	Saves feed parameters (sort, tags, search, since and until) under a name. Pass its ID as saved_search to the feed to read it.
+//	@Tags			feed
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateSavedSearchPayload	true	"Saved search payload"
+//	@Success		201		{object}	store.SavedSearch
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/saved-searches [post]
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateSavedSearchPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	filters, err := payload.Query.Normalize()
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	search := &store.SavedSearch{
+		UserID: getUserFromCtx(r).ID,
+		Name:   payload.Name,
+		Query:  filters,
+	}
+
+	if err := app.store.SavedSearches.Create(r.Context(), search); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictError(w, r, errors.New("a saved search with that name already exists"))
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, search); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteSavedSearch godoc
+//
+//	@Summary		Deletes a saved search
+//	@Description	Deletes one of the current user's saved searches
+//	@Tags			feed
+//	@Accept			json
+//	@Produce		json
+//	@Param			savedSearchID	path		int	true	"Saved search ID"
+//	@Success		204				{object}	string
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/saved-searches/{savedSearchID} [delete]
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	searchID, err := strconv.ParseInt(chi.URLParam(r, "savedSearchID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.SavedSearches.Delete(r.Context(), getUserFromCtx(r).ID, searchID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// Search godoc
+//
+//	@Summary		Searches posts or comments
+//	@Description	Full-text search, best match first. Quoted phrases, OR and -word work as on search engines, and from:user, tag:x (or #x), since:YYYY-MM-DD and until:YYYY-MM-DD narrow the results down. Each result has a snippet with its matches in <mark> elements.
+//	@Tags			search
+//	@Accept			json
+//	@Produce		json
+//	@Param			q		query		string	true	"Query"
+//	@Param			type	query		string	false	"posts (default) or comments"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset, from next_offset"
+//	@Success		200		{object}	[]store.PostSearchResult
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+//
+// Results are paged by offset rather than with cursors like other lists. A
+// cursor would have to carry the rank of the last result, which is a float
+// recomputed on every request: rounding or an edit between pages would skip
+// or repeat results instead of only shifting them, and offsets are capped low
+// enough for the cost of skipping to stay small.
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.SearchQuery{
+		Type:  "posts",
+		Limit: 20,
+	}
+
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	var (
+		results any
+		n       int
+	)
+
+	switch sq.Type {
+	case "comments":
+		matches, err := app.store.Search.Comments(ctx, user.ID, sq)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		comments := make([]*store.Comment, len(matches))
+		for i, m := range matches {
+			comments[i] = &m.Comment
+		}
+		n = len(matches)
+
+		if err := app.loadCommentEntities(ctx, comments); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		results = matches
+	default:
+		matches, err := app.store.Search.Posts(ctx, user.ID, sq)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		posts := make([]*store.PostWithMetadata, len(matches))
+		for i, m := range matches {
+			posts[i] = &m.PostWithMetadata
+		}
+		n = len(matches)
+
+		if err := app.hydratePostList(ctx, posts); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		results = matches
+	}
+
+	// A full page may have more after it, unless it reaches the last offset
+	// allowed
+	var nextOffset int
+	if n == sq.Limit && sq.Offset+sq.Limit <= store.MaxSearchOffset {
+		nextOffset = sq.Offset + sq.Limit
+	}
+
+	if err := app.offsetJSONResponse(w, r, http.StatusOK, results, nextOffset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import "net/http"
+
+type UpdateSettingsPayload struct {
+	ExpandSensitive *bool `json:"expand_sensitive"`
+	// IsPrivate limits the user's posts to their followers, including the
+	// public ones
+	IsPrivate *bool `json:"is_private"`
+}
+
+// GetSettings godoc
+//
+//	@Summary		Gets the user's settings
+//	@Description	Gets the current user's settings, the defaults when they never changed any
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	store.UserSettings
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/settings [get]
+func (app *application) getSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	settings, err := app.store.Settings.Get(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, settings); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateSettings godoc
+//
+//	@Summary		Updates the user's settings
+//	@Description	Updates the current user's settings, the ones left out are kept
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateSettingsPayload	true	"Update Settings Payload"
+//	@Success		200		{object}	store.UserSettings
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/settings [patch]
+func (app *application) updateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateSettingsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	settings, err := app.store.Settings.Get(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if payload.ExpandSensitive != nil {
+		settings.ExpandSensitive = *payload.ExpandSensitive
+	}
+
+	// Going public approves the pending follow requests, whose timelines
+	// have to be built again with the user's posts
+	var requesterIDs []int64
+	if payload.IsPrivate != nil && settings.IsPrivate && !*payload.IsPrivate && app.timeline != nil {
+		requesterIDs, err = app.store.Followers.GetRequesterIDs(ctx, user.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if payload.IsPrivate != nil {
+		settings.IsPrivate = *payload.IsPrivate
+	}
+
+	if err := app.store.Settings.Update(ctx, settings); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for _, id := range requesterIDs {
+		app.invalidateTimeline(ctx, id)
+	}
+
+	// The cached user carries the privacy used to decide what can be shared
+	if app.config.redisCfg.enabled {
+		app.cacheStorage.Users.Delete(ctx, user.ID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, settings); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
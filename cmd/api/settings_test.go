@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestSettings(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	getSettings := func(method, body string) store.UserSettings {
+		t.Helper()
+
+		rr := executeRequest(newAuthRequest(t, app, method, "/v1/users/me/settings", body), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data store.UserSettings `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+
+		return res.Data
+	}
+
+	if settings := getSettings(http.MethodGet, ""); settings.ExpandSensitive {
+		t.Error("got expand_sensitive by default, want sensitive posts collapsed")
+	}
+
+	if settings := getSettings(http.MethodPatch, `{"expand_sensitive":true}`); !settings.ExpandSensitive {
+		t.Error("got expand_sensitive off after turning it on")
+	}
+
+	// Settings left out of the payload are kept
+	if settings := getSettings(http.MethodPatch, `{}`); !settings.ExpandSensitive {
+		t.Error("got expand_sensitive reset by a payload without it")
+	}
+
+	if settings := getSettings(http.MethodGet, ""); !settings.ExpandSensitive {
+		t.Error("got expand_sensitive off after saving it")
+	}
+
+	if settings := getSettings(http.MethodPatch, `{"is_private":true}`); !settings.IsPrivate || !settings.ExpandSensitive {
+		t.Errorf("got is_private %t and expand_sensitive %t, want both on", settings.IsPrivate, settings.ExpandSensitive)
+	}
+
+	if settings := getSettings(http.MethodPatch, `{"is_private":false}`); settings.IsPrivate {
+		t.Error("got is_private on after turning it off")
+	}
+}
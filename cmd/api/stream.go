@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/mute"
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/qwerqy/social-api-go/internal/stream"
+)
+
+// streamPingInterval is how often idle streams are written to, proxies
+// close connections that stay silent for too long.
+const streamPingInterval = time.Second * 25
+
+type DeletedPost struct {
+	ID int64 `json:"id"`
+}
+
+// Stream godoc
+//
+//	@Summary		Streams events
+//	@Description	Pushes new posts in the feed, updates of those posts and notifications as Server-Sent Events. Browsers can pass the token in access_token.
+//	@Tags			stream
+//	@Produce		text/event-stream
+//	@Param			access_token	query		string	false	"Token"
+//	@Success		200				{object}	stream.Message
+//	@Failure		401				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/stream [get]
+func (app *application) streamHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	rc := http.NewResponseController(w)
+
+	// The server's write timeout is meant for requests, streams stay open
+	// for as long as the client listens
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	messages, cancel := app.stream.Subscribe(user.ID)
+	defer cancel()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// Keeps proxies such as nginx from buffering the stream
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			err = m.WriteSSE(w)
+		case <-ping.C:
+			_, err = io.WriteString(w, ": ping\n\n")
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// StreamWebSocket godoc
+//
+//	@Summary		Streams events over a WebSocket
+//	@Description	Pushes the same messages as /stream over a WebSocket. Browsers can pass the token in access_token.
+//	@Tags			stream
+//	@Param			access_token	query		string	false	"Token"
+//	@Success		101				{object}	stream.Message
+//	@Failure		401				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/stream/ws [get]
+func (app *application) streamWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	rc := http.NewResponseController(w)
+
+	// The connection outlives the request, the server's timeouts would
+	// still apply to it once it's handed over
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Any origin may connect, the token is what authenticates a socket
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			app.serveWebSocket(ws, user.ID)
+		},
+	}
+
+	server.ServeHTTP(w, r)
+}
+
+func (app *application) serveWebSocket(ws *websocket.Conn, userID int64) {
+	defer ws.Close()
+
+	messages, cancel := app.stream.Subscribe(userID)
+	defer cancel()
+
+	// Clients don't send anything but closing the socket, reading is how
+	// it's noticed
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		io.Copy(io.Discard, ws)
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		var m stream.Message
+
+		select {
+		case <-gone:
+			return
+		case next, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			m = next
+		case <-ping.C:
+			m = stream.Message{Event: stream.Ping}
+		}
+
+		if err := websocket.JSON.Send(ws, m); err != nil {
+			return
+		}
+	}
+}
+
+// subscribeStream pushes the events published on bus to the streams of the
+// users they concern.
+func (app *application) subscribeStream(bus *events.Bus) {
+	bus.Subscribe(app.streamPost, events.PostCreated, events.PostUpdated)
+	bus.Subscribe(app.streamDeletedPost, events.PostDeleted)
+}
+
+// streamPost pushes a new or edited post to the users whose feed it's in,
+// but those who muted a word of it.
+func (app *application) streamPost(ctx context.Context, e events.Event) error {
+	post, err := app.store.Posts.GetByID(ctx, e.PostID)
+	if err != nil {
+		return err
+	}
+
+	// Only public posts can be shared, whoever sees the share sees them
+	if err := app.loadOriginal(ctx, post, post.UserID); err != nil {
+		return err
+	}
+
+	if err := app.hydratePosts(ctx, post); err != nil {
+		return err
+	}
+
+	readers, err := app.feedReaders(ctx, post.UserID, post)
+	if err != nil {
+		return err
+	}
+
+	words, err := app.store.MutedWords.GetByUserIDs(ctx, readers)
+	if err != nil {
+		return err
+	}
+
+	var unmuted []int64
+	for _, id := range readers {
+		filter, err := mute.New(id, words[id], store.MuteScopeFeed)
+		if err != nil {
+			return err
+		}
+
+		if !filter.HidesPost(post) {
+			unmuted = append(unmuted, id)
+		}
+	}
+
+	event := stream.FeedItem
+	if e.Type == events.PostUpdated {
+		event = stream.PostUpdated
+	}
+
+	m, err := stream.NewMessage(event, post)
+	if err != nil {
+		return err
+	}
+
+	return app.stream.Publish(ctx, unmuted, m)
+}
+
+func (app *application) streamDeletedPost(ctx context.Context, e events.Event) error {
+	readers, err := app.feedReaders(ctx, e.UserID, nil)
+	if err != nil {
+		return err
+	}
+
+	m, err := stream.NewMessage(stream.PostDeleted, DeletedPost{ID: e.PostID})
+	if err != nil {
+		return err
+	}
+
+	return app.stream.Publish(ctx, readers, m)
+}
+
+// feedReaders returns the users whose feed has the posts of authorID: the
+// author and their followers. Only the followers mentioned in post read it
+// when it's visible to mentioned users, a nil post is read by them all.
+func (app *application) feedReaders(ctx context.Context, authorID int64, post *store.Post) ([]int64, error) {
+	followers, err := app.store.Followers.GetFollowerIDs(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	readers := []int64{authorID}
+
+	if post == nil || post.Visibility != store.VisibilityMentioned {
+		return append(readers, followers...), nil
+	}
+
+	mentioned := make(map[int64]bool)
+	for _, e := range post.Entities {
+		if e.Type == store.EntityMention {
+			mentioned[e.UserID] = true
+		}
+	}
+
+	for _, id := range followers {
+		if mentioned[id] {
+			readers = append(readers, id)
+		}
+	}
+
+	return readers, nil
+}
+
+// streamNotification pushes a notification just created to its user.
+func (app *application) streamNotification(ctx context.Context, n *store.Notification) error {
+	actor, err := app.getUser(ctx, n.ActorID)
+	if err != nil {
+		return err
+	}
+
+	n.Actors = []store.User{{ID: actor.ID, Username: actor.Username}}
+	n.ActorCount = 1
+
+	m, err := stream.NewMessage(stream.Notification, n)
+	if err != nil {
+		return err
+	}
+
+	return app.stream.Publish(ctx, []int64{n.UserID}, m)
+}
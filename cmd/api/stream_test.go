@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/net/websocket"
+
+	"github.com/qwerqy/social-api-go/internal/stream"
+)
+
+func TestStream(t *testing.T) {
+	app := newTestApplication(t, config{})
+	ts := httptest.NewServer(app.mount())
+	defer ts.Close()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := stream.NewMessage(stream.PostDeleted, DeletedPost{ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated streams", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/v1/stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("should push messages as server-sent events", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/v1/stream?access_token=" + testToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		checkResponseCode(t, http.StatusOK, resp.StatusCode)
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected an event stream, got %q", ct)
+		}
+
+		// The stream is subscribed once the headers are sent
+		if err := app.stream.Publish(context.Background(), []int64{1}, m); err != nil {
+			t.Fatal(err)
+		}
+
+		reader := bufio.NewReader(resp.Body)
+		var got strings.Builder
+		for !strings.HasSuffix(got.String(), "\n\n") {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.WriteString(line)
+		}
+
+		want := "event: post.deleted\ndata: {\"id\":7}\n\n"
+		if got.String() != want {
+			t.Errorf("got %q, want %q", got.String(), want)
+		}
+	})
+
+	t.Run("should push messages over a websocket", func(t *testing.T) {
+		url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/v1/stream/ws?access_token=" + testToken
+
+		ws, err := websocket.Dial(url, "", ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+
+		// The socket subscribes after the handshake, publish until it has
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Millisecond * 10):
+					app.stream.Publish(context.Background(), []int64{1}, m)
+				}
+			}
+		}()
+
+		ws.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+		var got stream.Message
+		if err := websocket.JSON.Receive(ws, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Event != stream.PostDeleted || string(got.Data) != `{"id":7}` {
+			t.Errorf("got %s %s", got.Event, got.Data)
+		}
+	})
+}
+
+func TestStreamTokenIsNotLogged(t *testing.T) {
+	var logs bytes.Buffer
+
+	defaultLogger := middleware.DefaultLogger
+	middleware.DefaultLogger = middleware.RequestLogger(&middleware.DefaultLogFormatter{
+		Logger:  log.New(&logs, "", 0),
+		NoColor: true,
+	})
+	defer func() { middleware.DefaultLogger = defaultLogger }()
+
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	req, err := http.NewRequest(http.MethodGet, "/v1/stream?access_token=not-a-token&since=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RequestURI = req.URL.RequestURI()
+
+	// The token was read, and rejected
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(req, mux).Code)
+
+	if !strings.Contains(logs.String(), "/v1/stream?since=1") {
+		t.Fatalf("expected the request to be logged, got %q", logs.String())
+	}
+
+	if strings.Contains(logs.String(), "not-a-token") {
+		t.Errorf("the token was logged: %q", logs.String())
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/entities"
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/qwerqy/social-api-go/internal/syndication"
+)
+
+// syndicationSize is how many of the newest posts a syndication feed has.
+const syndicationSize = 50
+
+// anonymousViewer is the viewer syndication feeds are read as. No user has
+// its ID, so the only posts it can see are the public posts of public
+// accounts.
+const anonymousViewer int64 = 0
+
+// GetUserSyndication godoc
+//
+//	@Summary		Gets a user's posts for feed readers
+//	@Description	Renders the newest public posts of a user as RSS, Atom or JSON Feed. Private accounts have no feed.
+//	@Tags			users
+//	@Produce		xml
+//	@Produce		json
+//	@Param			id		path		int		true	"User ID"
+//	@Param			format	path		string	true	"rss, atom or json"
+//	@Success		200		{string}	string
+//	@Success		304		{string}	string
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/users/{id}/feed.{format} [get]
+func (app *application) getUserSyndicationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	user, err := app.getUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Feed readers can't follow, a private account's posts are never shown
+	if user.IsPrivate {
+		app.notFoundError(w, r, store.ErrNotFound)
+		return
+	}
+
+	posts, err := app.store.Posts.GetByUserID(ctx, userID, anonymousViewer, store.UserPostsQuery{
+		KeysetPaginatedQuery: store.KeysetPaginatedQuery{Limit: syndicationSize},
+		ExcludeReposts:       true,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.writeSyndication(w, r, &syndication.Feed{
+		Title:       fmt.Sprintf("%s's posts", user.Username),
+		Description: fmt.Sprintf("Public posts of %s", user.Username),
+		Link:        fmt.Sprintf("%s/users/%d", app.config.frontendURL, user.ID),
+	}, posts)
+}
+
+// GetTagSyndication godoc
+//
+//	@Summary		Gets a tag's posts for feed readers
+//	@Description	Renders the newest public posts with a tag as RSS, Atom or JSON Feed
+//	@Tags			tags
+//	@Produce		xml
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag"
+//	@Param			format	path		string	true	"rss, atom or json"
+//	@Success		200		{string}	string
+//	@Success		304		{string}	string
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/tags/{tag}/feed.{format} [get]
+func (app *application) getTagSyndicationHandler(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeTag(chi.URLParam(r, "tag"))
+	if tag == "" {
+		app.badRequestError(w, r, errors.New("tag is invalid"))
+		return
+	}
+
+	posts, err := app.store.Posts.GetByTag(r.Context(), tag, anonymousViewer, store.KeysetPaginatedQuery{
+		Limit: syndicationSize,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.writeSyndication(w, r, &syndication.Feed{
+		Title:       "#" + tag,
+		Description: fmt.Sprintf("Public posts tagged #%s", tag),
+		Link:        app.config.frontendURL + "/tags/" + url.PathEscape(tag),
+	}, posts)
+}
+
+// writeSyndication renders posts into feed in the format the URL asks for.
+func (app *application) writeSyndication(w http.ResponseWriter, r *http.Request, feed *syndication.Feed, posts []*store.PostWithMetadata) {
+	format := chi.URLParam(r, "format")
+	if format != syndication.FormatRSS && format != syndication.FormatAtom && format != syndication.FormatJSON {
+		app.badRequestError(w, r, syndication.ErrUnknownFormat)
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.hydratePostList(ctx, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	feed.FeedURL = app.feedURL(r)
+
+	for _, p := range posts {
+		// Only ever public posts, whatever the queries above let through
+		if p.Visibility != store.VisibilityPublic || p.Kind == store.PostKindRepost {
+			continue
+		}
+
+		item, err := app.syndicationItem(p)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		feed.Items = append(feed.Items, item)
+	}
+
+	if err := syndication.Write(w, r, format, feed); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) syndicationItem(p *store.PostWithMetadata) (syndication.Item, error) {
+	published, err := time.Parse(time.RFC3339Nano, p.CreatedAt)
+	if err != nil {
+		return syndication.Item{}, err
+	}
+
+	postURL := fmt.Sprintf("%s/posts/%d", app.config.frontendURL, p.ID)
+
+	// Readers can't collapse a post, the warning goes first instead
+	content := p.ContentHTML
+	if p.ContentWarning != "" {
+		content = "<p><strong>" + html.EscapeString(p.ContentWarning) + "</strong></p>" + content
+	}
+
+	return syndication.Item{
+		ID:          postURL,
+		URL:         postURL,
+		Title:       p.Title,
+		ContentHTML: content,
+		AuthorName:  p.User.Username,
+		AuthorURL:   fmt.Sprintf("%s/users/%d", app.config.frontendURL, p.UserID),
+		Published:   published,
+		Tags:        p.Tags,
+	}, nil
+}
+
+// feedURL returns the absolute URL of the feed requested. It is built from
+// the configured address rather than the request's headers, which clients
+// and proxies can set to anything.
+func (app *application) feedURL(r *http.Request) string {
+	return app.config.apiScheme + "://" + app.config.apiURL + r.URL.EscapedPath()
+}
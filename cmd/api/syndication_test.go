@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSyndicationFeedURL(t *testing.T) {
+	app := newTestApplication(t, config{apiURL: "api.example.com", apiScheme: "https"})
+	mux := app.mount()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/tags/golang/feed.json", nil)
+	req.Host = "evil.example.com"
+	req.Header.Set("X-Forwarded-Proto", "http")
+
+	rr := executeRequest(req, mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	var feed struct {
+		FeedURL string `json:"feed_url"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "https://api.example.com/v1/tags/golang/feed.json"; feed.FeedURL != want {
+		t.Errorf("feed_url = %q, want %q", feed.FeedURL, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/entities"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+const maxTagSuggestions = 20
+
+// SearchTags godoc
+//
+//	@Summary		Autocompletes tags
+//	@Description	Lists the tags starting with a prefix, most used first. A prefix that normalises to nothing, such as "#", matches no tags.
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			prefix	query		string	true	"Prefix"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.Tag
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags [get]
+func (app *application) searchTagsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if param := r.URL.Query().Get("limit"); param != "" {
+		l, err := strconv.Atoi(param)
+		if err != nil || l < 1 || l > maxTagSuggestions {
+			app.badRequestError(w, r, fmt.Errorf("limit must be between 1 and %d", maxTagSuggestions))
+			return
+		}
+
+		limit = l
+	}
+
+	// An empty prefix would match every tag
+	prefix := entities.NormalizeTag(r.URL.Query().Get("prefix"))
+	if prefix == "" {
+		if err := app.jsonResponse(w, http.StatusOK, []*store.Tag{}); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	tags, err := app.store.Tags.Search(r.Context(), prefix, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetTagPosts godoc
+//
+//	@Summary		Lists the posts of a tag
+//	@Description	Lists the posts with a tag, newest first. The tag is normalised so "Go" and "#go" give the same posts.
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			cursor	query		string	false	"Cursor from next_cursor or prev_cursor"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeTag(chi.URLParam(r, "tag"))
+	if tag == "" {
+		app.badRequestError(w, r, errors.New("tag is invalid"))
+		return
+	}
+
+	kq := store.KeysetPaginatedQuery{
+		Limit: 20,
+	}
+
+	kq, err := kq.Parse(r, app.cursors)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(kq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	posts, err := app.store.Posts.GetByTag(ctx, tag, user.ID, kq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.hydratePostList(ctx, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	nextCursor, prevCursor := app.pageCursors(posts, kq.Cursor, kq.Limit)
+
+	if err := app.keysetJSONResponse(w, r, http.StatusOK, posts, nextCursor, prevCursor); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// setPostTags sets the tags given explicitly on a post, and its tags to those
+// followed by the hashtags in its content, normalised and without duplicates.
+// The explicit tags are kept apart so a tag given both ways outlives its
+// hashtag being edited out of the content.
+func setPostTags(post *store.Post, tags []string) {
+	post.ExplicitTags = entities.NormalizeTags(tags)
+
+	all := slices.Clone(post.ExplicitTags)
+	for _, h := range entities.Hashtags(post.Content) {
+		all = append(all, h.Tag)
+	}
+
+	post.Tags = entities.NormalizeTags(all)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/qwerqy/social-api-go/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetPostTags(t *testing.T) {
+	post := &store.Post{Content: "learning #Go and #rust"}
+	setPostTags(post, []string{"#go", "Backend", "backend"})
+
+	if want := []string{"go", "backend"}; !slices.Equal(post.ExplicitTags, want) {
+		t.Errorf("got explicit tags %q, want %q", post.ExplicitTags, want)
+	}
+
+	if want := []string{"go", "backend", "rust"}; !slices.Equal(post.Tags, want) {
+		t.Errorf("got tags %q, want %q", post.Tags, want)
+	}
+}
+
+func TestPatchPostKeepsExplicitTags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"hashtag edited out of the content", `{"content":"learning"}`, []string{"go"}},
+		{"new hashtag", `{"content":"learning #zig"}`, []string{"go", "zig"}},
+		{"tags replaced", `{"tags":["backend"]}`, []string{"backend", "go", "rust"}},
+		{"tags cleared", `{"tags":[],"content":"learning"}`, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+			app.cacheStorage.Users.(*cache.MockUserStore).On("Delete", mock.Anything)
+
+			// "go" was given explicitly and as a hashtag, "rust" only as a
+			// hashtag
+			app.store.Posts = &store.MockPostStore{Posts: map[int64]*store.Post{
+				1: {
+					ID:           1,
+					UserID:       1,
+					Kind:         store.PostKindPost,
+					Visibility:   store.VisibilityPublic,
+					Content:      "learning #go and #rust",
+					Tags:         []string{"go", "rust"},
+					ExplicitTags: []string{"go"},
+				},
+			}}
+
+			rr := executeRequest(newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1", tt.body), app.mount())
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			if post := decodePost(t, rr.Body.Bytes()); !slices.Equal(post.Tags, tt.want) {
+				t.Errorf("got tags %q, want %q", post.Tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTags(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	app.store.Tags.(*store.MockTagStore).Tags = []*store.Tag{
+		{Name: "golang", PostCount: 3},
+		{Name: "go", PostCount: 2},
+		{Name: "rust", PostCount: 1},
+	}
+
+	tests := []struct {
+		query string
+		code  int
+		want  []string
+	}{
+		{"prefix=Go", http.StatusOK, []string{"golang", "go"}},
+		{"prefix=%23go&limit=1", http.StatusOK, []string{"golang"}},
+		{"prefix=%23", http.StatusOK, []string{}},
+		{"prefix=+", http.StatusOK, []string{}},
+		{"", http.StatusOK, []string{}},
+		{"prefix=go&limit=0", http.StatusBadRequest, nil},
+		{"prefix=go&limit=21", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/tags?"+tt.query, ""), mux)
+			checkResponseCode(t, tt.code, rr.Code)
+
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var res struct {
+				Data []*store.Tag `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+
+			names := []string{}
+			for _, tag := range res.Data {
+				names = append(names, tag.Name)
+			}
+
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("got %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagPostsRejectsEmptyTags(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/tags/"+url.PathEscape("##")+"/posts", ""), app.mount())
+	checkResponseCode(t, http.StatusBadRequest, rr.Code)
+}
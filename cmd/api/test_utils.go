@@ -3,12 +3,15 @@ package main
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/qwerqy/social-api-go/internal/auth"
+	"github.com/qwerqy/social-api-go/internal/events"
 	"github.com/qwerqy/social-api-go/internal/ratelimiter"
 	"github.com/qwerqy/social-api-go/internal/store"
 	"github.com/qwerqy/social-api-go/internal/store/cache"
+	"github.com/qwerqy/social-api-go/internal/stream"
 	"go.uber.org/zap"
 )
 
@@ -34,9 +37,32 @@ func newTestApplication(t *testing.T, cfg config) *application {
 		authenticator: testAuth,
 		config:        cfg,
 		rateLimiter:   rateLimiter,
+		cursors:       store.NewCursorSigner("test"),
+		events:        events.New(nil),
+		stream:        stream.NewMemoryBroker(),
 	}
 }
 
+// newAuthRequest returns a request authenticated as the test user, whose ID
+// is 1.
+func newAuthRequest(t *testing.T, app *application, method, url, body string) *http.Request {
+	t.Helper()
+
+	token, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return req
+}
+
 func executeRequest(req *http.Request, mux http.Handler) *httptest.ResponseRecorder {
 	rr := httptest.NewRecorder()
 	mux.ServeHTTP(rr, req)
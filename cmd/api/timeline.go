@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/qwerqy/social-api-go/internal/timeline"
+)
+
+// feedPage is a page of the feed with the cursors of the pages around it.
+type feedPage struct {
+	posts      []*store.PostWithMetadata
+	nextCursor string
+	prevCursor string
+}
+
+// fromTimeline reports whether the feed page asked for can be read from the
+// home timelines. They only hold the newest posts without any filter.
+func (app *application) fromTimeline(fq store.PaginatedFeedQuery) bool {
+	return app.timeline != nil &&
+		fq.Sort == "desc" &&
+		len(fq.Tags) == 0 && fq.Search == "" &&
+		fq.Since == "" && fq.Until == "" &&
+		fq.Offset == 0 &&
+		(fq.Cursor == nil || !fq.Cursor.Backward)
+}
+
+// timelineFeed reads a page of the user's feed from their home timeline,
+// building it first if needed. The page is nil when the timeline can't
+// serve it and the feed has to be read from the database.
+func (app *application) timelineFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) (*feedPage, error) {
+	cfg := app.timeline.Config()
+
+	var before int64
+	if fq.Cursor != nil {
+		before = fq.Cursor.ID
+	}
+
+	entries, ok, err := app.timeline.Page(ctx, userID, before, fq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok && before == 0 {
+		seed, err := app.store.Timelines.GetEntries(ctx, userID, cfg.CelebrityFollowers, cfg.MaxEntries)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := app.timeline.Seed(ctx, userID, seed); err != nil {
+			return nil, err
+		}
+
+		entries, ok, err = app.timeline.Page(ctx, userID, before, fq.Limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	// Posts of popular users aren't pushed to timelines, they are read here
+	popular, err := app.store.Timelines.GetPopularEntries(ctx, userID, cfg.CelebrityFollowers, before, fq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = timeline.Merge(fq.Limit, entries, popular)
+
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.PostID
+	}
+
+	// Visibility is checked here rather than when posts are pushed, so
+	// timelines don't go stale when it changes. Posts deleted since they
+	// were pushed drop out here too, as do muted posts.
+	posts, err := app.store.Posts.GetVisibleByIDs(ctx, ids, userID, fq.Mutes)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*store.PostWithMetadata, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	page := &feedPage{posts: make([]*store.PostWithMetadata, 0, len(posts))}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			page.posts = append(page.posts, p)
+		}
+	}
+
+	// Cursors come from the entries rather than the posts, a page whose
+	// posts were all left out still leads to the next one
+	if len(entries) == fq.Limit {
+		last := entries[len(entries)-1]
+		page.nextCursor = app.cursors.Encode(store.Cursor{CreatedAt: last.CreatedAt, ID: last.PostID})
+	}
+
+	if fq.Cursor != nil && len(entries) > 0 {
+		first := entries[0]
+		page.prevCursor = app.cursors.Encode(store.Cursor{CreatedAt: first.CreatedAt, ID: first.PostID, Backward: true})
+	}
+
+	return page, nil
+}
+
+// pushToTimelines adds a new post to the home timelines of its author's
+// followers in the background. The post is already saved, failing to push
+// it only delays it until the timelines are built again.
+func (app *application) pushToTimelines(ctx context.Context, post *store.Post) {
+	if app.timeline == nil {
+		return
+	}
+
+	entry, err := timelineEntry(post)
+	if err != nil {
+		app.logger.Errorw("failed to push post to timelines", "id", post.ID, "error", err.Error())
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		followers, err := app.store.Followers.GetFollowerIDs(ctx, post.UserID)
+		if err == nil {
+			err = app.timeline.Push(ctx, entry, followers)
+		}
+
+		if err != nil {
+			app.logger.Errorw("failed to push post to timelines", "id", post.ID, "error", err.Error())
+		}
+	}()
+}
+
+// removeFromTimelines takes a deleted post out of the home timelines in the
+// background.
+func (app *application) removeFromTimelines(ctx context.Context, post *store.Post) {
+	if app.timeline == nil {
+		return
+	}
+
+	entry, err := timelineEntry(post)
+	if err != nil {
+		app.logger.Errorw("failed to remove post from timelines", "id", post.ID, "error", err.Error())
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		followers, err := app.store.Followers.GetFollowerIDs(ctx, post.UserID)
+		if err == nil {
+			err = app.timeline.Remove(ctx, entry, followers)
+		}
+
+		if err != nil {
+			app.logger.Errorw("failed to remove post from timelines", "id", post.ID, "error", err.Error())
+		}
+	}()
+}
+
+// removeAuthorFromTimeline takes the posts of authorID out of the user's
+// home timeline.
+func (app *application) removeAuthorFromTimeline(ctx context.Context, userID, authorID int64) {
+	if app.timeline == nil {
+		return
+	}
+
+	if err := app.timeline.RemoveAuthor(ctx, userID, authorID); err != nil {
+		app.logger.Errorw("failed to remove author from timeline", "user", userID, "author", authorID, "error", err.Error())
+	}
+}
+
+// invalidateTimeline has the user's home timeline built again on their next
+// read, with the posts of someone they started following.
+func (app *application) invalidateTimeline(ctx context.Context, userID int64) {
+	if app.timeline == nil {
+		return
+	}
+
+	if err := app.timeline.Invalidate(ctx, userID); err != nil {
+		app.logger.Errorw("failed to invalidate timeline", "user", userID, "error", err.Error())
+	}
+}
+
+func timelineEntry(post *store.Post) (store.TimelineEntry, error) {
+	createdAt, err := time.Parse(time.RFC3339Nano, post.CreatedAt)
+	if err != nil {
+		return store.TimelineEntry{}, err
+	}
+
+	return store.TimelineEntry{PostID: post.ID, AuthorID: post.UserID, CreatedAt: createdAt}, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+type trendingWindow struct {
+	length time.Duration
+	// halfLife is how long it takes for engagement to count half as much
+	halfLife time.Duration
+}
+
+var trendingWindows = map[string]trendingWindow{
+	"hour": {length: time.Hour, halfLife: time.Minute * 15},
+	"day":  {length: time.Hour * 24, halfLife: time.Hour * 6},
+	"week": {length: time.Hour * 24 * 7, halfLife: time.Hour * 48},
+}
+
+const (
+	// trendingSize is how many posts and tags a snapshot keeps, more than a
+	// response shows so there is some left once a viewer's are filtered out
+	trendingSize     = 100
+	maxTrendingLimit = 50
+)
+
+type TrendingResponse struct {
+	Window     string                    `json:"window"`
+	Tags       []store.TrendingTag       `json:"tags"`
+	Posts      []*store.PostWithMetadata `json:"posts"`
+	ComputedAt time.Time                 `json:"computed_at"`
+}
+
+// GetTrending godoc
+//
+//	@Summary		Lists trending posts and tags
+//	@Description	Lists the public posts and tags with the most engagement over a window, recent engagement counting for more. Posts the current user can't see are left out.
+//	@Tags			explore
+//	@Accept			json
+//	@Produce		json
+//	@Param			window	query		string	false	"Window"	Enums(hour, day, week)
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	TrendingResponse
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/explore/trending [get]
+func (app *application) getTrendingHandler(w http.ResponseWriter, r *http.Request) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "day"
+	}
+
+	if _, ok := trendingWindows[window]; !ok {
+		app.badRequestError(w, r, errors.New("window must be one of hour, day or week"))
+		return
+	}
+
+	limit := 20
+	if param := r.URL.Query().Get("limit"); param != "" {
+		l, err := strconv.Atoi(param)
+		if err != nil || l < 1 || l > maxTrendingLimit {
+			app.badRequestError(w, r, fmt.Errorf("limit must be between 1 and %d", maxTrendingLimit))
+			return
+		}
+
+		limit = l
+	}
+
+	user := getUserFromCtx(r)
+	ctx := r.Context()
+
+	trending, err := app.getTrending(ctx, window)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	posts, err := app.visibleTrendingPosts(ctx, trending.Posts, user.ID, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.hydratePostList(ctx, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tags := trending.Tags
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	response := TrendingResponse{
+		Window:     window,
+		Tags:       tags,
+		Posts:      posts,
+		ComputedAt: trending.ComputedAt,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getTrending returns the cached snapshot of a window, computing it when the
+// refresh job hasn't got to it yet.
+func (app *application) getTrending(ctx context.Context, window string) (*store.Trending, error) {
+	trending, err := app.cacheStorage.Trending.Get(ctx, window)
+	if err != nil {
+		return nil, err
+	}
+
+	if trending != nil {
+		return trending, nil
+	}
+
+	return app.computeTrending(ctx, window)
+}
+
+func (app *application) computeTrending(ctx context.Context, window string) (*store.Trending, error) {
+	w := trendingWindows[window]
+
+	trending, err := app.store.Trending.Compute(ctx, w.length, w.halfLife, trendingSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.cacheStorage.Trending.Set(ctx, window, trending); err != nil {
+		app.logger.Errorw("failed to cache trending", "window", window, "error", err.Error())
+	}
+
+	return trending, nil
+}
+
+// visibleTrendingPosts loads the trending posts the viewer can still see, in
+// trending order. The snapshot is shared, so posts by users blocking or
+// blocked by the viewer, and posts made private or deleted since, are
+// dropped here.
+func (app *application) visibleTrendingPosts(ctx context.Context, trending []store.TrendingPost, viewerID int64, limit int) ([]*store.PostWithMetadata, error) {
+	ids := make([]int64, len(trending))
+	for i, t := range trending {
+		ids[i] = t.PostID
+	}
+
+	if len(ids) == 0 {
+		return []*store.PostWithMetadata{}, nil
+	}
+
+	visible, err := app.store.Posts.GetVisibleByIDs(ctx, ids, viewerID, store.MuteQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*store.PostWithMetadata, len(visible))
+	for _, p := range visible {
+		byID[p.ID] = p
+	}
+
+	posts := []*store.PostWithMetadata{}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			posts = append(posts, p)
+		}
+
+		if len(posts) == limit {
+			break
+		}
+	}
+
+	return posts, nil
+}
+
+// refreshTrending recomputes the trending snapshot of every window
+// periodically until ctx is cancelled.
+func (app *application) refreshTrending(ctx context.Context) {
+	ticker := time.NewTicker(app.config.trending.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		for window := range trendingWindows {
+			if _, err := app.computeTrending(ctx, window); err != nil {
+				app.logger.Errorw("failed to refresh trending", "window", window, "error", err.Error())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
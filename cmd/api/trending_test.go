@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func getTrendingResponse(t *testing.T, app *application, mux http.Handler, url string) TrendingResponse {
+	t.Helper()
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, url, ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	var res struct {
+		Data TrendingResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return res.Data
+}
+
+func trendingPostIDs(res TrendingResponse) []int64 {
+	ids := []int64{}
+	for _, p := range res.Posts {
+		ids = append(ids, p.ID)
+	}
+
+	return ids
+}
+
+func TestTrendingFiltersPerViewer(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	// post 2 has been made private, or its author blocked the viewer, since
+	// the snapshot was cached
+	app.store.Posts = &store.MockPostStore{
+		Posts: map[int64]*store.Post{
+			1: {ID: 1, UserID: 2, Visibility: store.VisibilityPublic},
+			2: {ID: 2, UserID: 3, Visibility: store.VisibilityPublic},
+			3: {ID: 3, UserID: 2, Visibility: store.VisibilityPublic},
+		},
+		Visible: func(post *store.Post, viewerID int64) bool {
+			return post.ID != 2
+		},
+	}
+
+	err := app.cacheStorage.Trending.Set(context.Background(), "day", &store.Trending{
+		Posts:      []store.TrendingPost{{PostID: 3, Score: 9}, {PostID: 2, Score: 5}, {PostID: 1, Score: 2}},
+		Tags:       []store.TrendingTag{{Name: "go"}, {Name: "rust"}},
+		ComputedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := getTrendingResponse(t, app, mux, "/v1/explore/trending")
+	if want := []int64{3, 1}; !slices.Equal(trendingPostIDs(res), want) {
+		t.Errorf("got posts %v, want %v", trendingPostIDs(res), want)
+	}
+
+	res = getTrendingResponse(t, app, mux, "/v1/explore/trending?limit=1")
+	if len(res.Posts) != 1 || res.Posts[0].ID != 3 || len(res.Tags) != 1 {
+		t.Errorf("got posts %v and %d tags with limit=1, want [3] and 1 tag", trendingPostIDs(res), len(res.Tags))
+	}
+
+	if trending := app.store.Trending.(*store.MockTrendingStore); trending.Computed != 0 {
+		t.Errorf("computed trending %d times with a cached snapshot, want 0", trending.Computed)
+	}
+}
+
+func TestTrendingComputesUncachedWindow(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	trending := app.store.Trending.(*store.MockTrendingStore)
+	trending.Trending = &store.Trending{
+		Posts:      []store.TrendingPost{},
+		Tags:       []store.TrendingTag{{Name: "go"}},
+		ComputedAt: time.Now(),
+	}
+
+	for range 2 {
+		res := getTrendingResponse(t, app, mux, "/v1/explore/trending?window=week")
+		if res.Window != "week" || len(res.Tags) != 1 {
+			t.Errorf("got window %q with %d tags, want week with 1", res.Window, len(res.Tags))
+		}
+	}
+
+	if trending.Computed != 1 {
+		t.Errorf("computed trending %d times, want once and cached after", trending.Computed)
+	}
+}
+
+func TestTrendingRejectsBadParams(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	for _, query := range []string{"window=month", "limit=0", "limit=51", "limit=abc"} {
+		rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/explore/trending?"+query, ""), mux)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want %d", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/qwerqy/social-api-go/internal/events"
 	"github.com/qwerqy/social-api-go/internal/store"
 )
 
@@ -62,11 +63,12 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 // FollowUser godoc
 //
 //	@Summary		Follows a user
-//	@Description	Follows a user by ID
+//	@Description	Follows a user by ID. Following a private account sends it a follow request instead, answered with 202 until the account approves it.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			user_id	path		int	true	"User ID"
+//	@Success		202		{object}	string	"Follow requested"
 //	@Success		204		{object}	string
 //	@Failure		400		{object}	error
 //	@Failure		404		{object}	error
@@ -83,17 +85,36 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 
 	ctx := r.Context()
 
-	if err := app.store.Followers.Follow(ctx, followerUser.ID, followedUserID); err != nil {
+	pending, err := app.store.Followers.Follow(ctx, followerUser.ID, followedUserID)
+	if err != nil {
 		if errors.Is(err, store.ErrConflict) {
 			app.conflictError(w, r, err)
 			return
 		}
 
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+	// Pending follows have nothing to show yet, and are notified once
+	// approved
+	status := http.StatusAccepted
+	if !pending {
+		status = http.StatusNoContent
+		app.invalidateTimeline(ctx, followerUser.ID)
+		app.events.Publish(ctx, events.Event{
+			Type:    events.UserFollowed,
+			ActorID: followerUser.ID,
+			UserID:  followedUserID,
+		})
+	}
+
+	if err := app.jsonResponse(w, status, nil); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -127,6 +148,90 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.removeAuthorFromTimeline(ctx, followerUser.ID, unfollowedUserID)
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// BlockUser godoc
+//
+//	@Summary		Blocks a user
+//	@Description	Blocks a user by ID. Neither user sees the other's posts any more, can follow or mention the other.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	string
+//	@Failure		400	{object}	error
+//	@Failure		409	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	blockedUserID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if blockedUserID == user.ID {
+		app.badRequestError(w, r, errors.New("you can't block yourself"))
+		return
+	}
+
+	if err := app.store.Blocks.Block(r.Context(), user.ID, blockedUserID); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.removeAuthorFromTimeline(r.Context(), user.ID, blockedUserID)
+	app.removeAuthorFromTimeline(r.Context(), blockedUserID, user.ID)
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblocks a user
+//	@Description	Unblocks a user by ID
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	string
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{id}/unblock [put]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+	blockedUserID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Blocks.Unblock(r.Context(), user.ID, blockedUserID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/qwerqy/social-api-go/internal/events"
+	"github.com/qwerqy/social-api-go/internal/store"
 	"github.com/qwerqy/social-api-go/internal/store/cache"
+	"github.com/qwerqy/social-api-go/internal/timeline"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -105,3 +113,220 @@ func TestGetUser(t *testing.T) {
 		mockCacheStore.Calls = nil // Reset mock expectations
 	})
 }
+
+func TestBlockUser(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	blocks := app.store.Blocks.(*store.MockBlockStore)
+	app.store.Followers.(*store.MockFollowerStore).Blocked = func(userID, otherID int64) bool {
+		blocked, _ := blocks.IsBlocked(context.Background(), userID, otherID)
+		return blocked
+	}
+
+	steps := []struct {
+		name   string
+		method string
+		url    string
+		want   int
+	}{
+		{"can't block yourself", http.MethodPut, "/v1/users/1/block", http.StatusBadRequest},
+		{"block", http.MethodPut, "/v1/users/2/block", http.StatusNoContent},
+		{"block again", http.MethodPut, "/v1/users/2/block", http.StatusConflict},
+		{"follow a blocked user", http.MethodPut, "/v1/users/2/follow", http.StatusNotFound},
+		{"unblock", http.MethodPut, "/v1/users/2/unblock", http.StatusNoContent},
+		{"unblock again", http.MethodPut, "/v1/users/2/unblock", http.StatusNotFound},
+		{"follow once unblocked", http.MethodPut, "/v1/users/2/follow", http.StatusNoContent},
+	}
+
+	for _, step := range steps {
+		rr := executeRequest(newAuthRequest(t, app, step.method, step.url, ""), mux)
+		if rr.Code != step.want {
+			t.Fatalf("%s: got %d, want %d", step.name, rr.Code, step.want)
+		}
+	}
+
+	// Blocks go both ways
+	blocks.Blocks = map[int64]map[int64]bool{3: {1: true}}
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPut, "/v1/users/3/follow", ""), mux)
+	checkResponseCode(t, http.StatusNotFound, rr.Code)
+}
+
+func TestFollowRequests(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{2: {ID: 2, IsPrivate: true}}
+	followers := app.store.Followers.(*store.MockFollowerStore)
+	followers.Private = func(userID int64) bool { return userID == 2 }
+
+	steps := []struct {
+		name   string
+		method string
+		url    string
+		want   int
+	}{
+		{"follow a public account", http.MethodPut, "/v1/users/3/follow", http.StatusNoContent},
+		{"follow a private account", http.MethodPut, "/v1/users/2/follow", http.StatusAccepted},
+		{"ask again", http.MethodPut, "/v1/users/2/follow", http.StatusConflict},
+	}
+
+	for _, step := range steps {
+		rr := executeRequest(newAuthRequest(t, app, step.method, step.url, ""), mux)
+		if rr.Code != step.want {
+			t.Fatalf("%s: got %d, want %d", step.name, rr.Code, step.want)
+		}
+	}
+
+	if !followers.Pending[1][2] || followers.Following[1][2] {
+		t.Fatalf("got follows %v and requests %v, want user 2 asked", followers.Following, followers.Pending)
+	}
+}
+
+func TestFollowNotifications(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	followers := app.store.Followers.(*store.MockFollowerStore)
+	followers.Private = func(userID int64) bool { return userID == 2 }
+	followers.Pending = map[int64]map[int64]bool{4: {1: true}}
+
+	var (
+		mu       sync.Mutex
+		followed []events.Event
+	)
+	app.events.Subscribe(func(ctx context.Context, e events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		followed = append(followed, e)
+		return nil
+	}, events.UserFollowed)
+
+	for _, url := range []string{
+		"/v1/users/3/follow",
+		"/v1/users/2/follow",
+		"/v1/users/me/follow-requests/4/approve",
+	} {
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPut, url, ""), mux)
+		if rr.Code >= 300 {
+			t.Fatalf("%s: got %d", url, rr.Code)
+		}
+	}
+
+	app.events.Wait()
+
+	// Handlers run concurrently, so events arrive in any order
+	slices.SortFunc(followed, func(a, b events.Event) int { return int(a.ActorID - b.ActorID) })
+
+	// The request to user 2 isn't a follow until they approve it
+	want := []events.Event{
+		{Type: events.UserFollowed, ActorID: 1, UserID: 3},
+		{Type: events.UserFollowed, ActorID: 4, UserID: 1},
+	}
+	if !slices.Equal(followed, want) {
+		t.Errorf("got follow events %+v, want %+v", followed, want)
+	}
+}
+
+func TestAnswerFollowRequests(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	followers := app.store.Followers.(*store.MockFollowerStore)
+	followers.Pending = map[int64]map[int64]bool{
+		2: {1: true},
+		3: {1: true},
+	}
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/users/me/follow-requests?limit=1", ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	var page struct {
+		Data       []*store.Follower `json:"data"`
+		NextCursor string            `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Data) != 1 || page.Data[0].FollowerID != 3 {
+		t.Fatalf("got requests %+v, want user 3's", page.Data)
+	}
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodGet, "/v1/users/me/follow-requests?limit=1&cursor="+page.NextCursor, ""), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Data) != 1 || page.Data[0].FollowerID != 2 {
+		t.Fatalf("got requests %+v on the second page, want user 2's", page.Data)
+	}
+
+	steps := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"approve", "/v1/users/me/follow-requests/3/approve", http.StatusNoContent},
+		{"approve again", "/v1/users/me/follow-requests/3/approve", http.StatusNotFound},
+		{"reject", "/v1/users/me/follow-requests/2/reject", http.StatusNoContent},
+		{"reject again", "/v1/users/me/follow-requests/2/reject", http.StatusNotFound},
+		{"no request", "/v1/users/me/follow-requests/4/approve", http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		rr := executeRequest(newAuthRequest(t, app, http.MethodPut, step.url, ""), mux)
+		if rr.Code != step.want {
+			t.Fatalf("%s: got %d, want %d", step.name, rr.Code, step.want)
+		}
+	}
+
+	if !followers.Following[3][1] || followers.Following[2][1] {
+		t.Errorf("got follows %v, want only user 3 following", followers.Following)
+	}
+}
+
+func TestFollowRequestsRebuildTimelines(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+	ctx := context.Background()
+
+	app.timeline = timeline.New(timeline.NewMemoryBackend(), timeline.Config{MaxEntries: 10, CelebrityFollowers: 100})
+	built := func(userID int64) bool {
+		_, ok, err := app.timeline.Page(ctx, userID, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return ok
+	}
+
+	for _, id := range []int64{1, 2} {
+		if err := app.timeline.Seed(ctx, id, []store.TimelineEntry{{PostID: 1, AuthorID: 9, CreatedAt: time.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	followers := app.store.Followers.(*store.MockFollowerStore)
+	followers.Private = func(userID int64) bool { return userID == 3 }
+	followers.Pending = map[int64]map[int64]bool{2: {1: true}}
+
+	// Asking to follow adds nothing to the timeline yet
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPut, "/v1/users/3/follow", ""), mux)
+	checkResponseCode(t, http.StatusAccepted, rr.Code)
+
+	if !built(1) {
+		t.Error("got the timeline dropped by a follow request")
+	}
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodPut, "/v1/users/me/follow-requests/2/approve", ""), mux)
+	checkResponseCode(t, http.StatusNoContent, rr.Code)
+
+	if built(2) {
+		t.Error("got the approved follower's timeline kept, want it built again")
+	}
+}
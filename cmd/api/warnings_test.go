@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/qwerqy/social-api-go/internal/store/cache"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePostContentWarning(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantWarning   string
+		wantSensitive bool
+	}{
+		{"none", `{"title":"t","content":"c"}`, "", false},
+		{"sensitive", `{"title":"t","content":"c","sensitive":true}`, "", true},
+		{"warning", `{"title":"t","content":"c","content_warning":" spoilers "}`, "spoilers", true},
+		{"blank warning", `{"title":"t","content":"c","content_warning":"  "}`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+
+			rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts", tt.body), app.mount())
+			checkResponseCode(t, http.StatusCreated, rr.Code)
+
+			post := decodePost(t, rr.Body.Bytes())
+			if post.ContentWarning != tt.wantWarning || post.Sensitive != tt.wantSensitive {
+				t.Errorf("got warning %q and sensitive %v, want %q and %v", post.ContentWarning, post.Sensitive, tt.wantWarning, tt.wantSensitive)
+			}
+		})
+	}
+
+	app := newTestApplication(t, config{})
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPost, "/v1/posts", `{"title":"t","content":"c","content_warning":"spoilers"}`), app.mount())
+	post := app.store.Posts.(*store.MockPostStore).Posts[decodePost(t, rr.Body.Bytes()).ID]
+	if post.WarnedBy == nil || *post.WarnedBy != 1 {
+		t.Errorf("got warning set by %v, want the author", post.WarnedBy)
+	}
+}
+
+func TestPatchPostContentWarning(t *testing.T) {
+	const (
+		user      = 1
+		moderator = 2
+	)
+
+	author, other := int64(1), int64(3)
+
+	tests := []struct {
+		name      string
+		postUser  int64
+		warnedBy  *int64
+		roleLevel int64
+		body      string
+		code      int
+	}{
+		{"author clears their own warning", author, &author, user, `{"content_warning":"","sensitive":false}`, http.StatusOK},
+		{"moderator warns someone's post", other, nil, moderator, `{"content_warning":"gore"}`, http.StatusOK},
+		{"author clears a moderator's warning", author, &other, user, `{"content_warning":"","sensitive":false}`, http.StatusForbidden},
+		{"author rewords a moderator's warning", author, &other, user, `{"content_warning":"mild"}`, http.StatusForbidden},
+		{"author unflags a moderator's sensitive post", author, &other, user, `{"sensitive":false}`, http.StatusForbidden},
+		{"author edits a post a moderator warned", author, &other, user, `{"title":"edited","content_warning":"gore"}`, http.StatusOK},
+		{"moderator clears a moderator's warning", author, &other, moderator, `{"content_warning":"","sensitive":false}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{})
+			app.cacheStorage.Users.(*cache.MockUserStore).On("Delete", mock.Anything)
+			app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{
+				1: {ID: 1, Role: store.Role{Level: tt.roleLevel}},
+			}
+
+			posts := &store.MockPostStore{Posts: map[int64]*store.Post{
+				1: {ID: 1, UserID: tt.postUser, Content: "c", WarnedBy: tt.warnedBy},
+			}}
+			if tt.warnedBy != nil {
+				posts.Posts[1].ContentWarning, posts.Posts[1].Sensitive = "gore", true
+			}
+			app.store.Posts = posts
+
+			rr := executeRequest(newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1", tt.body), app.mount())
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestPatchPostRecordsWarningSetter(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.cacheStorage.Users.(*cache.MockUserStore).On("Delete", mock.Anything)
+	app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{
+		1: {ID: 1, Role: store.Role{Level: 2}},
+	}
+
+	posts := &store.MockPostStore{Posts: map[int64]*store.Post{
+		1: {ID: 1, UserID: 3, Content: "c"},
+	}}
+	app.store.Posts = posts
+
+	rr := executeRequest(newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1", `{"content_warning":"gore"}`), app.mount())
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	if post := posts.Posts[1]; post.WarnedBy == nil || *post.WarnedBy != 1 {
+		t.Errorf("got warning set by %v, want the moderator, 1", post.WarnedBy)
+	}
+
+	rr = executeRequest(newAuthRequest(t, app, http.MethodPatch, "/v1/posts/1", `{"content_warning":"","sensitive":false}`), app.mount())
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	if post := posts.Posts[1]; post.WarnedBy != nil || post.Sensitive {
+		t.Errorf("got warning set by %v and sensitive %v after clearing it, want neither", post.WarnedBy, post.Sensitive)
+	}
+}
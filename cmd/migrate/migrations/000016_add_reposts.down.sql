@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_posts_unique_repost;
+DROP INDEX IF EXISTS idx_posts_original_id;
+
+ALTER TABLE posts
+DROP COLUMN original_id;
+
+ALTER TABLE posts
+DROP COLUMN kind;
\ No newline at end of file
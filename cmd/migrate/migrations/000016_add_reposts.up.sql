@@ -0,0 +1,12 @@
+ALTER TABLE posts
+ADD COLUMN kind VARCHAR(10) NOT NULL DEFAULT 'post' CHECK (kind IN ('post', 'repost', 'quote'));
+
+ALTER TABLE posts
+ADD COLUMN original_id bigint REFERENCES posts (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_original_id ON posts (original_id);
+
+-- A user can only repost the same post once, quotes are not restricted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_unique_repost ON posts (user_id, original_id)
+WHERE
+  kind = 'repost';
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE comments
+ADD COLUMN parent_id bigint REFERENCES comments (id) ON DELETE CASCADE;
+
+ALTER TABLE comments
+ADD COLUMN depth int NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_top_level ON comments (post_id, id)
+WHERE
+  parent_id IS NULL;
\ No newline at end of file
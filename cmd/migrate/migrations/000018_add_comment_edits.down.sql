@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS comment_edits;
+
+ALTER TABLE comments
+DROP COLUMN deleted_at;
+
+ALTER TABLE comments
+DROP COLUMN updated_at;
+
+ALTER TABLE comments
+DROP COLUMN version;
\ No newline at end of file
@@ -0,0 +1,23 @@
+ALTER TABLE comments
+ADD COLUMN version INT NOT NULL DEFAULT 0;
+
+ALTER TABLE comments
+ADD COLUMN updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+-- Deleted comments that still have replies are kept as tombstones
+ALTER TABLE comments
+ADD COLUMN deleted_at timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS comment_edits (
+  id bigserial PRIMARY KEY,
+  comment_id bigint NOT NULL,
+  version INT NOT NULL,
+  content TEXT NOT NULL,
+  edited_by bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+  FOREIGN KEY (edited_by) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_edits_comment_id ON comment_edits (comment_id);
\ No newline at end of file
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS media (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  -- Uploads are unattached until a post references them
+  post_id bigint,
+  position int NOT NULL DEFAULT 0,
+  kind varchar(10) NOT NULL CHECK (kind IN ('image', 'video')),
+  content_type varchar(50) NOT NULL,
+  storage_key varchar(255) NOT NULL UNIQUE,
+  width int NOT NULL DEFAULT 0,
+  height int NOT NULL DEFAULT 0,
+  alt_text varchar(1500) NOT NULL DEFAULT '',
+  blurhash varchar(100) NOT NULL DEFAULT '',
+  size_bytes bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_post_id ON media (post_id, position);
+CREATE INDEX IF NOT EXISTS idx_media_unattached ON media (created_at) WHERE post_id IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE posts
+ADD COLUMN visibility varchar(10) NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'mentioned'));
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS blocks (
+  user_id bigint NOT NULL,
+  blocked_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (user_id, blocked_id),
+  CHECK (user_id <> blocked_id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tags;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS explicit_tags;
\ No newline at end of file
@@ -0,0 +1,39 @@
+-- Normalise existing tags the way the API does (entities.NormalizeTag): trim,
+-- strip leading #s and the whitespace after them, turn inner whitespace into
+-- underscores, lowercase and NFC (the API case folds, which only differs from
+-- lower() for a handful of characters such as ß). Tags over MaxTagLength
+-- bytes are dropped.
+UPDATE posts p
+SET tags = ARRAY(
+  SELECT t.tag
+  FROM (
+    SELECT
+      normalize(lower(regexp_replace(regexp_replace(tag, '^\s*#*\s*|\s+$', '', 'g'), '\s+', '_', 'g')), NFC) AS tag,
+      ord
+    FROM unnest(p.tags) WITH ORDINALITY AS x (tag, ord)
+  ) t
+  WHERE t.tag <> '' AND octet_length(t.tag) <= 100
+  GROUP BY t.tag
+  ORDER BY MIN(t.ord)
+)
+WHERE p.tags IS NOT NULL;
+
+-- Tags given with a post are kept apart from the hashtags in its content.
+-- Until now every tag was given explicitly.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS explicit_tags VARCHAR(100) [];
+
+UPDATE posts SET explicit_tags = tags;
+
+CREATE TABLE IF NOT EXISTS tags (
+  name varchar(100) PRIMARY KEY,
+  post_count int NOT NULL DEFAULT 0,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Prefix searches for autocomplete
+CREATE INDEX IF NOT EXISTS idx_tags_name_pattern ON tags (name varchar_pattern_ops);
+
+INSERT INTO tags (name, post_count)
+SELECT tag, COUNT(*)
+FROM posts, unnest(posts.tags) AS tag
+GROUP BY tag;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_created_at;
+DROP INDEX IF EXISTS idx_comments_created_at;
+DROP TABLE IF EXISTS reactions;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reactions (
+  post_id bigint NOT NULL,
+  user_id bigint NOT NULL,
+  kind varchar(10) NOT NULL CHECK (kind IN ('like', 'love', 'laugh', 'wow', 'sad', 'angry')),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (post_id, user_id),
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Trending only looks at recent engagement
+CREATE INDEX IF NOT EXISTS idx_reactions_created_at ON reactions (created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments (created_at);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at);
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content_html_version;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content_html;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content_format;
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE posts
+ADD COLUMN content_format varchar(10) NOT NULL DEFAULT 'plain' CHECK (content_format IN ('plain', 'markdown'));
+
+-- The content is rendered when a post is written, content_html_version is the
+-- version it was rendered from. Existing posts are rendered by the API's
+-- backfill job on startup
+ALTER TABLE posts
+ADD COLUMN content_html text;
+
+ALTER TABLE posts
+ADD COLUMN content_html_version int;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS link_previews (
+  url text PRIMARY KEY,
+  title text NOT NULL DEFAULT '',
+  description text NOT NULL DEFAULT '',
+  image_url text NOT NULL DEFAULT '',
+  site_name text NOT NULL DEFAULT '',
+  -- Failed fetches are kept too so a broken link isn't fetched for every post
+  -- that has it
+  ok boolean NOT NULL,
+  fetched_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
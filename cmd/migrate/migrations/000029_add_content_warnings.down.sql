@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS user_settings;
+
+ALTER TABLE media
+DROP COLUMN IF EXISTS sensitive;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content_warning_by;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS sensitive;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content_warning;
\ No newline at end of file
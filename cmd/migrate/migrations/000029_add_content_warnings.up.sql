@@ -0,0 +1,23 @@
+ALTER TABLE posts
+ADD COLUMN content_warning varchar(200) NOT NULL DEFAULT '';
+
+ALTER TABLE posts
+ADD COLUMN sensitive boolean NOT NULL DEFAULT false;
+
+-- Who set the warning or the flag, only a moderator can clear them when it
+-- wasn't the author. Not a foreign key so the warning outlives the moderator
+ALTER TABLE posts
+ADD COLUMN content_warning_by bigint;
+
+ALTER TABLE media
+ADD COLUMN sensitive boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS user_settings (
+  user_id bigint PRIMARY KEY,
+  -- Whether posts with a content warning or sensitive media are shown
+  -- expanded instead of collapsed
+  expand_sensitive boolean NOT NULL DEFAULT false,
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pinned_posts;
\ No newline at end of file
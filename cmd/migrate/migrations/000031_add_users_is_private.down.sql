@@ -0,0 +1,5 @@
+ALTER TABLE followers
+DROP COLUMN IF EXISTS pending;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS is_private;
\ No newline at end of file
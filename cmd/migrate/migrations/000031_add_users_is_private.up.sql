@@ -0,0 +1,8 @@
+-- Only followers see the posts of private accounts
+ALTER TABLE users
+ADD COLUMN is_private boolean NOT NULL DEFAULT false;
+
+-- Following a private account asks it first, the follow counts once the
+-- account approves it
+ALTER TABLE followers
+ADD COLUMN pending boolean NOT NULL DEFAULT false;
\ No newline at end of file
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_followers_requests;
+
+DROP INDEX IF EXISTS idx_comments_post_id_top_level;
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_top_level ON comments (post_id, id)
+WHERE
+  parent_id IS NULL;
+
+DROP INDEX IF EXISTS idx_mentions_user_id;
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id, id);
+
+DROP INDEX IF EXISTS idx_bookmarks_user_id;
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id ON bookmarks (user_id, id);
\ No newline at end of file
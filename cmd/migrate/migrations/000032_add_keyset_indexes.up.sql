@@ -0,0 +1,16 @@
+-- Bookmarks, mentions, comments and follow requests are paged by creation
+-- time then ID
+DROP INDEX IF EXISTS idx_bookmarks_user_id;
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id ON bookmarks (user_id, created_at, id);
+
+DROP INDEX IF EXISTS idx_mentions_user_id;
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id, created_at, id);
+
+DROP INDEX IF EXISTS idx_comments_post_id_top_level;
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_top_level ON comments (post_id, created_at, id)
+WHERE
+  parent_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_followers_requests ON followers (user_id, created_at, follower_id)
+WHERE
+  pending;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_followers_follower_id;
+
+DROP INDEX IF EXISTS idx_posts_user_id_id;
\ No newline at end of file
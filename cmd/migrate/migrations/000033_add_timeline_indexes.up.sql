@@ -0,0 +1,4 @@
+-- Looking up who a user follows, to build their timeline
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_id ON posts (user_id, id);
\ No newline at end of file
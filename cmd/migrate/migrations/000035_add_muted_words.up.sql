@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS muted_words (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  kind varchar(10) NOT NULL CHECK (kind IN ('keyword', 'regex', 'hashtag')),
+  pattern varchar(100) NOT NULL,
+  -- Where the rule hides things: feed, notifications and/or comments
+  scopes varchar(20)[] NOT NULL,
+  -- NULL for rules that never expire
+  expires_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  UNIQUE (user_id, kind, pattern),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
\ No newline at end of file
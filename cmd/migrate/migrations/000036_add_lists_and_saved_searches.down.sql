@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS saved_searches;
+
+DROP TABLE IF EXISTS list_followers;
+
+DROP TABLE IF EXISTS list_members;
+
+DROP TABLE IF EXISTS lists;
\ No newline at end of file
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  id bigserial PRIMARY KEY,
+  -- Who is notified, and who did what they are notified of
+  user_id bigint NOT NULL,
+  actor_id bigint NOT NULL,
+  type varchar(20) NOT NULL CHECK (type IN ('follow', 'mention', 'comment', 'reply', 'reaction', 'repost', 'quote')),
+  post_id bigint,
+  comment_id bigint,
+  -- Notifications with the same key are shown as one, like everyone who
+  -- reacted to a post on a given day. NULL for the ones shown on their own.
+  group_key varchar(100),
+  read_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_id ON notifications (user_id, id);
+
+-- A user doing the same thing twice, like reacting again, isn't notified
+-- twice
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_group_actor ON notifications (user_id, group_key, actor_id)
+WHERE group_key IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,85 @@
+// Package entities finds structured references, such as @mentions, in the
+// text of posts and comments.
+package entities
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 255
+)
+
+// Mention is an @username in a text. Offset and Length count runes, not
+// bytes, and cover the leading @.
+type Mention struct {
+	Offset   int
+	Length   int
+	Username string
+}
+
+// Mentions returns the @mentions in text in the order they appear. An @
+// only starts a mention at the beginning of a word, so email addresses are
+// not picked up.
+func Mentions(text string) []Mention {
+	var mentions []Mention
+
+	var prev rune
+	offset := 0
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		if r == '@' && !isUsernameRune(prev) && prev != '@' {
+			username, runes := readUsername(text[i+size:])
+			if n := len(username); n >= minUsernameLength && n <= maxUsernameLength {
+				mentions = append(mentions, Mention{
+					Offset:   offset,
+					Length:   runes + 1,
+					Username: username,
+				})
+
+				i += size + len(username)
+				offset += runes + 1
+				prev, _ = utf8.DecodeLastRuneInString(username)
+				continue
+			}
+		}
+
+		prev = r
+		i += size
+		offset++
+	}
+
+	return mentions
+}
+
+// readUsername returns the username at the start of text and its length in
+// runes. Dots and dashes can't end a username so sentences like "thanks
+// @alice." work.
+func readUsername(text string) (string, int) {
+	end, runes := 0, 0
+	lastEnd, lastRunes := 0, 0
+
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isUsernameRune(r) && r != '.' && r != '-' {
+			break
+		}
+
+		end += size
+		runes++
+
+		if isUsernameRune(r) {
+			lastEnd, lastRunes = end, runes
+		}
+	}
+
+	return text[:lastEnd], lastRunes
+}
+
+func isUsernameRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
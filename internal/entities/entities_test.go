@@ -0,0 +1,89 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Mention
+	}{
+		{
+			name: "single mention",
+			text: "hi @alice",
+			want: []Mention{{Offset: 3, Length: 6, Username: "alice"}},
+		},
+		{
+			name: "trailing punctuation",
+			text: "thanks @bob.smith. and @carol-",
+			want: []Mention{
+				{Offset: 7, Length: 10, Username: "bob.smith"},
+				{Offset: 23, Length: 6, Username: "carol"},
+			},
+		},
+		{
+			name: "offsets count runes",
+			text: "héllo 👋 @zoë!",
+			want: []Mention{{Offset: 8, Length: 4, Username: "zoë"}},
+		},
+		{
+			name: "email addresses are not mentions",
+			text: "mail me at dave@example.com",
+		},
+		{
+			name: "too short",
+			text: "@ab @@alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{"Go", "go", "#golang", "  ##GoLang ", "Café", "café", "new  york", "#", ""})
+	want := []string{"go", "golang", "café", "new_york"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags() = %q, want %q", got, want)
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Hashtag
+	}{
+		{
+			name: "hashtags are normalised",
+			text: "learning #Go and #GoLang!",
+			want: []Hashtag{
+				{Offset: 9, Length: 3, Tag: "go"},
+				{Offset: 17, Length: 7, Tag: "golang"},
+			},
+		},
+		{
+			name: "numbers and anchors are not hashtags",
+			text: "issue #123 see page#top ##twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hashtags(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
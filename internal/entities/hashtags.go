@@ -0,0 +1,113 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxTagLength is the longest a tag can be once normalised, in bytes, to fit
+// the database column.
+const MaxTagLength = 100
+
+var folder = cases.Fold()
+
+// Hashtag is a #tag in a text. Offset and Length count runes and cover the
+// leading #, Tag is normalised.
+type Hashtag struct {
+	Offset int
+	Length int
+	Tag    string
+}
+
+// NormalizeTag returns the canonical form of a tag so "Go", "go" and "#go"
+// are the same tag: NFC normalised, case folded, without leading #s and with
+// inner whitespace turned into underscores. It returns "" when nothing is
+// left or the tag is too long.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimLeft(tag, "#")
+	tag = strings.Join(strings.Fields(tag), "_")
+	tag = norm.NFC.String(folder.String(tag))
+
+	if len(tag) > MaxTagLength {
+		return ""
+	}
+
+	return tag
+}
+
+// NormalizeTags normalises tags and drops empty ones and duplicates, keeping
+// the order they were given in.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+
+	for _, t := range tags {
+		t = NormalizeTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+
+	return normalized
+}
+
+// Hashtags returns the #hashtags in text in the order they appear. Like
+// mentions, a # only starts a hashtag at the beginning of a word, and a
+// hashtag needs at least one letter so "#1" is left alone.
+func Hashtags(text string) []Hashtag {
+	var hashtags []Hashtag
+
+	var prev rune
+	offset := 0
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		if r == '#' && !isUsernameRune(prev) && prev != '#' {
+			word, runes := readHashtag(text[i+size:])
+			if tag := NormalizeTag(word); tag != "" && strings.IndexFunc(word, unicode.IsLetter) >= 0 {
+				hashtags = append(hashtags, Hashtag{
+					Offset: offset,
+					Length: runes + 1,
+					Tag:    tag,
+				})
+
+				i += size + len(word)
+				offset += runes + 1
+				prev, _ = utf8.DecodeLastRuneInString(word)
+				continue
+			}
+		}
+
+		prev = r
+		i += size
+		offset++
+	}
+
+	return hashtags
+}
+
+func readHashtag(text string) (string, int) {
+	end, runes := 0, 0
+
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		// Combining marks keep decomposed accents part of the tag
+		if !isUsernameRune(r) && !unicode.Is(unicode.Mn, r) {
+			break
+		}
+
+		end += size
+		runes++
+	}
+
+	return text[:end], runes
+}
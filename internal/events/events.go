@@ -0,0 +1,103 @@
+// Package events lets the write paths of the API announce what happened
+// without knowing who cares. Handlers publish an event once a change is
+// stored, and subscribers such as notifications react to it in the
+// background, so a slow or failing subscriber never fails the request.
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	PostCreated = "post.created"
+	PostUpdated = "post.updated"
+	PostDeleted = "post.deleted"
+	// PostReacted is published when a user reacts to a post or changes
+	// their reaction
+	PostReacted    = "post.reacted"
+	CommentCreated = "comment.created"
+	UserFollowed   = "user.followed"
+)
+
+// Event is something a user did. Only the fields that apply to its type are
+// set, subscribers load whatever else they need.
+type Event struct {
+	Type string
+	// ActorID is the user who did it
+	ActorID   int64
+	PostID    int64
+	CommentID int64
+	// UserID is the user it was done to, like the one followed or the
+	// author of the post
+	UserID int64
+	// Reaction is the kind of reaction of PostReacted events
+	Reaction string
+}
+
+type Handler func(context.Context, Event) error
+
+// Bus delivers each published event to the handlers subscribed to its type,
+// each in its own goroutine.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	all      []Handler
+	inFlight sync.WaitGroup
+	onError  func(Event, error)
+}
+
+// New returns a bus reporting the errors of handlers to onError, which may
+// be nil to ignore them.
+func New(onError func(Event, error)) *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+		onError:  onError,
+	}
+}
+
+// Subscribe calls h with every event of the given types, or with every event
+// when no type is given.
+func (b *Bus) Subscribe(h Handler, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(types) == 0 {
+		b.all = append(b.all, h)
+		return
+	}
+
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], h)
+	}
+}
+
+// Publish hands e to its handlers and returns without waiting for them.
+// They run with ctx's values but aren't cancelled with it, the request that
+// published e usually ends first.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[e.Type])+len(b.all))
+	handlers = append(handlers, b.handlers[e.Type]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+
+	for _, h := range handlers {
+		b.inFlight.Add(1)
+
+		go func() {
+			defer b.inFlight.Done()
+
+			if err := h(ctx, e); err != nil && b.onError != nil {
+				b.onError(e, err)
+			}
+		}()
+	}
+}
+
+// Wait blocks until the handlers of the events published so far are done.
+func (b *Bus) Wait() {
+	b.inFlight.Wait()
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	var failed []Event
+
+	record := func(name string) Handler {
+		return func(_ context.Context, e Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			got = append(got, name+":"+e.Type)
+			return nil
+		}
+	}
+
+	bus := New(func(e Event, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		failed = append(failed, e)
+	})
+
+	bus.Subscribe(record("follows"), UserFollowed)
+	bus.Subscribe(record("posts"), PostCreated, PostDeleted)
+	bus.Subscribe(record("all"))
+	bus.Subscribe(func(context.Context, Event) error { return errors.New("boom") }, PostDeleted)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Handlers outlive the request that published the event
+	bus.Subscribe(func(ctx context.Context, e Event) error { return ctx.Err() }, PostCreated)
+
+	bus.Publish(ctx, Event{Type: PostCreated})
+	bus.Publish(ctx, Event{Type: UserFollowed})
+	bus.Publish(ctx, Event{Type: PostDeleted})
+	bus.Publish(ctx, Event{Type: CommentCreated})
+	bus.Wait()
+
+	slices.Sort(got)
+	want := []string{
+		"all:comment.created",
+		"all:post.created",
+		"all:post.deleted",
+		"all:user.followed",
+		"follows:user.followed",
+		"posts:post.created",
+		"posts:post.deleted",
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("handlers got %v, want %v", got, want)
+	}
+
+	if len(failed) != 1 || failed[0].Type != PostDeleted {
+		t.Errorf("errors were reported for %v, want one post.deleted", failed)
+	}
+}
@@ -0,0 +1,317 @@
+// Package linkpreview fetches the Open Graph and Twitter card metadata of web
+// pages to show links as preview cards.
+//
+// The pages are chosen by users, so fetching them must not become a way to
+// reach the network the server runs in: connections to private, loopback and
+// link-local addresses are refused after DNS resolution, redirects are
+// capped and re-checked, and both the time spent and the bytes read are
+// bounded.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultTimeout = time.Second * 5
+	// MaxBodySize is how much of a page is read, the metadata lives in the
+	// head so there is no need for the rest
+	MaxBodySize  = 1 << 20 // 1MB
+	MaxRedirects = 3
+
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+var (
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrNotHTML          = errors.New("not an html page")
+	ErrNoMetadata       = errors.New("page has no preview metadata")
+)
+
+type Card struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a Fetcher that gives up on a page after timeout.
+func NewFetcher(timeout time.Duration) *Fetcher {
+	return newFetcher(timeout, publicIP)
+}
+
+func newFetcher(timeout time.Duration, allowed func(net.IP) bool) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// Checking the address being connected to, rather than the host
+		// name, also covers names resolving to private addresses
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		// No proxy, it would be the one doing the connecting
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       time.Minute,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				if !fetchable(req.URL) {
+					return fmt.Errorf("%w: %s", ErrForbiddenAddress, req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Fetch returns the preview card of the page at rawURL.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Card, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fetchable(u) {
+		return nil, fmt.Errorf("%w: %s", ErrForbiddenAddress, u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "GopherSocialBot/1.0 (link preview)")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	card := parse(io.LimitReader(resp.Body, MaxBodySize), resp.Request.URL)
+	if card.Title == "" {
+		return nil, ErrNoMetadata
+	}
+
+	return card, nil
+}
+
+// parse reads the preview metadata from the head of a page. Open Graph wins
+// over Twitter cards, which win over the plain title and description.
+func parse(r io.Reader, base *url.URL) *Card {
+	meta := make(map[string]string)
+	var title string
+
+	z := html.NewTokenizer(r)
+
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+
+			switch string(name) {
+			case "body":
+				break loop
+
+			case "title":
+				if z.Next() == html.TextToken && title == "" {
+					title = string(z.Text())
+				}
+
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+
+				if _, ok := meta[key]; key != "" && !ok {
+					meta[key] = content
+				}
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				break loop
+			}
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := clean(meta[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	card := &Card{
+		URL:         base.String(),
+		Title:       truncate(first("og:title", "twitter:title"), maxTitleLength),
+		Description: truncate(first("og:description", "twitter:description", "description"), maxDescriptionLength),
+		SiteName:    first("og:site_name"),
+	}
+
+	if card.Title == "" {
+		card.Title = truncate(clean(title), maxTitleLength)
+	}
+
+	if image := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if u, err := base.Parse(image); err == nil && fetchable(u) {
+			card.ImageURL = u.String()
+		}
+	}
+
+	return card
+}
+
+// FirstURL returns the first http(s) URL in text, or "" when there is none.
+func FirstURL(text string) string {
+	for i := 0; i < len(text); i++ {
+		if !strings.HasPrefix(text[i:], "http://") && !strings.HasPrefix(text[i:], "https://") {
+			continue
+		}
+
+		if i > 0 {
+			if r, _ := utf8.DecodeLastRuneInString(text[:i]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+				continue
+			}
+		}
+
+		end := strings.IndexFunc(text[i:], func(r rune) bool {
+			return unicode.IsSpace(r) || r == '<' || r == '>' || r == '"'
+		})
+		if end < 0 {
+			end = len(text) - i
+		}
+
+		candidate := strings.TrimRight(text[i:i+end], ".,;:!?)'*_~")
+
+		u, err := url.Parse(candidate)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		u.Fragment = ""
+		return u.String()
+	}
+
+	return ""
+}
+
+func fetchable(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// publicIP reports whether ip can be reached from the internet, which is
+// the only place link previews are fetched from.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, block := range reservedBlocks {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reservedBlocks are the ranges not covered by the net.IP checks that still
+// don't lead to the public internet.
+var reservedBlocks = func() []*net.IPNet {
+	var blocks []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",     // this network
+		"100.64.0.0/10", // carrier-grade NAT
+		"192.0.0.0/24",  // IETF protocol assignments
+		"198.18.0.0/15", // benchmarking
+		"240.0.0.0/4",   // reserved
+		"64:ff9b::/96",  // NAT64, can embed private IPv4 addresses
+	} {
+		_, block, _ := net.ParseCIDR(cidr)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}()
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+
+	return string([]rune(s)[:max-1]) + "…"
+}
@@ -0,0 +1,181 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestFetcher returns a fetcher that can reach the local test servers.
+func newTestFetcher() *Fetcher {
+	return newFetcher(time.Second, func(ip net.IP) bool { return ip.IsLoopback() })
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head>
+			<title>Fallback</title>
+			<meta property="og:title" content="Gophers &amp; friends">
+			<meta name="twitter:title" content="Twitter title">
+			<meta name="description" content="  A page
+				about gophers ">
+			<meta property="og:image" content="/img/gopher.png">
+			<meta property="og:site_name" content="Example">
+		</head><body><meta property="og:title" content="ignored"></body></html>`)
+	})
+
+	mux.HandleFunc("/title", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<title>Just a title</title><meta property="og:image" content="javascript:alert(1)">`)
+	})
+
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"title": "nope"}`)
+	})
+
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	})
+
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<!-- padding -->", MaxBodySize/10))
+		fmt.Fprint(w, `<title>Too far down</title></head></html>`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newTestFetcher()
+	ctx := context.Background()
+
+	t.Run("open graph", func(t *testing.T) {
+		card, err := f.Fetch(ctx, srv.URL+"/og")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Card{
+			URL:         srv.URL + "/og",
+			Title:       "Gophers & friends",
+			Description: "A page about gophers",
+			ImageURL:    srv.URL + "/img/gopher.png",
+			SiteName:    "Example",
+		}
+		if *card != want {
+			t.Errorf("got %+v, want %+v", *card, want)
+		}
+	})
+
+	t.Run("title fallback and unsafe image", func(t *testing.T) {
+		card, err := f.Fetch(ctx, srv.URL+"/title")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if card.Title != "Just a title" || card.ImageURL != "" {
+			t.Errorf("got %+v", *card)
+		}
+	})
+
+	t.Run("not html", func(t *testing.T) {
+		if _, err := f.Fetch(ctx, srv.URL+"/json"); !errors.Is(err, ErrNotHTML) {
+			t.Errorf("got %v, want %v", err, ErrNotHTML)
+		}
+	})
+
+	t.Run("redirects are capped", func(t *testing.T) {
+		if _, err := f.Fetch(ctx, srv.URL+"/redirect/"); !errors.Is(err, ErrTooManyRedirects) {
+			t.Errorf("got %v, want %v", err, ErrTooManyRedirects)
+		}
+	})
+
+	t.Run("body is capped", func(t *testing.T) {
+		if _, err := f.Fetch(ctx, srv.URL+"/large"); !errors.Is(err, ErrNoMetadata) {
+			t.Errorf("got %v, want %v", err, ErrNoMetadata)
+		}
+	})
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address was fetched")
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(time.Second)
+
+	for _, u := range []string{
+		srv.URL,
+		strings.Replace(srv.URL, "127.0.0.1", "localhost", 1),
+		"ftp://example.com/file",
+	} {
+		if _, err := f.Fetch(context.Background(), u); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Fetch(%q) = %v, want %v", u, err, ErrForbiddenAddress)
+		}
+	}
+}
+
+func TestFetchTimesOut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second * 5):
+		}
+	}))
+	defer srv.Close()
+
+	f := newFetcher(time.Millisecond*100, func(ip net.IP) bool { return true })
+
+	if _, err := f.Fetch(context.Background(), srv.URL); err == nil {
+		t.Error("expected a timeout")
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fc00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	} {
+		if got := publicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("publicIP(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestFirstURL(t *testing.T) {
+	tests := map[string]string{
+		"see https://example.com/a?b=1#frag.":       "https://example.com/a?b=1",
+		"[link](http://example.com/x) and more":     "http://example.com/x",
+		"nothttps://example.com or https:// broken": "",
+		"no links here": "",
+	}
+
+	for text, want := range tests {
+		if got := FirstURL(text); got != want {
+			t.Errorf("FirstURL(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+// Package markdown renders the restricted Markdown dialect of posts to HTML.
+//
+// The renderer never passes any of its input through: every character of the
+// source is escaped and only the handful of tags below are ever produced, so
+// the output is safe to embed without further sanitising.
+//
+// Blocks: paragraphs, fenced code blocks, > quotes and - or 1. lists.
+// Inline: **strong**, *em* or _em_, ~~del~~, `code`, [text](url) links and
+// bare http(s) URLs. Mentions and hashtags are linked through Options.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/qwerqy/social-api-go/internal/entities"
+)
+
+// maxQuoteDepth stops quotes nesting past a readable depth, deeper markers
+// are kept as text.
+const maxQuoteDepth = 3
+
+// linkRel is set on every link as they all lead to user submitted content.
+const linkRel = "nofollow noopener noreferrer"
+
+type Options struct {
+	// MentionURL returns where a mention links to, or "" to leave it as text.
+	MentionURL func(username string) string
+	// HashtagURL returns where a normalised hashtag links to, or "" to leave
+	// it as text.
+	HashtagURL func(tag string) string
+}
+
+// Render renders Markdown source to HTML.
+func Render(src string, opts Options) string {
+	r := &renderer{opts: opts}
+	r.blocks(splitLines(src), 0)
+
+	return r.out.String()
+}
+
+// RenderText renders plain text to HTML: paragraphs and line breaks are kept
+// and URLs, mentions and hashtags are linked, anything else is left as text.
+func RenderText(src string, opts Options) string {
+	r := &renderer{opts: opts, plain: true}
+
+	for _, p := range paragraphs(splitLines(src)) {
+		r.out.WriteString("<p>")
+		r.inline(strings.Join(p, "\n"))
+		r.out.WriteString("</p>\n")
+	}
+
+	return r.out.String()
+}
+
+type renderer struct {
+	opts  Options
+	out   strings.Builder
+	plain bool
+	// inLink stops links from being nested in link text
+	inLink bool
+}
+
+func (r *renderer) blocks(lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			// Skip the closing fence, an unclosed block runs to the end
+			i++
+
+			r.out.WriteString("<pre><code>")
+			r.out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			r.out.WriteString("</code></pre>\n")
+
+		case strings.HasPrefix(trimmed, ">") && depth < maxQuoteDepth:
+			var quote []string
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(line, ">") {
+					break
+				}
+				line = strings.TrimPrefix(line, ">")
+				quote = append(quote, strings.TrimPrefix(line, " "))
+			}
+
+			r.out.WriteString("<blockquote>\n")
+			r.blocks(quote, depth+1)
+			r.out.WriteString("</blockquote>\n")
+
+		case listItem(trimmed) != "":
+			tag := listItem(trimmed)
+
+			r.out.WriteString("<" + tag + ">\n")
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if listItem(line) != tag {
+					break
+				}
+
+				r.out.WriteString("<li>")
+				r.inline(listText(line))
+				r.out.WriteString("</li>\n")
+			}
+			r.out.WriteString("</" + tag + ">\n")
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				line := strings.TrimSpace(lines[i])
+				if line == "" || (len(para) > 0 && startsBlock(line)) {
+					break
+				}
+				para = append(para, line)
+			}
+
+			r.out.WriteString("<p>")
+			r.inline(strings.Join(para, "\n"))
+			r.out.WriteString("</p>\n")
+		}
+	}
+}
+
+func (r *renderer) inline(s string) {
+	var text strings.Builder
+
+	flush := func() {
+		r.text(text.String())
+		text.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if c == '\n' {
+			flush()
+			r.out.WriteString("<br>\n")
+			i++
+			continue
+		}
+
+		if !r.inLink && atWordStart(s, i) && (strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")) {
+			if n := autolinkLength(s[i:]); n > 0 {
+				flush()
+				r.link(s[i:i+n], s[i:i+n], false)
+				i += n
+				continue
+			}
+		}
+
+		if r.plain {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+
+		// Escaped characters are written out directly so an escaped @ or #
+		// doesn't start a mention or hashtag either
+		if c == '\\' && i+1 < len(s) && isMarkup(s[i+1]) {
+			flush()
+			r.out.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		}
+
+		if c == '`' {
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				flush()
+				r.out.WriteString("<code>")
+				r.out.WriteString(html.EscapeString(s[i+1 : i+1+end]))
+				r.out.WriteString("</code>")
+				i += end + 2
+				continue
+			}
+		}
+
+		if c == '[' && !r.inLink {
+			if label, href, n := parseLink(s[i:]); n > 0 {
+				flush()
+				r.link(label, href, true)
+				i += n
+				continue
+			}
+		}
+
+		if n := r.emphasis(s, i, flush); n > 0 {
+			i += n
+			continue
+		}
+
+		text.WriteByte(c)
+		i++
+	}
+
+	flush()
+}
+
+// emphasis renders the emphasis starting at s[i], if any, and returns how
+// much of s it used.
+func (r *renderer) emphasis(s string, i int, flush func()) int {
+	for _, e := range []struct{ delim, tag string }{
+		{"**", "strong"},
+		{"~~", "del"},
+		{"*", "em"},
+		{"_", "em"},
+	} {
+		if !strings.HasPrefix(s[i:], e.delim) {
+			continue
+		}
+
+		// Underscores only count at word boundaries so snake_case words,
+		// mentions and hashtags are left alone
+		if e.delim == "_" && !atWordStart(s, i) {
+			return 0
+		}
+
+		start := i + len(e.delim)
+		end := closingDelim(s[start:], e.delim)
+		if end < 0 {
+			continue
+		}
+
+		if e.delim == "_" {
+			if next := start + end + 1; next < len(s) && isWordRune(s[next:]) {
+				continue
+			}
+		}
+
+		flush()
+		r.out.WriteString("<" + e.tag + ">")
+		r.inline(s[start : start+end])
+		r.out.WriteString("</" + e.tag + ">")
+
+		return len(e.delim)*2 + end
+	}
+
+	return 0
+}
+
+// link writes a link to href, or just its label when href isn't a safe URL.
+func (r *renderer) link(label, href string, markup bool) {
+	u, err := url.Parse(href)
+	if err != nil || !safeURL(u) {
+		r.text(label)
+		return
+	}
+
+	r.out.WriteString(`<a href="` + html.EscapeString(u.String()) + `" rel="` + linkRel + `">`)
+
+	r.inLink = true
+	if markup {
+		r.inline(label)
+	} else {
+		r.out.WriteString(html.EscapeString(label))
+	}
+	r.inLink = false
+
+	r.out.WriteString("</a>")
+}
+
+// text writes s escaped, linking its mentions and hashtags.
+func (r *renderer) text(s string) {
+	if s == "" {
+		return
+	}
+
+	if r.inLink {
+		r.out.WriteString(html.EscapeString(s))
+		return
+	}
+
+	type span struct {
+		start, end int
+		href       string
+	}
+
+	var spans []span
+	offsets := runeOffsets(s)
+
+	for _, m := range entities.Mentions(s) {
+		if r.opts.MentionURL == nil {
+			break
+		}
+		if href := r.opts.MentionURL(m.Username); href != "" {
+			spans = append(spans, span{offsets[m.Offset], offsets[m.Offset+m.Length], href})
+		}
+	}
+
+	for _, h := range entities.Hashtags(s) {
+		if r.opts.HashtagURL == nil {
+			break
+		}
+		if href := r.opts.HashtagURL(h.Tag); href != "" {
+			spans = append(spans, span{offsets[h.Offset], offsets[h.Offset+h.Length], href})
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	last := 0
+	for _, sp := range spans {
+		r.out.WriteString(html.EscapeString(s[last:sp.start]))
+		r.out.WriteString(`<a href="` + html.EscapeString(sp.href) + `" rel="` + linkRel + `">`)
+		r.out.WriteString(html.EscapeString(s[sp.start:sp.end]))
+		r.out.WriteString("</a>")
+		last = sp.end
+	}
+
+	r.out.WriteString(html.EscapeString(s[last:]))
+}
+
+func safeURL(u *url.URL) bool {
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	default:
+		return false
+	}
+}
+
+// parseLink parses a [label](href) link at the start of s and returns how
+// long it is, or 0 when there is none.
+func parseLink(s string) (label, href string, n int) {
+	mid := strings.Index(s, "](")
+	if mid < 2 {
+		return "", "", 0
+	}
+
+	end := strings.IndexByte(s[mid+2:], ')')
+	if end < 0 {
+		return "", "", 0
+	}
+
+	label = s[1:mid]
+	href = strings.TrimSpace(s[mid+2 : mid+2+end])
+
+	if strings.ContainsAny(label, "[]\n") || href == "" || strings.ContainsAny(href, " \n") {
+		return "", "", 0
+	}
+
+	return label, href, mid + 3 + end
+}
+
+// autolinkLength returns the length of the URL at the start of s, leaving
+// out trailing punctuation that more likely ends the sentence.
+func autolinkLength(s string) int {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '<' || r == '>' || r == '"'
+	})
+	if end < 0 {
+		end = len(s)
+	}
+
+	return len(strings.TrimRight(s[:end], ".,;:!?)'*_~"))
+}
+
+// closingDelim returns where delim closes the span starting at s, or -1.
+// Spans can't be empty or start or end with a space.
+func closingDelim(s, delim string) int {
+	if s == "" || s[0] == ' ' || strings.HasPrefix(s, delim) {
+		return -1
+	}
+
+	for i := 1; i+len(delim) <= len(s); i++ {
+		if s[i] == '\n' && s[i-1] == '\n' {
+			return -1
+		}
+
+		if strings.HasPrefix(s[i:], delim) && s[i-1] != ' ' {
+			// "**" closes strong, not an em that happens to end there
+			if delim == "*" && strings.HasPrefix(s[i:], "**") {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+
+	return -1
+}
+
+func listItem(line string) string {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+		return "ul"
+	}
+
+	digits := strings.IndexFunc(line, func(r rune) bool { return r < '0' || r > '9' })
+	if digits > 0 && digits <= 3 && strings.HasPrefix(line[digits:], ". ") {
+		return "ol"
+	}
+
+	return ""
+}
+
+func listText(line string) string {
+	_, text, _ := strings.Cut(line, " ")
+	return strings.TrimSpace(text)
+}
+
+func startsBlock(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, ">") || listItem(line) != ""
+}
+
+func paragraphs(lines []string) [][]string {
+	var paras [][]string
+	var current []string
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paras = append(paras, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, strings.TrimSpace(line))
+	}
+
+	if len(current) > 0 {
+		paras = append(paras, current)
+	}
+
+	return paras
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(s, "\n")
+}
+
+// runeOffsets maps the rune offsets of s to byte offsets, with one past the
+// end for the length of s.
+func runeOffsets(s string) []int {
+	offsets := make([]int, 0, len(s)+1)
+	for i := range s {
+		offsets = append(offsets, i)
+	}
+
+	return append(offsets, len(s))
+}
+
+func atWordStart(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+func isWordRune(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isMarkup(c byte) bool {
+	return strings.IndexByte("\\`*_~[]()#@>-", c) >= 0
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	opts := Options{
+		MentionURL: func(username string) string {
+			if username == "alice" {
+				return "/users/1"
+			}
+			return ""
+		},
+		HashtagURL: func(tag string) string {
+			return "/tags/" + tag
+		},
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "inline markup",
+			src:  "**bold** *em* _em_ ~~gone~~ `a<b`",
+			want: "<p><strong>bold</strong> <em>em</em> <em>em</em> <del>gone</del> <code>a&lt;b</code></p>\n",
+		},
+		{
+			name: "html is escaped",
+			src:  `<script>alert("x")</script> <b onclick=x>`,
+			want: "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &lt;b onclick=x&gt;</p>\n",
+		},
+		{
+			name: "links",
+			src:  "[site](https://example.com/a?b=1&c=2) see https://go.dev.",
+			want: `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">site</a> see <a href="https://go.dev" rel="nofollow noopener noreferrer">https://go.dev</a>.</p>` + "\n",
+		},
+		{
+			name: "unsafe links are left as text",
+			src:  `[click](javascript:void) [x](data:text/html,hi)`,
+			want: "<p>click x</p>\n",
+		},
+		{
+			name: "mentions and hashtags",
+			src:  "hi @alice and @bob #GoLang snake_case_word",
+			want: `<p>hi <a href="/users/1" rel="nofollow noopener noreferrer">@alice</a> and @bob <a href="/tags/golang" rel="nofollow noopener noreferrer">#GoLang</a> snake_case_word</p>` + "\n",
+		},
+		{
+			name: "escapes",
+			src:  `\*not em\* \#notatag`,
+			want: "<p>*not em* #notatag</p>\n",
+		},
+		{
+			name: "blocks",
+			src:  "first\nline\n\n> quoted\n\n- one\n- two\n\n1. first\n\n```\n<code> **as is**\n```",
+			want: "<p>first<br>\nline</p>\n" +
+				"<blockquote>\n<p>quoted</p>\n</blockquote>\n" +
+				"<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n" +
+				"<ol>\n<li>first</li>\n</ol>\n" +
+				"<pre><code>&lt;code&gt; **as is**</code></pre>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src, opts); got != tt.want {
+				t.Errorf("Render(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	got := RenderText("**not bold** <i>\n\nhttps://example.com #go", Options{
+		HashtagURL: func(tag string) string { return "/tags/" + tag },
+	})
+	want := `<p>**not bold** &lt;i&gt;</p>` + "\n" +
+		`<p><a href="https://example.com" rel="nofollow noopener noreferrer">https://example.com</a> <a href="/tags/go" rel="nofollow noopener noreferrer">#go</a></p>` + "\n"
+
+	if got != want {
+		t.Errorf("RenderText() =\n%q\nwant\n%q", got, want)
+	}
+}
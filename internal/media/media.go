@@ -0,0 +1,153 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"time"
+)
+
+const (
+	KindImage = "image"
+	KindVideo = "video"
+)
+
+const (
+	MaxImageSize = 10 << 20
+	MaxVideoSize = 40 << 20
+	// MaxVideoDuration keeps videos short, longer clips belong elsewhere
+	MaxVideoDuration = 60 * time.Second
+	// maxPixels guards against small files that decode into huge images
+	maxPixels = 40_000_000
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("media file is too large")
+	ErrTooLong         = errors.New("video is too long")
+	ErrInvalid         = errors.New("media file is corrupt")
+)
+
+// File is an upload ready to be stored. Data has had its metadata stripped.
+type File struct {
+	Kind        string
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Blurhash    string
+	Data        []byte
+}
+
+// Process validates an upload and strips location metadata from it. Images
+// are decoded and re-encoded, which drops EXIF entirely, videos have their
+// metadata boxes blanked.
+func Process(data []byte) (*File, error) {
+	contentType := http.DetectContentType(data)
+
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return processImage(data, contentType)
+	case "video/mp4":
+		return processVideo(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+}
+
+func processImage(data []byte, contentType string) (*File, error) {
+	if len(data) > MaxImageSize {
+		return nil, ErrTooLarge
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	file := &File{
+		Kind:        KindImage,
+		ContentType: contentType,
+	}
+
+	var img image.Image
+	var buf bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalid
+		}
+
+		img = applyOrientation(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+		file.Extension = "jpg"
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalid
+		}
+
+		err = png.Encode(&buf, img)
+		file.Extension = "png"
+	case "image/gif":
+		// Keep every frame of animated gifs
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(g.Image) == 0 {
+			return nil, ErrInvalid
+		}
+
+		img = g.Image[0]
+		err = gif.EncodeAll(&buf, g)
+		file.Extension = "gif"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	file.Width = bounds.Dx()
+	file.Height = bounds.Dy()
+	file.Blurhash = Blurhash(img, 4, 3)
+	file.Data = buf.Bytes()
+
+	return file, nil
+}
+
+func processVideo(data []byte) (*File, error) {
+	if len(data) > MaxVideoSize {
+		return nil, ErrTooLarge
+	}
+
+	// sanitiseMP4 edits in place, work on a copy of the caller's bytes
+	data = bytes.Clone(data)
+
+	info, err := sanitiseMP4(data)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	if info.duration > MaxVideoDuration {
+		return nil, ErrTooLong
+	}
+
+	return &File{
+		Kind:        KindVideo,
+		ContentType: "video/mp4",
+		Extension:   "mp4",
+		Width:       info.width,
+		Height:      info.height,
+		Data:        data,
+	}, nil
+}
@@ -0,0 +1,242 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+// gpsMarker stands in for the coordinates a phone writes into a file, none
+// of it may survive processing.
+const gpsMarker = "+48.8584+002.2945/"
+
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 128, 255})
+		}
+	}
+	return img
+}
+
+// exifTIFF builds a big endian TIFF block with an orientation and a GPS IFD
+// holding the latitude as text.
+func exifTIFF(orientation uint16) []byte {
+	be := binary.BigEndian
+	tiff := []byte("MM\x00\x2a")
+	tiff = be.AppendUint32(tiff, 8)
+
+	// IFD0: orientation and a pointer to the GPS IFD right after it
+	gpsIFD := uint32(8 + 2 + 2*12 + 4)
+	tiff = be.AppendUint16(tiff, 2)
+	tiff = be.AppendUint16(tiff, exifOrientationTag)
+	tiff = be.AppendUint16(tiff, 3) // SHORT
+	tiff = be.AppendUint32(tiff, 1)
+	tiff = be.AppendUint16(tiff, orientation)
+	tiff = be.AppendUint16(tiff, 0)
+	tiff = be.AppendUint16(tiff, 0x8825) // GPSInfo
+	tiff = be.AppendUint16(tiff, 4)      // LONG
+	tiff = be.AppendUint32(tiff, 1)
+	tiff = be.AppendUint32(tiff, gpsIFD)
+	tiff = be.AppendUint32(tiff, 0)
+
+	// GPS IFD: GPSLatitude as an ASCII value stored after the IFD
+	value := gpsIFD + 2 + 12 + 4
+	tiff = be.AppendUint16(tiff, 1)
+	tiff = be.AppendUint16(tiff, 0x0002)
+	tiff = be.AppendUint16(tiff, 2) // ASCII
+	tiff = be.AppendUint32(tiff, uint32(len(gpsMarker)+1))
+	tiff = be.AppendUint32(tiff, value)
+	tiff = be.AppendUint32(tiff, 0)
+
+	return append(append(tiff, gpsMarker...), 0)
+}
+
+func jpegWithEXIF(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	segment := append([]byte("Exif\x00\x00"), exifTIFF(orientation)...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	// The APP1 segment goes right after the start of image marker
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func pngWithEXIF(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(w, h)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Signature and IHDR, the metadata chunks follow them
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", exifTIFF(1))...)
+	out = append(out, pngChunk("tEXt", []byte("GPSLatitude\x00"+gpsMarker))...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func mp4Box(typ string, children ...[]byte) []byte {
+	body := bytes.Join(children, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	box = append(box, typ...)
+	return append(box, body...)
+}
+
+func mvhd(timescale, duration uint32) []byte {
+	body := make([]byte, 100)
+	binary.BigEndian.PutUint32(body[12:], timescale)
+	binary.BigEndian.PutUint32(body[16:], duration)
+	return mp4Box("mvhd", body)
+}
+
+func tkhd(w, h uint32) []byte {
+	body := make([]byte, 84)
+	binary.BigEndian.PutUint32(body[76:], w<<16)
+	binary.BigEndian.PutUint32(body[80:], h<<16)
+	return mp4Box("tkhd", body)
+}
+
+func location() []byte {
+	return mp4Box("udta", mp4Box("\xa9xyz", []byte(gpsMarker)))
+}
+
+func testMP4(seconds uint32) []byte {
+	return bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("mp42\x00\x00\x00\x00mp42isom")),
+		mp4Box("moov",
+			mvhd(1000, seconds*1000),
+			mp4Box("trak", tkhd(0, 0)), // audio
+			mp4Box("trak", tkhd(640, 360), location()),
+			location(),
+			mp4Box("meta", []byte("\x00\x00\x00\x00"), mp4Box("\xa9xyz", []byte(gpsMarker))),
+		),
+		mp4Box("mdat", []byte("frames")),
+	}, nil)
+}
+
+func TestProcessImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		w, h        int
+	}{
+		{"jpeg", jpegWithEXIF(t, 8, 4, 1), "image/jpeg", 8, 4},
+		{"rotated jpeg", jpegWithEXIF(t, 8, 4, 6), "image/jpeg", 4, 8},
+		{"png", pngWithEXIF(t, 8, 4), "image/png", 8, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Contains(tt.data, []byte(gpsMarker)) {
+				t.Fatal("fixture has no location")
+			}
+
+			f, err := Process(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if f.Kind != KindImage || f.ContentType != tt.contentType {
+				t.Errorf("got %s %s", f.Kind, f.ContentType)
+			}
+
+			if f.Width != tt.w || f.Height != tt.h {
+				t.Errorf("got %dx%d, want %dx%d", f.Width, f.Height, tt.w, tt.h)
+			}
+
+			for _, metadata := range []string{gpsMarker, "Exif", "eXIf", "tEXt"} {
+				if bytes.Contains(f.Data, []byte(metadata)) {
+					t.Errorf("%q survived processing", metadata)
+				}
+			}
+
+			if f.Blurhash == "" {
+				t.Error("expected a blurhash")
+			}
+		})
+	}
+}
+
+func TestProcessVideo(t *testing.T) {
+	data := testMP4(10)
+	original := bytes.Clone(data)
+
+	f, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, original) {
+		t.Error("the upload was modified in place")
+	}
+
+	if f.Kind != KindVideo || f.Width != 640 || f.Height != 360 {
+		t.Errorf("got %s %dx%d", f.Kind, f.Width, f.Height)
+	}
+
+	if len(f.Data) != len(data) {
+		t.Errorf("got %d bytes, want %d", len(f.Data), len(data))
+	}
+
+	for _, metadata := range []string{gpsMarker, "\xa9xyz", "udta", "meta"} {
+		if bytes.Contains(f.Data, []byte(metadata)) {
+			t.Errorf("%q survived processing", metadata)
+		}
+	}
+
+	// The file must still parse, with the metadata boxes turned into free ones
+	if _, err := sanitiseMP4(f.Data); err != nil {
+		t.Errorf("processed file is malformed: %v", err)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"text", []byte("hello, world"), ErrUnsupportedType},
+		{"long video", testMP4(uint32(MaxVideoDuration/time.Second) + 1), ErrTooLong},
+		{"truncated video", testMP4(10)[:60], ErrInvalid},
+		{"truncated jpeg", jpegWithEXIF(t, 8, 4, 1)[:200], ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data); !errors.Is(err, tt.err) {
+				t.Errorf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var errMalformedMP4 = errors.New("malformed mp4 file")
+
+type mp4Info struct {
+	width    int
+	height   int
+	duration time.Duration
+}
+
+// sanitiseMP4 reads the dimensions and duration of an MP4 and blanks its
+// user metadata in place. The udta and meta boxes, where cameras and phones
+// put the recording location, are zeroed and renamed to free boxes so no
+// offsets in the file have to change.
+func sanitiseMP4(data []byte) (*mp4Info, error) {
+	info := &mp4Info{}
+
+	foundMoov := false
+	err := walkBoxes(data, func(typ string, box []byte, body []byte) error {
+		if typ != "moov" {
+			return nil
+		}
+
+		foundMoov = true
+		return sanitiseMoov(body, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !foundMoov {
+		return nil, errMalformedMP4
+	}
+
+	return info, nil
+}
+
+func sanitiseMoov(moov []byte, info *mp4Info) error {
+	return walkBoxes(moov, func(typ string, box []byte, body []byte) error {
+		switch typ {
+		case "mvhd":
+			info.duration = mvhdDuration(body)
+		case "trak":
+			return sanitiseTrak(body, info)
+		case "udta", "meta":
+			blankBox(box, body)
+		}
+
+		return nil
+	})
+}
+
+func sanitiseTrak(trak []byte, info *mp4Info) error {
+	return walkBoxes(trak, func(typ string, box []byte, body []byte) error {
+		switch typ {
+		case "tkhd":
+			// Audio tracks have no dimensions, keep the first video track's
+			if w, h := tkhdDimensions(body); info.width == 0 && w > 0 && h > 0 {
+				info.width, info.height = w, h
+			}
+		case "udta", "meta":
+			blankBox(box, body)
+		}
+
+		return nil
+	})
+}
+
+// blankBox turns a box into a free box and zeroes its contents, renaming it
+// alone would leave the metadata readable in the file.
+func blankBox(box, body []byte) {
+	copy(box[4:8], "free")
+	clear(body)
+}
+
+// walkBoxes calls fn for every box directly inside data with the whole box
+// and its body.
+func walkBoxes(data []byte, fn func(typ string, box []byte, body []byte) error) error {
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return errMalformedMP4
+		}
+
+		size := uint64(binary.BigEndian.Uint32(data))
+		typ := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			// The box extends to the end of the file
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return errMalformedMP4
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			header = 16
+		}
+
+		if size < header || size > uint64(len(data)) {
+			return errMalformedMP4
+		}
+
+		if err := fn(typ, data[:size], data[header:size]); err != nil {
+			return err
+		}
+
+		data = data[size:]
+	}
+
+	return nil
+}
+
+func mvhdDuration(body []byte) time.Duration {
+	var timescale, duration uint64
+
+	switch {
+	case len(body) >= 20 && body[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(body[12:]))
+		duration = uint64(binary.BigEndian.Uint32(body[16:]))
+	case len(body) >= 32 && body[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(body[20:]))
+		duration = binary.BigEndian.Uint64(body[24:])
+	}
+
+	if timescale == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+func tkhdDimensions(body []byte) (int, int) {
+	// Width and height are 16.16 fixed point numbers closing the box, after
+	// a header whose size depends on the version
+	offset := 76
+	if len(body) > 0 && body[0] == 1 {
+		offset = 88
+	}
+
+	if len(body) < offset+8 {
+		return 0, 0
+	}
+
+	w := int(binary.BigEndian.Uint32(body[offset:]) >> 16)
+	h := int(binary.BigEndian.Uint32(body[offset+4:]) >> 16)
+
+	return w, h
+}
@@ -0,0 +1,205 @@
+// Package mute decides what a user's muted words hide from them. All of a
+// user's keywords and regexes are compiled into a single regular expression,
+// so checking a post costs one scan of its text however many words are
+// muted, and Go's regular expressions run in linear time whatever users
+// write.
+package mute
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/qwerqy/social-api-go/internal/entities"
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+var ErrEmptyPattern = errors.New("pattern is empty")
+
+// wordChar is what keywords must not be next to, so "cat" doesn't mute
+// "concatenate". It goes beyond \b, which only knows ASCII. sqlWordChar is
+// the same for PostgreSQL.
+const (
+	wordChar    = `\p{L}\p{N}_`
+	sqlWordChar = `[:alnum:]_`
+)
+
+// Normalize returns the pattern a muted word of kind is stored with:
+// keywords in lower case with single spaces, hashtags without the # and
+// regexes as written, once they are known to compile.
+func Normalize(kind, pattern string) (string, error) {
+	switch kind {
+	case store.MuteKindKeyword:
+		pattern = strings.ToLower(strings.Join(strings.Fields(pattern), " "))
+	case store.MuteKindHashtag:
+		pattern = entities.NormalizeTag(pattern)
+	case store.MuteKindRegex:
+		if strings.TrimSpace(pattern) == "" {
+			return "", ErrEmptyPattern
+		}
+
+		if _, err := regexp.Compile(pattern); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("kind must be keyword, regex or hashtag")
+	}
+
+	if pattern == "" {
+		return "", ErrEmptyPattern
+	}
+
+	return pattern, nil
+}
+
+// Filter hides what matches a user's muted words in one scope. A nil Filter
+// hides nothing.
+type Filter struct {
+	userID int64
+	words  *regexp.Regexp
+	tags   map[string]bool
+	// keywords and regexes are kept apart for Query
+	keywords []string
+	regexes  []string
+}
+
+// New builds the filter of the words userID muted in scope. Words muted in
+// other scopes are ignored.
+func New(userID int64, words []*store.MutedWord, scope string) (*Filter, error) {
+	f := &Filter{userID: userID, tags: make(map[string]bool)}
+
+	for _, w := range words {
+		if !slices.Contains(w.Scopes, scope) {
+			continue
+		}
+
+		switch w.Kind {
+		case store.MuteKindKeyword:
+			// Phrases match whatever whitespace separates their words
+			parts := strings.Fields(w.Pattern)
+			for i, p := range parts {
+				parts[i] = regexp.QuoteMeta(p)
+			}
+
+			f.keywords = append(f.keywords, strings.Join(parts, `\s+`))
+		case store.MuteKindRegex:
+			f.regexes = append(f.regexes, "(?:"+w.Pattern+")")
+		case store.MuteKindHashtag:
+			f.tags[w.Pattern] = true
+		}
+	}
+
+	patterns := f.regexes
+	if len(f.keywords) > 0 {
+		patterns = append(slices.Clip(patterns), "(?i)"+keywordPattern(f.keywords, wordChar))
+	}
+
+	if len(patterns) == 0 && len(f.tags) == 0 {
+		return nil, nil
+	}
+
+	if len(patterns) > 0 {
+		re, err := regexp.Compile(strings.Join(patterns, "|"))
+		if err != nil {
+			return nil, err
+		}
+
+		f.words = re
+	}
+
+	return f, nil
+}
+
+// keywordPattern matches any of the quoted keywords when they aren't next
+// to a character of wordChar.
+func keywordPattern(keywords []string, wordChar string) string {
+	return `(?:^|[^` + wordChar + `])(?:` + strings.Join(keywords, "|") + `)(?:[^` + wordChar + `]|$)`
+}
+
+// Query splits the filter for a feed query. The keywords and hashtags are
+// left out by the query itself, so its pages come out full, and the
+// returned filter only has the regexes left to check: their syntax is Go's,
+// which PostgreSQL doesn't share.
+func (f *Filter) Query() (store.MuteQuery, *Filter) {
+	if f == nil {
+		return store.MuteQuery{}, nil
+	}
+
+	var mq store.MuteQuery
+	if len(f.keywords) > 0 {
+		// QuoteMeta's escapes and \s mean the same to PostgreSQL, which
+		// matches the pattern without regard to case
+		mq.Words = keywordPattern(f.keywords, sqlWordChar)
+	}
+
+	for tag := range f.tags {
+		mq.Tags = append(mq.Tags, tag)
+	}
+	slices.Sort(mq.Tags)
+
+	if len(f.regexes) == 0 {
+		return mq, nil
+	}
+
+	// The regexes compiled once already as part of words
+	return mq, &Filter{
+		userID:  f.userID,
+		words:   regexp.MustCompile(strings.Join(f.regexes, "|")),
+		regexes: f.regexes,
+	}
+}
+
+// Matches reports whether text, or any of tags, is muted. The hashtags in
+// text count as tags.
+func (f *Filter) Matches(text string, tags []string) bool {
+	if f == nil {
+		return false
+	}
+
+	if f.words != nil && f.words.MatchString(text) {
+		return true
+	}
+
+	if len(f.tags) == 0 {
+		return false
+	}
+
+	for _, t := range tags {
+		if f.tags[t] {
+			return true
+		}
+	}
+
+	for _, h := range entities.Hashtags(text) {
+		if f.tags[h.Tag] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HidesPost reports whether the post, or the post it shares, is muted.
+// Users never mute their own posts.
+func (f *Filter) HidesPost(p *store.Post) bool {
+	if f == nil || p.UserID == f.userID {
+		return false
+	}
+
+	if f.Matches(p.Title+"\n"+p.ContentWarning+"\n"+p.Content, p.Tags) {
+		return true
+	}
+
+	return p.Original != nil && f.HidesPost(p.Original)
+}
+
+// HidesComment reports whether the comment is muted. Users never mute their
+// own comments.
+func (f *Filter) HidesComment(c *store.Comment) bool {
+	if f == nil || c.Deleted || c.UserID == f.userID {
+		return false
+	}
+
+	return f.Matches(c.Content, nil)
+}
@@ -0,0 +1,183 @@
+package mute
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func word(kind, pattern string, scopes ...string) *store.MutedWord {
+	if len(scopes) == 0 {
+		scopes = []string{store.MuteScopeFeed}
+	}
+
+	return &store.MutedWord{Kind: kind, Pattern: pattern, Scopes: scopes}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		kind, pattern string
+		want          string
+		wantErr       bool
+	}{
+		{kind: store.MuteKindKeyword, pattern: "  Game   of Thrones ", want: "game of thrones"},
+		{kind: store.MuteKindHashtag, pattern: "#Spoilers", want: "spoilers"},
+		{kind: store.MuteKindRegex, pattern: `s\d+e\d+`, want: `s\d+e\d+`},
+		{kind: store.MuteKindRegex, pattern: `(unclosed`, wantErr: true},
+		{kind: store.MuteKindKeyword, pattern: "   ", wantErr: true},
+		{kind: store.MuteKindHashtag, pattern: "#", wantErr: true},
+		{kind: "emoji", pattern: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.kind, tt.pattern)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, %v", tt.kind, tt.pattern, got, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	f, err := New(1, []*store.MutedWord{
+		word(store.MuteKindKeyword, "cat"),
+		word(store.MuteKindKeyword, "red wedding"),
+		word(store.MuteKindKeyword, "c++"),
+		word(store.MuteKindKeyword, "été"),
+		word(store.MuteKindRegex, `(?i)s\d+e\d+`),
+		word(store.MuteKindHashtag, "spoilers"),
+		word(store.MuteKindKeyword, "dog", store.MuteScopeComments),
+	}, store.MuteScopeFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		tags []string
+		want bool
+	}{
+		{text: "my Cat is asleep", want: true},
+		{text: "cat", want: true},
+		{text: "concatenate strings", want: false},
+		{text: "the RED\n  wedding episode", want: true},
+		{text: "learning C++ today", want: true},
+		{text: "un été chaud", want: true},
+		{text: "les étés", want: false},
+		{text: "just watched S03E09", want: true},
+		{text: "no #spoilers please", want: true},
+		{text: "tagged", tags: []string{"spoilers"}, want: true},
+		{text: "my dog", want: false},
+		{text: "nothing to see", tags: []string{"go"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := f.Matches(tt.text, tt.tags); got != tt.want {
+			t.Errorf("Matches(%q, %v) = %v, want %v", tt.text, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestNoWords(t *testing.T) {
+	f, err := New(1, []*store.MutedWord{word(store.MuteKindKeyword, "cat", store.MuteScopeComments)}, store.MuteScopeFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f != nil {
+		t.Fatal("words of other scopes shouldn't make a filter")
+	}
+
+	if f.Matches("cat", nil) || f.HidesPost(&store.Post{Content: "cat"}) || f.HidesComment(&store.Comment{Content: "cat"}) {
+		t.Error("a nil filter hid something")
+	}
+}
+
+func TestHides(t *testing.T) {
+	f, err := New(1, []*store.MutedWord{
+		word(store.MuteKindKeyword, "finale", store.MuteScopeFeed, store.MuteScopeComments),
+	}, store.MuteScopeFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		post *store.Post
+		want bool
+	}{
+		{name: "content", post: &store.Post{UserID: 2, Content: "the finale!"}, want: true},
+		{name: "title", post: &store.Post{UserID: 2, Title: "Finale thoughts"}, want: true},
+		{name: "content warning", post: &store.Post{UserID: 2, ContentWarning: "finale", Content: "wow"}, want: true},
+		{name: "own post", post: &store.Post{UserID: 1, Content: "the finale!"}, want: false},
+		{
+			name: "repost of a muted post",
+			post: &store.Post{UserID: 2, Kind: store.PostKindRepost, Original: &store.Post{UserID: 3, Content: "finale"}},
+			want: true,
+		},
+		{
+			name: "quote of own muted post",
+			post: &store.Post{UserID: 2, Kind: store.PostKindQuote, Content: "nice", Original: &store.Post{UserID: 1, Content: "finale"}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.HidesPost(tt.post); got != tt.want {
+				t.Errorf("HidesPost = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if f.HidesComment(&store.Comment{UserID: 2, Deleted: true}) {
+		t.Error("tombstones have nothing to hide")
+	}
+
+	if !f.HidesComment(&store.Comment{UserID: 2, Content: "finale"}) {
+		t.Error("comment wasn't hidden")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	f, err := New(1, []*store.MutedWord{
+		word(store.MuteKindKeyword, "red wedding"),
+		word(store.MuteKindKeyword, "c++"),
+		word(store.MuteKindHashtag, "spoilers"),
+		word(store.MuteKindHashtag, "finale"),
+		word(store.MuteKindRegex, `s\d+e\d+`),
+	}, store.MuteScopeFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mq, rest := f.Query()
+
+	if want := `(?:^|[^[:alnum:]_])(?:red\s+wedding|c\+\+)(?:[^[:alnum:]_]|$)`; mq.Words != want {
+		t.Errorf("Words = %q, want %q", mq.Words, want)
+	}
+
+	// The pattern means the same to Go, as far as ASCII goes
+	re := regexp.MustCompile("(?i)" + mq.Words)
+	if !re.MatchString("the Red  Wedding!") || !re.MatchString("c++") || re.MatchString("abc++") {
+		t.Errorf("Words = %q matches the wrong text", mq.Words)
+	}
+
+	if want := []string{"finale", "spoilers"}; !slices.Equal(mq.Tags, want) {
+		t.Errorf("Tags = %v, want %v", mq.Tags, want)
+	}
+
+	if rest.Matches("red wedding", []string{"spoilers"}) {
+		t.Error("the rest of the filter checks what the query does")
+	}
+
+	if !rest.Matches("s01e02", nil) {
+		t.Error("the rest of the filter lost the regexes")
+	}
+
+	mq, rest = (&Filter{userID: 1, tags: map[string]bool{"x": true}}).Query()
+	if mq.Words != "" || rest != nil {
+		t.Errorf("Query() = %+v, %v without keywords nor regexes", mq, rest)
+	}
+}
@@ -0,0 +1,141 @@
+// Package ranking scores the posts that could be shown in a user's ranked
+// feed. It only does arithmetic on what is known about each candidate, so
+// the weights can be tuned and tested without a database.
+package ranking
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// Candidate is a post that could be shown, with what is known about it.
+type Candidate struct {
+	PostID    int64
+	AuthorID  int64
+	CreatedAt time.Time
+	Comments  int
+	Reactions int
+	Reposts   int
+	// Interactions is how many times the viewer reacted to, commented on or
+	// shared the author's posts lately
+	Interactions int
+	// Followed is false for posts of users followed by the ones the viewer
+	// follows
+	Followed bool
+}
+
+type Weights struct {
+	// HalfLife is how long it takes a post to lose half its score to age
+	HalfLife time.Duration
+	Comment  float64
+	Reaction float64
+	Repost   float64
+	// Affinity scales how much past interactions with the author count
+	Affinity float64
+	// FriendOfFriend multiplies the score of posts from users the viewer
+	// doesn't follow
+	FriendOfFriend float64
+	// Diversity multiplies the score of a post once for every post of the
+	// same author ranked above it
+	Diversity float64
+}
+
+var DefaultWeights = Weights{
+	HalfLife:       time.Hour * 6,
+	Comment:        2,
+	Reaction:       1,
+	Repost:         3,
+	Affinity:       0.5,
+	FriendOfFriend: 0.5,
+	Diversity:      0.6,
+}
+
+// Explanation breaks a score down into the factors it is the product of.
+type Explanation struct {
+	Recency    float64 `json:"recency"`
+	Engagement float64 `json:"engagement"`
+	Affinity   float64 `json:"affinity"`
+	Source     float64 `json:"source"`
+	Diversity  float64 `json:"diversity"`
+	Score      float64 `json:"score"`
+}
+
+type Ranked struct {
+	Candidate
+	Explanation Explanation
+}
+
+// Score returns the score of a candidate before diversity is taken into
+// account.
+func Score(now time.Time, c Candidate, w Weights) Explanation {
+	age := max(now.Sub(c.CreatedAt), 0)
+
+	e := Explanation{
+		Recency:    math.Pow(0.5, float64(age)/float64(w.HalfLife)),
+		Engagement: 1 + math.Log1p(w.Comment*float64(c.Comments)+w.Reaction*float64(c.Reactions)+w.Repost*float64(c.Reposts)),
+		Affinity:   1 + w.Affinity*math.Log1p(float64(c.Interactions)),
+		Source:     1,
+		Diversity:  1,
+	}
+
+	if !c.Followed {
+		e.Source = w.FriendOfFriend
+	}
+
+	e.Score = e.Recency * e.Engagement * e.Affinity * e.Source
+
+	return e
+}
+
+// Rank orders the candidates by score, highest first. Each author's posts
+// after their first lose some of their score, so a few prolific authors
+// can't fill the feed.
+func Rank(now time.Time, candidates []Candidate, w Weights) []Ranked {
+	pending := make([]Ranked, len(candidates))
+	for i, c := range candidates {
+		pending[i] = Ranked{Candidate: c, Explanation: Score(now, c, w)}
+	}
+
+	byScore := func(a, b Ranked) int {
+		if c := cmp.Compare(b.Explanation.Score, a.Explanation.Score); c != 0 {
+			return c
+		}
+
+		// Newer posts first on ties so the order is stable
+		return cmp.Compare(b.PostID, a.PostID)
+	}
+
+	slices.SortFunc(pending, byScore)
+
+	ranked := make([]Ranked, 0, len(pending))
+	shown := make(map[int64]int)
+
+	// Picking a post lowers the scores of its author's other posts, so the
+	// rest is sorted again before picking the next one
+	for len(pending) > 0 {
+		next := pending[0]
+		pending = pending[1:]
+		ranked = append(ranked, next)
+		shown[next.AuthorID]++
+
+		moved := false
+		for i := range pending {
+			if pending[i].AuthorID != next.AuthorID {
+				continue
+			}
+
+			e := &pending[i].Explanation
+			e.Diversity = math.Pow(w.Diversity, float64(shown[next.AuthorID]))
+			e.Score = e.Recency * e.Engagement * e.Affinity * e.Source * e.Diversity
+			moved = true
+		}
+
+		if moved {
+			slices.SortStableFunc(pending, byScore)
+		}
+	}
+
+	return ranked
+}
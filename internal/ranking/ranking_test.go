@@ -0,0 +1,107 @@
+package ranking
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestScore(t *testing.T) {
+	base := Candidate{PostID: 1, AuthorID: 1, CreatedAt: now, Followed: true}
+
+	tests := []struct {
+		name   string
+		change func(*Candidate)
+		want   func(base, got Explanation) bool
+	}{
+		{
+			name:   "a post loses half its score every half-life",
+			change: func(c *Candidate) { c.CreatedAt = now.Add(-DefaultWeights.HalfLife) },
+			want:   func(base, got Explanation) bool { return near(got.Score, base.Score/2) },
+		},
+		{
+			name:   "posts from the future aren't boosted",
+			change: func(c *Candidate) { c.CreatedAt = now.Add(time.Hour) },
+			want:   func(base, got Explanation) bool { return got.Recency == 1 },
+		},
+		{
+			name:   "engagement raises the score",
+			change: func(c *Candidate) { c.Comments, c.Reactions, c.Reposts = 3, 10, 1 },
+			want:   func(base, got Explanation) bool { return got.Score > base.Score },
+		},
+		{
+			name:   "comments count more than reactions",
+			change: func(c *Candidate) { c.Comments = 5 },
+			want: func(base, got Explanation) bool {
+				reactions := Score(now, Candidate{CreatedAt: now, Reactions: 5, Followed: true}, DefaultWeights)
+				return got.Score > reactions.Score
+			},
+		},
+		{
+			name:   "past interactions with the author raise the score",
+			change: func(c *Candidate) { c.Interactions = 4 },
+			want:   func(base, got Explanation) bool { return got.Affinity > 1 && got.Score > base.Score },
+		},
+		{
+			name:   "friends of friends rank lower",
+			change: func(c *Candidate) { c.Followed = false },
+			want:   func(base, got Explanation) bool { return near(got.Score, base.Score*DefaultWeights.FriendOfFriend) },
+		},
+	}
+
+	b := Score(now, base, DefaultWeights)
+	if b.Score != 1 {
+		t.Fatalf("a new post without engagement scored %v, want 1", b.Score)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base
+			tt.change(&c)
+
+			if got := Score(now, c, DefaultWeights); !tt.want(b, got) {
+				t.Errorf("got %+v, base %+v", got, b)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	candidates := []Candidate{
+		{PostID: 1, AuthorID: 1, CreatedAt: now.Add(-time.Hour * 12), Followed: true},
+		{PostID: 2, AuthorID: 2, CreatedAt: now, Followed: true},
+		{PostID: 3, AuthorID: 2, CreatedAt: now, Followed: true},
+		{PostID: 4, AuthorID: 2, CreatedAt: now, Followed: true},
+		{PostID: 5, AuthorID: 3, CreatedAt: now.Add(-time.Minute), Followed: true},
+	}
+
+	ranked := Rank(now, candidates, DefaultWeights)
+
+	var got []int64
+	for _, r := range ranked {
+		got = append(got, r.PostID)
+	}
+
+	// Author 2's posts are spread out rather than taking the top spots
+	if want := []int64{4, 5, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, r := range ranked {
+		e := r.Explanation
+		if !near(e.Score, e.Recency*e.Engagement*e.Affinity*e.Source*e.Diversity) {
+			t.Errorf("post %d: score %v doesn't match its explanation %+v", r.PostID, e.Score, e)
+		}
+	}
+
+	if d := ranked[4].Explanation.Diversity; d != 1 {
+		t.Errorf("only post of its author has diversity %v, want 1", d)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,191 @@
+// Package search reads the queries users type into the search box and
+// prepares the snippets shown with the results.
+//
+// A query is free text passed to Postgres' websearch_to_tsquery, so
+// "quoted phrases", OR and -excluded words work as they do on search
+// engines, mixed with operators that narrow the results down:
+//
+//	from:alice      posts or comments by alice
+//	tag:go, #go     posts tagged go
+//	since:2024-01-31, until:2024-02-29
+//	                written on or after, or on or before, a day (UTC)
+package search
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/entities"
+)
+
+// DateLayout is how the days of since: and until: are written.
+const DateLayout = "2006-01-02"
+
+var ErrEmptyQuery = errors.New("query has nothing to search for")
+
+type Query struct {
+	// Text is what's left of the query once the operators are taken out
+	Text  string
+	From  []string
+	Tags  []string
+	Since time.Time
+	// Until is the end of the day given to until:, exclusive
+	Until time.Time
+}
+
+// Parse splits q into its text and operators. Operators it doesn't know,
+// like "note:", are searched for as text.
+func Parse(q string) (Query, error) {
+	var query Query
+	var text []string
+
+	for _, token := range tokenize(q) {
+		// Phrases are kept whole, whatever they contain
+		if strings.HasPrefix(token, `"`) {
+			text = append(text, token)
+			continue
+		}
+
+		if strings.HasPrefix(token, "#") {
+			if tag := entities.NormalizeTag(token); tag != "" {
+				query.Tags = append(query.Tags, tag)
+			}
+			continue
+		}
+
+		name, value, ok := strings.Cut(token, ":")
+		if !ok || value == "" {
+			text = append(text, token)
+			continue
+		}
+
+		switch strings.ToLower(name) {
+		case "from":
+			query.From = append(query.From, strings.TrimPrefix(value, "@"))
+		case "tag":
+			if tag := entities.NormalizeTag(value); tag != "" {
+				query.Tags = append(query.Tags, tag)
+			}
+		case "since":
+			day, err := time.Parse(DateLayout, value)
+			if err != nil {
+				return query, fmt.Errorf("since must be a date like %s", DateLayout)
+			}
+
+			query.Since = day
+		case "until":
+			day, err := time.Parse(DateLayout, value)
+			if err != nil {
+				return query, fmt.Errorf("until must be a date like %s", DateLayout)
+			}
+
+			query.Until = day.AddDate(0, 0, 1)
+		default:
+			text = append(text, token)
+		}
+	}
+
+	if !query.Since.IsZero() && !query.Until.IsZero() && !query.Since.Before(query.Until) {
+		return query, errors.New("since must be before until")
+	}
+
+	query.Text = strings.Join(text, " ")
+	query.Tags = entities.NormalizeTags(query.Tags)
+
+	if query.Text == "" && len(query.From) == 0 && len(query.Tags) == 0 {
+		return query, ErrEmptyQuery
+	}
+
+	return query, nil
+}
+
+// tokenize splits q on whitespace, keeping quoted phrases, quotes included,
+// as single tokens. An unterminated quote runs to the end of q.
+func tokenize(q string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"' && !quoted:
+			flush()
+			quoted = true
+			current.WriteRune(r)
+		case r == '"' && quoted:
+			current.WriteRune(r)
+			quoted = false
+			flush()
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	flush()
+
+	return tokens
+}
+
+// The matches in a snippet from ts_headline are marked with characters from
+// Unicode's private use area rather than HTML, so the text around them can
+// be escaped safely afterwards.
+const (
+	StartSel = "\ue000"
+	StopSel  = "\ue001"
+)
+
+// HeadlineOptions are the ts_headline options producing the snippets
+// Highlight expects.
+const HeadlineOptions = `StartSel="` + StartSel + `", StopSel="` + StopSel +
+	`", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+
+// Highlight turns a snippet from ts_headline into HTML, with its matches in
+// <mark> elements. Unbalanced marks, from users typing the private use
+// characters themselves, are dropped or closed.
+func Highlight(snippet string) string {
+	var b strings.Builder
+	open := false
+
+	for {
+		i := strings.IndexAny(snippet, StartSel+StopSel)
+		if i < 0 {
+			break
+		}
+
+		b.WriteString(html.EscapeString(snippet[:i]))
+
+		if strings.HasPrefix(snippet[i:], StartSel) {
+			if !open {
+				b.WriteString("<mark>")
+				open = true
+			}
+			snippet = snippet[i+len(StartSel):]
+		} else {
+			if open {
+				b.WriteString("</mark>")
+				open = false
+			}
+			snippet = snippet[i+len(StopSel):]
+		}
+	}
+
+	b.WriteString(html.EscapeString(snippet))
+
+	if open {
+		b.WriteString("</mark>")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateLayout, s)
+		return d
+	}
+
+	tests := []struct {
+		name string
+		q    string
+		want Query
+	}{
+		{
+			name: "plain text",
+			q:    "  postgres   indexes ",
+			want: Query{Text: "postgres indexes"},
+		},
+		{
+			name: "phrases are kept whole",
+			q:    `"from:alice wrote this" -draft`,
+			want: Query{Text: `"from:alice wrote this" -draft`},
+		},
+		{
+			name: "unterminated phrases run to the end",
+			q:    `go "generics are`,
+			want: Query{Text: `go "generics are`},
+		},
+		{
+			name: "operators",
+			q:    "from:@alice from:bob tag:Go #Rust release",
+			want: Query{Text: "release", From: []string{"alice", "bob"}, Tags: []string{"go", "rust"}},
+		},
+		{
+			name: "until includes the whole day",
+			q:    "since:2024-01-01 until:2024-01-31 go",
+			want: Query{Text: "go", Since: day("2024-01-01"), Until: day("2024-02-01")},
+		},
+		{
+			name: "unknown operators are text",
+			q:    "note: todo:later",
+			want: Query{Text: "note: todo:later"},
+		},
+		{
+			name: "operators alone are a query",
+			q:    "tag:go",
+			want: Query{Tags: []string{"go"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.q)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned %v", tt.q, err)
+			}
+
+			if got.Text != tt.want.Text ||
+				!slices.Equal(got.From, tt.want.From) ||
+				!slices.Equal(got.Tags, tt.want.Tags) ||
+				!got.Since.Equal(tt.want.Since) ||
+				!got.Until.Equal(tt.want.Until) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{"", "   ", "since:yesterday go", "since:2024-02-01 until:2024-01-01 go", "#"} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) succeeded", q)
+		}
+	}
+
+	if _, err := Parse("since:2024-01-01"); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("a date range alone should be an empty query, got %v", err)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"no matches", "no matches"},
+		{"the " + StartSel + "quick" + StopSel + " fox", "the <mark>quick</mark> fox"},
+		{StartSel + "<b>" + StopSel + " & co", "<mark>&lt;b&gt;</mark> &amp; co"},
+		{"stray " + StopSel + "close", "stray close"},
+		{"left " + StartSel + "open", "left <mark>open</mark>"},
+		{StartSel + StartSel + "twice" + StopSel, "<mark>twice</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := Highlight(tt.snippet); got != tt.want {
+			t.Errorf("Highlight(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+// Block stops blockedID from seeing or interacting with userID's posts, and
+// the other way round. Any follow between the two users is removed.
+func (s *BlockStore) Block(ctx context.Context, userID, blockedID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO blocks (user_id, blocked_id) VALUES ($1, $2)`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, query, userID, blockedID); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+
+			return err
+		}
+
+		query = `
+			DELETE FROM followers
+			WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)
+		`
+
+		_, err := tx.ExecContext(ctx, query, userID, blockedID)
+		return err
+	})
+}
+
+func (s *BlockStore) Unblock(ctx context.Context, userID, blockedID int64) error {
+	query := `DELETE FROM blocks WHERE user_id = $1 AND blocked_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// IsBlocked reports whether either user blocked the other.
+func (s *BlockStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	query := `SELECT ` + blockedBetween("$1", "$2")
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var blocked bool
+	err := s.db.QueryRowContext(ctx, query, userID, otherID).Scan(&blocked)
+
+	return blocked, err
+}
+
+// blockedBetween returns the SQL condition that holds when either user
+// blocked the other.
+func blockedBetween(user, other string) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM blocks bl
+		WHERE (bl.user_id = %[1]s AND bl.blocked_id = %[2]s) OR
+			(bl.user_id = %[2]s AND bl.blocked_id = %[1]s)
+	)`, user, other)
+}
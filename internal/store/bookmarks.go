@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+type Bookmark struct {
+	ID           int64   `json:"id"`
+	UserID       int64   `json:"user_id"`
+	PostID       int64   `json:"post_id"`
+	CollectionID *int64  `json:"collection_id"`
+	Collection   *string `json:"collection"`
+	CreatedAt    string  `json:"created_at"`
+	Post         *Post   `json:"post,omitempty"`
+}
+
+type BookmarkCollection struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	CreatedAt      string `json:"created_at"`
+	BookmarksCount int    `json:"bookmarks_count"`
+}
+
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+// Save bookmarks a post for a user, moving it into the named collection when
+// one is given. Saving an already bookmarked post only updates its collection.
+func (s *BookmarkStore) Save(ctx context.Context, bookmark *Bookmark, collection string) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		bookmark.CollectionID = nil
+		bookmark.Collection = nil
+
+		if collection != "" {
+			id, err := s.upsertCollection(ctx, tx, bookmark.UserID, collection)
+			if err != nil {
+				return err
+			}
+
+			bookmark.CollectionID = &id
+			bookmark.Collection = &collection
+		}
+
+		query := `
+			INSERT INTO bookmarks (user_id, post_id, collection_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, post_id) DO UPDATE SET collection_id = EXCLUDED.collection_id
+			RETURNING id, created_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			bookmark.UserID,
+			bookmark.PostID,
+			bookmark.CollectionID,
+		).Scan(
+			&bookmark.ID,
+			&bookmark.CreatedAt,
+		)
+	})
+}
+
+func (s *BookmarkStore) Delete(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM bookmarks WHERE user_id = $1 AND post_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *BookmarkStore) Exists(ctx context.Context, userID, postID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM bookmarks WHERE user_id = $1 AND post_id = $2)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, userID, postID).Scan(&exists)
+	return exists, err
+}
+
+// GetByUserID returns the user's bookmarks, most recently saved first.
+func (s *BookmarkStore) GetByUserID(ctx context.Context, userID int64, bq BookmarkQuery) ([]*Bookmark, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", bq.Cursor)
+
+	query := `
+		SELECT b.id, b.user_id, b.post_id, b.collection_id, bc.name, b.created_at,
+			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, p.kind, p.visibility, u.username
+		FROM bookmarks b
+		JOIN posts p ON p.id = b.post_id
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN posts o ON o.id = p.original_id AND ` + visibleTo("o", "$1") + `
+		LEFT JOIN bookmark_collections bc ON bc.id = b.collection_id
+		WHERE
+			b.user_id = $1 AND
+			` + visibleTo("p", "$1") + ` AND
+			($2::timestamptz IS NULL OR (b.created_at, b.id) ` + cmp + ` ($2, $7::bigint)) AND
+			` + textMatch("$4", "p.search_vector", "o.search_vector") + ` AND
+			(p.tags @> $5 OR o.tags @> $5 OR $5 = '{}') AND
+			($6 = '' OR bc.name = $6)
+		ORDER BY b.created_at ` + order + `, b.id ` + order + `
+		LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		userID,
+		cursorAt,
+		bq.Limit,
+		bq.Search,
+		pq.Array(bq.Tags),
+		bq.Collection,
+		cursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []*Bookmark{}
+
+	for rows.Next() {
+		b := &Bookmark{Post: &Post{Bookmarked: true}}
+		err := rows.Scan(
+			&b.ID,
+			&b.UserID,
+			&b.PostID,
+			&b.CollectionID,
+			&b.Collection,
+			&b.CreatedAt,
+			&b.Post.ID,
+			&b.Post.UserID,
+			&b.Post.Title,
+			&b.Post.Content,
+			&b.Post.CreatedAt,
+			&b.Post.Version,
+			pq.Array(&b.Post.Tags),
+			&b.Post.Kind,
+			&b.Post.Visibility,
+			&b.Post.User.Username,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Post.User.ID = b.Post.UserID
+		bookmarks = append(bookmarks, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if bq.Cursor != nil && bq.Cursor.Backward {
+		slices.Reverse(bookmarks)
+	}
+
+	return bookmarks, nil
+}
+
+func (s *BookmarkStore) GetCollections(ctx context.Context, userID int64) ([]*BookmarkCollection, error) {
+	query := `
+		SELECT bc.id, bc.name, bc.created_at, COUNT(b.id)
+		FROM bookmark_collections bc
+		LEFT JOIN bookmarks b ON b.collection_id = bc.id
+		WHERE bc.user_id = $1
+		GROUP BY bc.id
+		ORDER BY bc.name
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*BookmarkCollection{}
+
+	for rows.Next() {
+		c := &BookmarkCollection{}
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt, &c.BookmarksCount); err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, c)
+	}
+
+	return collections, rows.Err()
+}
+
+func (s *BookmarkStore) DeleteCollection(ctx context.Context, userID, collectionID int64) error {
+	query := `DELETE FROM bookmark_collections WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, collectionID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *BookmarkStore) upsertCollection(ctx context.Context, tx *sql.Tx, userID int64, name string) (int64, error) {
+	query := `
+		INSERT INTO bookmark_collections (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var id int64
+	err := tx.QueryRowContext(ctx, query, userID, name).Scan(&id)
+	return id, err
+}
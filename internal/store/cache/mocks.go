@@ -9,7 +9,8 @@ import (
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:    &MockUserStore{},
+		Trending: NewMemoryTrendingStore(),
 	}
 }
 
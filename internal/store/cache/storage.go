@@ -13,10 +13,15 @@ type Storage struct {
 		Set(context.Context, *store.User) error
 		Delete(context.Context, int64)
 	}
+	Trending interface {
+		Get(ctx context.Context, window string) (*store.Trending, error)
+		Set(ctx context.Context, window string, trending *store.Trending) error
+	}
 }
 
 func NewRedisStorage(rdb *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb: rdb},
+		Users:    &UserStore{rdb: rdb},
+		Trending: &TrendingStore{rdb: rdb},
 	}
 }
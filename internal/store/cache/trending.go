@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// TrendingExpTime is how long a trending snapshot is served for when it
+// stops being refreshed.
+const TrendingExpTime = time.Hour
+
+type TrendingStore struct {
+	rdb *redis.Client
+}
+
+func (s *TrendingStore) Get(ctx context.Context, window string) (*store.Trending, error) {
+	cacheKey := fmt.Sprintf("trending-%v", window)
+
+	data, err := s.rdb.Get(ctx, cacheKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var trending store.Trending
+	if err := json.Unmarshal([]byte(data), &trending); err != nil {
+		return nil, err
+	}
+
+	return &trending, nil
+}
+
+func (s *TrendingStore) Set(ctx context.Context, window string, trending *store.Trending) error {
+	cacheKey := fmt.Sprintf("trending-%v", window)
+
+	json, err := json.Marshal(trending)
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.SetEx(ctx, cacheKey, json, TrendingExpTime).Err()
+}
+
+// MemoryTrendingStore keeps the trending snapshots in process, for when
+// Redis is disabled.
+type MemoryTrendingStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]*store.Trending
+}
+
+func NewMemoryTrendingStore() *MemoryTrendingStore {
+	return &MemoryTrendingStore{
+		snapshots: make(map[string]*store.Trending),
+	}
+}
+
+func (s *MemoryTrendingStore) Get(ctx context.Context, window string) (*store.Trending, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trending, ok := s.snapshots[window]
+	if !ok || time.Since(trending.ComputedAt) > TrendingExpTime {
+		return nil, nil
+	}
+
+	return trending, nil
+}
+
+func (s *MemoryTrendingStore) Set(ctx context.Context, window string, trending *store.Trending) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[window] = trending
+	return nil
+}
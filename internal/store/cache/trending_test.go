@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func TestMemoryTrendingStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryTrendingStore()
+
+	if trending, err := s.Get(ctx, "day"); err != nil || trending != nil {
+		t.Fatalf("got %v, %v for a window never set, want nil", trending, err)
+	}
+
+	day := &store.Trending{Tags: []store.TrendingTag{{Name: "go"}}, ComputedAt: time.Now()}
+	if err := s.Set(ctx, "day", day); err != nil {
+		t.Fatal(err)
+	}
+
+	if trending, err := s.Get(ctx, "day"); err != nil || trending != day {
+		t.Fatalf("got %v, %v, want the snapshot set", trending, err)
+	}
+
+	if trending, _ := s.Get(ctx, "week"); trending != nil {
+		t.Errorf("got %v for another window, want nil", trending)
+	}
+
+	stale := &store.Trending{ComputedAt: time.Now().Add(-TrendingExpTime - time.Minute)}
+	if err := s.Set(ctx, "hour", stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if trending, _ := s.Get(ctx, "hour"); trending != nil {
+		t.Errorf("got a snapshot computed %v ago, want it expired", time.Since(trending.ComputedAt))
+	}
+}
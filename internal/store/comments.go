@@ -3,15 +3,48 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/lib/pq"
 )
 
+// MaxCommentDepth is the deepest a reply can be nested, top-level comments
+// are at depth 0.
+const MaxCommentDepth = 5
+
+// maxReplySubtree caps how many replies are loaded for a single subtree.
+const maxReplySubtree = 200
+
+var ErrCommentTooDeep = errors.New("comment thread is too deep")
+
 type Comment struct {
 	ID int64 `json:"id"`
 	PostID int64 `json:"post_id"`
 	UserID int64 `json:"user_id"`
+	ParentID *int64 `json:"parent_id"`
+	Depth int `json:"depth"`
 	Content string `json:"content"`
 	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Version int64 `json:"version"`
+	Deleted bool `json:"deleted"`
+	// Muted comments match one of the viewer's muted words, their content
+	// is left out like a tombstone's
+	Muted bool `json:"muted,omitempty"`
 	User User `json:"user"`
+	ReplyCount int `json:"reply_count"`
+	Entities []Entity `json:"entities,omitempty"`
+	Replies []*Comment `json:"replies,omitempty"`
+}
+
+type CommentEdit struct {
+	ID int64 `json:"id"`
+	CommentID int64 `json:"comment_id"`
+	Version int64 `json:"version"`
+	Content string `json:"content"`
+	EditedBy int64 `json:"edited_by"`
+	CreatedAt string `json:"created_at"`
 }
 
 type CommentStore struct {
@@ -19,51 +52,267 @@ type CommentStore struct {
 }
 
 func (s *CommentStore) CreateByPostID(ctx context.Context, comment *Comment) error {
-	query := `
-		WITH inserted_comment AS (
-			INSERT INTO comments (post_id, user_id, content)
-			VALUES ($1, $2, $3)
-			RETURNING id, post_id, user_id, content, created_at
+	if comment.Depth > MaxCommentDepth {
+		return ErrCommentTooDeep
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			WITH inserted_comment AS (
+				INSERT INTO comments (post_id, user_id, content, parent_id, depth)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id, post_id, user_id, content, created_at, updated_at, version
+			)
+			SELECT
+				ic.id, ic.post_id, ic.user_id, ic.content, ic.created_at, ic.updated_at, ic.version,
+				u.id, u.username
+			FROM inserted_comment ic
+			JOIN users u ON ic.user_id = u.id
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			comment.PostID,
+			comment.UserID,
+			comment.Content,
+			comment.ParentID,
+			comment.Depth,
+		).Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.UserID,
+			&comment.Content,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.Version,
+			&comment.User.ID,
+			&comment.User.Username,
 		)
-		SELECT 
-			ic.id, ic.post_id, ic.user_id, ic.content, ic.created_at, 
-			u.id, u.username
-		FROM inserted_comment ic
-		JOIN users u ON ic.user_id = u.id
+
+		if err != nil {
+			return err
+		}
+
+		return saveMentions(ctx, tx, comment.UserID, nil, &comment.ID, &comment.Entities)
+	})
+}
+
+func (s *CommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users on users.id = c.user_id
+		WHERE c.id = $1
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content, 
-	).Scan(
-		&comment.ID,
-		&comment.PostID,
-		&comment.UserID,
-		&comment.Content,
-		&comment.CreatedAt,
-		&comment.User.ID,
-		&comment.User.Username,
-	)
-
+	c := &Comment{}
+	err := scanComment(s.db.QueryRowContext(ctx, query, commentID), c)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// PatchByID updates the content of a comment, keeping the previous content in
+// the edit history. editorID is who made the change, which might be a
+// moderator rather than the author. It fails with ErrConflict when the
+// comment changed since it was read.
+func (s *CommentStore) PatchByID(ctx context.Context, comment *Comment, editorID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.createEdit(ctx, tx, comment, editorID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE comments
+			SET content = $1, version = version + 1, updated_at = NOW()
+			WHERE id = $2 AND version = $3 AND deleted_at IS NULL
+			RETURNING version, updated_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			comment.Content,
+			comment.ID,
+			comment.Version,
+		).Scan(&comment.Version, &comment.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return s.patchMissed(ctx, tx, comment.ID)
+			}
+			return err
+		}
+
+		return saveMentions(ctx, tx, comment.UserID, nil, &comment.ID, &comment.Entities)
+	})
+}
+
+// patchMissed tells why a comment wasn't updated: ErrConflict when it was
+// changed since it was read, ErrNotFound when it is gone.
+func (s *CommentStore) patchMissed(ctx context.Context, tx *sql.Tx, commentID int64) error {
+	query := `SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, query, commentID).Scan(&exists); err != nil {
 		return err
 	}
 
-	return nil
+	if exists {
+		return ErrConflict
+	}
+
+	return ErrNotFound
 }
 
-func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]*Comment, error) {
+// DeleteByID removes a comment. A comment that has replies is turned into a
+// tombstone instead so the thread below it stays reachable.
+func (s *CommentStore) DeleteByID(ctx context.Context, commentID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.deleteEdits(ctx, tx, commentID); err != nil {
+			return err
+		}
+
+		hasReplies, err := s.hasReplies(ctx, tx, commentID)
+		if err != nil {
+			return err
+		}
+
+		query := `DELETE FROM comments WHERE id = $1 AND deleted_at IS NULL`
+		if hasReplies {
+			query = `
+				UPDATE comments
+				SET content = '', deleted_at = NOW(), version = version + 1, updated_at = NOW()
+				WHERE id = $1 AND deleted_at IS NULL
+			`
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		result, err := tx.ExecContext(ctx, query, commentID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		// A tombstone has no content left to mention anyone
+		var noEntities []Entity
+		return saveMentions(ctx, tx, 0, nil, &commentID, &noEntities)
+	})
+}
+
+func (s *CommentStore) GetEdits(ctx context.Context, commentID int64) ([]*CommentEdit, error) {
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c
+		SELECT id, comment_id, version, content, edited_by, created_at
+		FROM comment_edits
+		WHERE comment_id = $1
+		ORDER BY version DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []*CommentEdit{}
+
+	for rows.Next() {
+		e := &CommentEdit{}
+		err := rows.Scan(
+			&e.ID,
+			&e.CommentID,
+			&e.Version,
+			&e.Content,
+			&e.EditedBy,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		edits = append(edits, e)
+	}
+
+	return edits, rows.Err()
+}
+
+func (s *CommentStore) createEdit(ctx context.Context, tx *sql.Tx, comment *Comment, editorID int64) error {
+	query := `
+		INSERT INTO comment_edits (comment_id, version, content, edited_by)
+		SELECT id, version, content, $2
+		FROM comments
+		WHERE id = $1 AND version = $3 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, comment.ID, editorID, comment.Version)
+	return err
+}
+
+func (s *CommentStore) deleteEdits(ctx context.Context, tx *sql.Tx, commentID int64) error {
+	query := `DELETE FROM comment_edits WHERE comment_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, commentID)
+	return err
+}
+
+func (s *CommentStore) hasReplies(ctx context.Context, tx *sql.Tx, commentID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM comments WHERE parent_id = $1)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, query, commentID).Scan(&exists)
+	return exists, err
+}
+
+// GetByPostID returns a page of top-level comments, newest first, each with up
+// to `replies` of its oldest direct replies inlined.
+func (s *CommentStore) GetByPostID(ctx context.Context, postID int64, kq KeysetPaginatedQuery, replies int) ([]*Comment, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", kq.Cursor)
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
 		JOIN users on users.id = c.user_id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at DESC
+		WHERE
+			c.post_id = $1 AND c.parent_id IS NULL AND
+			($2::timestamptz IS NULL OR (c.created_at, c.id) ` + cmp + ` ($2, $4::bigint))
+		ORDER BY c.created_at ` + order + `, c.id ` + order + `
+		LIMIT $3
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -73,6 +322,9 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]*Commen
 		ctx,
 		query,
 		postID,
+		cursorAt,
+		kq.Limit,
+		cursorID,
 	)
 
 	if err != nil {
@@ -81,26 +333,164 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]*Commen
 	defer rows.Close()
 
 	comments := []*Comment{}
+	byID := map[int64]*Comment{}
 
 	for rows.Next() {
-		c := Comment{}
-		c.User = User{}
-		err := rows.Scan(
-			&c.ID,
-			&c.PostID,
-			&c.UserID,
-			&c.Content,
-			&c.CreatedAt,
-			&c.User.Username,
-			&c.User.ID,
+		c := &Comment{}
+		if err := scanComment(rows, c); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, c)
+		byID[c.ID] = c
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if kq.Cursor != nil && kq.Cursor.Backward {
+		slices.Reverse(comments)
+	}
+
+	if replies <= 0 || len(comments) == 0 {
+		return comments, nil
+	}
+
+	if err := s.inlineReplies(ctx, byID, replies); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetReplies returns the reply subtree below a comment as a nested tree.
+func (s *CommentStore) GetReplies(ctx context.Context, commentID int64) ([]*Comment, error) {
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT id FROM comments WHERE parent_id = $1
+			UNION ALL
+			SELECT c.id FROM comments c
+			JOIN thread t ON c.parent_id = t.id
 		)
+		SELECT ` + commentColumns + `
+		FROM thread t
+		JOIN comments c ON c.id = t.id
+		JOIN users on users.id = c.user_id
+		ORDER BY c.id ASC
+		LIMIT $2
+	`
 
-		if err != nil {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, commentID, maxReplySubtree)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies := []*Comment{}
+	byID := map[int64]*Comment{}
+
+	for rows.Next() {
+		c := &Comment{}
+		if err := scanComment(rows, c); err != nil {
 			return nil, err
 		}
 
-		comments = append(comments, &c)
+		byID[c.ID] = c
+
+		// Rows come ordered by ID so a parent is always seen before its replies
+		if parent, ok := byID[*c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+			continue
+		}
+
+		replies = append(replies, c)
 	}
 
-	return comments, nil
-}
\ No newline at end of file
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return replies, nil
+}
+
+func (s *CommentStore) inlineReplies(ctx context.Context, parents map[int64]*Comment, limit int) error {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY id ASC) AS position
+			FROM comments
+			WHERE parent_id = ANY($1)
+		) c
+		JOIN users on users.id = c.user_id
+		WHERE c.position <= $2
+		ORDER BY c.parent_id, c.id ASC
+	`
+
+	ids := make([]int64, 0, len(parents))
+	for id := range parents {
+		ids = append(ids, id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids), limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := &Comment{}
+		if err := scanComment(rows, c); err != nil {
+			return err
+		}
+
+		parent := parents[*c.ParentID]
+		parent.Replies = append(parent.Replies, c)
+	}
+
+	return rows.Err()
+}
+
+// commentColumns are the columns read by scanComment, selected from comments
+// aliased as c joined with users.
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at,
+	c.updated_at, c.version, c.deleted_at IS NOT NULL, users.username, users.id,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanComment(row rowScanner, c *Comment) error {
+	err := row.Scan(
+		&c.ID,
+		&c.PostID,
+		&c.UserID,
+		&c.ParentID,
+		&c.Depth,
+		&c.Content,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.Version,
+		&c.Deleted,
+		&c.User.Username,
+		&c.User.ID,
+		&c.ReplyCount,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Tombstones only keep their place in the thread
+	if c.Deleted {
+		c.UserID = 0
+		c.User = User{}
+	}
+
+	return nil
+}
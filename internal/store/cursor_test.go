@@ -0,0 +1,65 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorSigner(t *testing.T) {
+	s := NewCursorSigner("secret")
+	want := Cursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), ID: 42, Backward: true}
+
+	got, err := s.Decode(s.Encode(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID || got.Backward != want.Backward {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+
+	token := s.Encode(Cursor{CreatedAt: want.CreatedAt, ID: 42})
+	payload, mac, _ := strings.Cut(token, ".")
+
+	for name, token := range map[string]string{
+		"empty":        "",
+		"no signature": payload,
+		"other secret": NewCursorSigner("other").Encode(want),
+		"tampered":     strings.ToUpper(payload) + "." + mac,
+		"not base64":   "!!." + mac,
+	} {
+		if _, err := s.Decode(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: got %v, want %v", name, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestKeysetArgs(t *testing.T) {
+	at := time.Now()
+
+	tests := []struct {
+		order     string
+		cursor    *Cursor
+		readOrder string
+		cmp       string
+	}{
+		{"desc", nil, "desc", "<"},
+		{"asc", nil, "asc", ">"},
+		{"desc", &Cursor{CreatedAt: at, ID: 1}, "desc", "<"},
+		{"desc", &Cursor{CreatedAt: at, ID: 1, Backward: true}, "asc", ">"},
+		{"asc", &Cursor{CreatedAt: at, ID: 1, Backward: true}, "desc", "<"},
+	}
+
+	for _, tt := range tests {
+		readOrder, cmp, createdAt, id := keysetArgs(tt.order, tt.cursor)
+		if readOrder != tt.readOrder || cmp != tt.cmp {
+			t.Errorf("keysetArgs(%q, %+v) = %q, %q, want %q, %q", tt.order, tt.cursor, readOrder, cmp, tt.readOrder, tt.cmp)
+		}
+
+		if (tt.cursor == nil) != (createdAt == nil && id == nil) {
+			t.Errorf("keysetArgs(%q, %+v) returned cursor values %v, %v", tt.order, tt.cursor, createdAt, id)
+		}
+	}
+}
@@ -3,38 +3,54 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"slices"
 
 	"github.com/lib/pq"
 )
 
 type Follower struct {
 	UserID int64 `json:"user_id"`
-	FollowerID int64 `json:"follower_id`
-	CreatedAt string `json:"created_at`
+	FollowerID int64 `json:"follower_id"`
+	// Username is the follower's, filled in on follow requests
+	Username string `json:"username,omitempty"`
+	CreatedAt string `json:"created_at"`
  }
 
  type FollowerStore struct {
 	db *sql.DB
  }
 
- func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64 ) error {
+ // Follow makes followerID follow userID. Following a private account only
+ // asks to, the follow is pending until the account approves it.
+ func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64 ) (bool, error) {
 	query := `
-		INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)
+		INSERT INTO followers (user_id, follower_id, pending)
+		SELECT u.id, $2, u.is_private
+		FROM users u
+		WHERE u.id = $1 AND NOT ` + blockedBetween("u.id", "$2::bigint") + `
+		RETURNING pending
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, userID, followerID)
+	var pending bool
+	err := s.db.QueryRowContext(ctx, query, userID, followerID).Scan(&pending)
 	if err != nil {
 			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-				return ErrConflict
+				return false, ErrConflict
+			}
+
+			// Users who blocked each other can't follow each other
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, ErrNotFound
 			}
 
-			return err
+			return false, err
 	}
 
-	return nil
+	return pending, nil
  }
 
  func (s *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64 ) error {
@@ -48,4 +64,150 @@ type Follower struct {
 
 	_, err := s.db.ExecContext(ctx, query, userID, followerID)
 	return err
- }
\ No newline at end of file
+ }
+
+// IsFollowing reports whether followerID follows userID, a pending follow
+// request doesn't count.
+func (s *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM followers
+			WHERE user_id = $1 AND follower_id = $2 AND NOT pending
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var following bool
+	err := s.db.QueryRowContext(ctx, query, userID, followerID).Scan(&following)
+	return following, err
+}
+
+// GetRequests lists the pending follow requests to userID, newest first.
+func (s *FollowerStore) GetRequests(ctx context.Context, userID int64, kq KeysetPaginatedQuery) ([]*Follower, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", kq.Cursor)
+
+	query := `
+		SELECT f.user_id, f.follower_id, u.username, f.created_at
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE
+			f.user_id = $1 AND f.pending AND
+			($2::timestamptz IS NULL OR (f.created_at, f.follower_id) ` + cmp + ` ($2, $4::bigint))
+		ORDER BY f.created_at ` + order + `, f.follower_id ` + order + `
+		LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, cursorAt, kq.Limit, cursorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []*Follower{}
+	for rows.Next() {
+		f := &Follower{}
+		if err := rows.Scan(&f.UserID, &f.FollowerID, &f.Username, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if kq.Cursor != nil && kq.Cursor.Backward {
+		slices.Reverse(requests)
+	}
+
+	return requests, nil
+}
+
+// Approve turns followerID's pending request to follow userID into a follow.
+func (s *FollowerStore) Approve(ctx context.Context, userID, followerID int64) error {
+	query := `
+		UPDATE followers SET pending = false
+		WHERE user_id = $1 AND follower_id = $2 AND pending
+	`
+
+	return s.execRequest(ctx, query, userID, followerID)
+}
+
+// Reject drops followerID's pending request to follow userID.
+func (s *FollowerStore) Reject(ctx context.Context, userID, followerID int64) error {
+	query := `
+		DELETE FROM followers
+		WHERE user_id = $1 AND follower_id = $2 AND pending
+	`
+
+	return s.execRequest(ctx, query, userID, followerID)
+}
+
+// execRequest runs a query on a follow request, which is not found when the
+// query doesn't change anything.
+func (s *FollowerStore) execRequest(ctx context.Context, query string, userID, followerID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, followerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetFollowerIDs returns the IDs of the users following the user, leaving
+// out pending follow requests.
+func (s *FollowerStore) GetFollowerIDs(ctx context.Context, userID int64) ([]int64, error) {
+	query := `SELECT follower_id FROM followers WHERE user_id = $1 AND NOT pending`
+
+	return s.queryIDs(ctx, query, userID)
+}
+
+// GetRequesterIDs returns the IDs of the users waiting for the user to
+// approve their follow request.
+func (s *FollowerStore) GetRequesterIDs(ctx context.Context, userID int64) ([]int64, error) {
+	query := `SELECT follower_id FROM followers WHERE user_id = $1 AND pending`
+
+	return s.queryIDs(ctx, query, userID)
+}
+
+func (s *FollowerStore) queryIDs(ctx context.Context, query string, userID int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+type LinkPreviewStore struct {
+	db *sql.DB
+}
+
+// NeedsFetch reports whether url has no preview yet, or one older than
+// maxAge. Failed fetches are retried after failedMaxAge instead.
+func (s *LinkPreviewStore) NeedsFetch(ctx context.Context, url string, maxAge, failedMaxAge time.Duration) (bool, error) {
+	query := `
+		SELECT NOT EXISTS (
+			SELECT 1 FROM link_previews
+			WHERE url = $1 AND fetched_at > NOW() - (CASE WHEN ok THEN $2 ELSE $3 END) * INTERVAL '1 second'
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var needed bool
+	err := s.db.QueryRowContext(ctx, query, url, maxAge.Seconds(), failedMaxAge.Seconds()).Scan(&needed)
+
+	return needed, err
+}
+
+// Save stores the preview of url, or that fetching it failed when preview
+// is nil.
+func (s *LinkPreviewStore) Save(ctx context.Context, url string, preview *LinkPreview) error {
+	query := `
+		INSERT INTO link_previews (url, title, description, image_url, site_name, ok)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (url) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			image_url = EXCLUDED.image_url,
+			site_name = EXCLUDED.site_name,
+			ok = EXCLUDED.ok,
+			fetched_at = NOW()
+	`
+
+	if preview == nil {
+		preview = &LinkPreview{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		url,
+		preview.Title,
+		preview.Description,
+		preview.ImageURL,
+		preview.SiteName,
+		preview.Title != "",
+	)
+
+	return err
+}
+
+// GetByURLs returns the previews fetched for the URLs, keyed by URL. URLs
+// without one, or whose fetch failed, are left out.
+func (s *LinkPreviewStore) GetByURLs(ctx context.Context, urls []string) (map[string]*LinkPreview, error) {
+	query := `
+		SELECT url, title, description, image_url, site_name
+		FROM link_previews
+		WHERE url = ANY($1) AND ok
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	previews := make(map[string]*LinkPreview)
+
+	for rows.Next() {
+		p := &LinkPreview{}
+		if err := rows.Scan(&p.URL, &p.Title, &p.Description, &p.ImageURL, &p.SiteName); err != nil {
+			return nil, err
+		}
+
+		previews[p.URL] = p
+	}
+
+	return previews, rows.Err()
+}
@@ -0,0 +1,449 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// MaxListMembers is how many accounts a list can have.
+const MaxListMembers = 500
+
+var ErrListMemberLimit = fmt.Errorf("a list can have at most %d members", MaxListMembers)
+
+// List is a curated set of accounts whose posts make up a feed of their
+// own. Public lists can be seen and followed by other users.
+type List struct {
+	ID            int64  `json:"id"`
+	UserID        int64  `json:"user_id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	IsPrivate     bool   `json:"is_private"`
+	MemberCount   int    `json:"member_count"`
+	FollowerCount int    `json:"follower_count"`
+	// Following is set when the viewer follows the list
+	Following bool   `json:"following"`
+	User      User   `json:"user"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type ListStore struct {
+	db *sql.DB
+}
+
+// listVisibleTo returns the SQL condition under which the list aliased as
+// list can be seen by the user bound to viewer: their own lists, and the
+// public lists of users they haven't blocked or been blocked by.
+func listVisibleTo(list, viewer string) string {
+	return fmt.Sprintf(`(
+		%[1]s.user_id = %[2]s OR (NOT %[1]s.is_private AND NOT %[3]s)
+	)`, list, viewer, blockedBetween(list+".user_id", viewer))
+}
+
+// listColumns are the columns read by scanList, selected from lists aliased
+// as l joined with their owner as u, for the viewer bound to $1.
+const listColumns = `
+	l.id, l.user_id, l.name, l.description, l.is_private, l.created_at, l.updated_at, u.username,
+	(SELECT COUNT(*) FROM list_members lm WHERE lm.list_id = l.id) AS member_count,
+	(SELECT COUNT(*) FROM list_followers lf WHERE lf.list_id = l.id) AS follower_count,
+	EXISTS (SELECT 1 FROM list_followers lf WHERE lf.list_id = l.id AND lf.user_id = $1) AS following
+`
+
+func scanList(row rowScanner, l *List) error {
+	err := row.Scan(
+		&l.ID,
+		&l.UserID,
+		&l.Name,
+		&l.Description,
+		&l.IsPrivate,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+		&l.User.Username,
+		&l.MemberCount,
+		&l.FollowerCount,
+		&l.Following,
+	)
+	if err != nil {
+		return err
+	}
+
+	l.User.ID = l.UserID
+
+	return nil
+}
+
+// Create makes a new list, returning ErrConflict when its owner already has
+// one with the same name.
+func (s *ListStore) Create(ctx context.Context, list *List) error {
+	query := `
+		INSERT INTO lists (user_id, name, description, is_private)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		list.UserID,
+		list.Name,
+		list.Description,
+		list.IsPrivate,
+	).Scan(&list.ID, &list.CreatedAt, &list.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// GetByID returns a list the viewer can see.
+func (s *ListStore) GetByID(ctx context.Context, listID, viewerID int64) (*List, error) {
+	query := `
+		SELECT ` + listColumns + `
+		FROM lists l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.id = $2 AND ` + listVisibleTo("l", "$1") + `
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	list := &List{}
+	if err := scanList(s.db.QueryRowContext(ctx, query, viewerID, listID), list); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetByUserID returns the lists of a user that the viewer can see, by name.
+func (s *ListStore) GetByUserID(ctx context.Context, userID, viewerID int64) ([]*List, error) {
+	query := `
+		SELECT ` + listColumns + `
+		FROM lists l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.user_id = $2 AND ` + listVisibleTo("l", "$1") + `
+		ORDER BY l.name
+	`
+
+	return s.query(ctx, query, viewerID, userID)
+}
+
+// GetFollowed returns the lists the user follows and can still see, most
+// recently followed first.
+func (s *ListStore) GetFollowed(ctx context.Context, userID int64) ([]*List, error) {
+	query := `
+		SELECT ` + listColumns + `
+		FROM list_followers f
+		JOIN lists l ON l.id = f.list_id
+		JOIN users u ON u.id = l.user_id
+		WHERE f.user_id = $1 AND ` + listVisibleTo("l", "$1") + `
+		ORDER BY f.created_at DESC, l.id DESC
+	`
+
+	return s.query(ctx, query, userID)
+}
+
+func (s *ListStore) query(ctx context.Context, query string, args ...any) ([]*List, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []*List{}
+
+	for rows.Next() {
+		l := &List{}
+		if err := scanList(rows, l); err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, l)
+	}
+
+	return lists, rows.Err()
+}
+
+// Update saves the name, description and privacy of a list. Making a list
+// private drops its followers, who can't see it anymore.
+func (s *ListStore) Update(ctx context.Context, list *List) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			UPDATE lists
+			SET name = $1, description = $2, is_private = $3, updated_at = NOW()
+			WHERE id = $4
+			RETURNING updated_at
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			list.Name,
+			list.Description,
+			list.IsPrivate,
+			list.ID,
+		).Scan(&list.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrConflict
+			default:
+				return err
+			}
+		}
+
+		if !list.IsPrivate {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM list_followers WHERE list_id = $1`, list.ID); err != nil {
+			return err
+		}
+
+		list.FollowerCount = 0
+		list.Following = false
+
+		return nil
+	})
+}
+
+func (s *ListStore) Delete(ctx context.Context, listID int64) error {
+	query := `DELETE FROM lists WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, listID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// AddMember adds a user to a list. Adding a member twice does nothing, and
+// users who blocked the list's owner, or were blocked by them, are reported
+// as not found.
+func (s *ListStore) AddMember(ctx context.Context, listID, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Lock the list so two members added at once can't both fit under
+		// the limit
+		var ownerID int64
+		query := `SELECT user_id FROM lists WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, listID).Scan(&ownerID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+
+			return err
+		}
+
+		var members, alreadyMember int
+		query = `
+			SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2)
+			FROM list_members
+			WHERE list_id = $1
+		`
+
+		if err := tx.QueryRowContext(ctx, query, listID, userID).Scan(&members, &alreadyMember); err != nil {
+			return err
+		}
+
+		if alreadyMember > 0 {
+			return nil
+		}
+
+		if members >= MaxListMembers {
+			return ErrListMemberLimit
+		}
+
+		query = `
+			INSERT INTO list_members (list_id, user_id)
+			SELECT $1::bigint, u.id FROM users u
+			WHERE u.id = $2 AND NOT ` + blockedBetween("u.id", "$3::bigint") + `
+		`
+
+		result, err := tx.ExecContext(ctx, query, listID, userID, ownerID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+func (s *ListStore) RemoveMember(ctx context.Context, listID, userID int64) error {
+	query := `DELETE FROM list_members WHERE list_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, listID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetMembers returns a page of the members of a list, oldest accounts first.
+func (s *ListStore) GetMembers(ctx context.Context, listID int64, kq KeysetPaginatedQuery) ([]*User, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("asc", kq.Cursor)
+
+	query := `
+		SELECT u.id, u.username, u.created_at, u.is_private
+		FROM list_members lm
+		JOIN users u ON u.id = lm.user_id
+		WHERE
+			lm.list_id = $1 AND
+			($2::timestamptz IS NULL OR (u.created_at, u.id) ` + cmp + ` ($2, $4::bigint))
+		ORDER BY u.created_at ` + order + `, u.id ` + order + `
+		LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, listID, cursorAt, kq.Limit, cursorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+
+	for rows.Next() {
+		u := &User{}
+		if err := rows.Scan(&u.ID, &u.Username, &u.CreatedAt, &u.IsPrivate); err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if kq.Cursor != nil && kq.Cursor.Backward {
+		slices.Reverse(users)
+	}
+
+	return users, nil
+}
+
+// Follow makes the user follow a list they can see, returning ErrNotFound
+// when they can't and ErrConflict when they already follow it.
+func (s *ListStore) Follow(ctx context.Context, listID, userID int64) error {
+	query := `
+		INSERT INTO list_followers (list_id, user_id)
+		SELECT l.id, $2::bigint FROM lists l
+		WHERE l.id = $1 AND l.user_id <> $2 AND ` + listVisibleTo("l", "$2") + `
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, listID, userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ListStore) Unfollow(ctx context.Context, listID, userID int64) error {
+	query := `DELETE FROM list_followers WHERE list_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, listID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,241 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidMedia = errors.New("media does not exist or is already attached")
+
+type Media struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	PostID      *int64 `json:"post_id"`
+	Position    int    `json:"position"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"content_type"`
+	StorageKey  string `json:"-"`
+	URL         string `json:"url"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	AltText     string `json:"alt_text"`
+	Blurhash    string `json:"blurhash"`
+	Sensitive   bool   `json:"sensitive"`
+	SizeBytes   int64  `json:"size_bytes"`
+	CreatedAt   string `json:"created_at"`
+}
+
+const mediaColumns = `
+	id, user_id, post_id, position, kind, content_type, storage_key,
+	width, height, alt_text, blurhash, sensitive, size_bytes, created_at
+`
+
+type MediaStore struct {
+	db *sql.DB
+}
+
+func (s *MediaStore) Create(ctx context.Context, media *Media) error {
+	query := `
+		INSERT INTO media (user_id, kind, content_type, storage_key, width, height, alt_text, blurhash, sensitive, size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		media.UserID,
+		media.Kind,
+		media.ContentType,
+		media.StorageKey,
+		media.Width,
+		media.Height,
+		media.AltText,
+		media.Blurhash,
+		media.Sensitive,
+		media.SizeBytes,
+	).Scan(&media.ID, &media.CreatedAt)
+}
+
+func (s *MediaStore) GetByID(ctx context.Context, ID int64) (*Media, error) {
+	query := `SELECT ` + mediaColumns + ` FROM media WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	media, err := scanMedia(s.db.QueryRowContext(ctx, query, ID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// GetByPostIDs returns the attachments of several posts at once, keyed by
+// post ID and in the order they were attached.
+func (s *MediaStore) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]*Media, error) {
+	query := `
+		SELECT ` + mediaColumns + ` FROM media
+		WHERE post_id = ANY($1)
+		ORDER BY post_id, position
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := make(map[int64][]*Media)
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		media[*m.PostID] = append(media[*m.PostID], m)
+	}
+
+	return media, rows.Err()
+}
+
+// GetOrphaned returns uploads that were never attached to a post, or whose
+// post has been deleted, and are older than the given age.
+func (s *MediaStore) GetOrphaned(ctx context.Context, age time.Duration, limit int) ([]*Media, error) {
+	query := `
+		SELECT ` + mediaColumns + ` FROM media
+		WHERE post_id IS NULL AND created_at < NOW() - make_interval(secs => $1)
+		ORDER BY created_at
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, age.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orphans []*Media
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		orphans = append(orphans, m)
+	}
+
+	return orphans, rows.Err()
+}
+
+// Delete removes an unattached upload, attached media goes away with its
+// post.
+func (s *MediaStore) Delete(ctx context.Context, ID int64) error {
+	query := `DELETE FROM media WHERE id = $1 AND post_id IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// attachMedia links the uploads to a post in the order given. Each upload
+// must belong to the post's author and not be attached yet. The columns of
+// ids are named so they don't clash with the unqualified media columns
+// returned.
+func attachMedia(ctx context.Context, tx *sql.Tx, post *Post) error {
+	query := `
+		UPDATE media m
+		SET post_id = $1, position = ids.ord - 1
+		FROM unnest($3::bigint[]) WITH ORDINALITY AS ids (media_id, ord)
+		WHERE m.id = ids.media_id AND m.user_id = $2 AND m.post_id IS NULL
+		RETURNING ` + mediaColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := tx.QueryContext(ctx, query, post.ID, post.UserID, pq.Array(post.MediaIDs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	post.Media = make([]*Media, len(post.MediaIDs))
+	attached := 0
+
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return err
+		}
+
+		post.Media[m.Position] = m
+		attached++
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if attached != len(post.MediaIDs) {
+		return ErrInvalidMedia
+	}
+
+	return nil
+}
+
+func scanMedia(row rowScanner) (*Media, error) {
+	m := &Media{}
+
+	err := row.Scan(
+		&m.ID,
+		&m.UserID,
+		&m.PostID,
+		&m.Position,
+		&m.Kind,
+		&m.ContentType,
+		&m.StorageKey,
+		&m.Width,
+		&m.Height,
+		&m.AltText,
+		&m.Blurhash,
+		&m.Sensitive,
+		&m.SizeBytes,
+		&m.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
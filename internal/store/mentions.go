@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+const (
+	EntityMention = "mention"
+	EntityHashtag = "hashtag"
+)
+
+// Entity marks a span of a post or comment's content that refers to
+// something. Offset and Length count runes.
+type Entity struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	UserID   int64  `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+}
+
+// Mention is a post or comment that mentioned a user.
+type Mention struct {
+	ID        int64  `json:"id"`
+	PostID    int64  `json:"post_id"`
+	CommentID *int64 `json:"comment_id,omitempty"`
+	Content   string `json:"content"`
+	Author    User   `json:"author"`
+	CreatedAt string `json:"created_at"`
+}
+
+type MentionStore struct {
+	db *sql.DB
+}
+
+// GetByUserID lists the posts and comments mentioning the user, newest
+// first. Each post or comment is listed once however many times it mentions
+// the user, and only if the user can still see it.
+func (s *MentionStore) GetByUserID(ctx context.Context, userID int64, kq KeysetPaginatedQuery) ([]*Mention, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", kq.Cursor)
+
+	query := `
+		SELECT m.id, p.id, m.comment_id, COALESCE(c.content, p.content), u.id, u.username, m.created_at
+		FROM mentions m
+		LEFT JOIN comments c ON c.id = m.comment_id
+		JOIN posts p ON p.id = COALESCE(m.post_id, c.post_id)
+		JOIN users u ON u.id = m.author_id
+		WHERE
+			m.user_id = $1 AND
+			($2::timestamptz IS NULL OR (m.created_at, m.id) ` + cmp + ` ($2, $4::bigint)) AND
+			c.deleted_at IS NULL AND
+			NOT EXISTS (
+				SELECT 1 FROM mentions e
+				WHERE e.user_id = m.user_id AND e.id < m.id AND
+					e.post_id IS NOT DISTINCT FROM m.post_id AND
+					e.comment_id IS NOT DISTINCT FROM m.comment_id
+			) AND
+			NOT ` + blockedBetween("m.author_id", "$1") + ` AND
+			` + visibleTo("p", "$1") + `
+		ORDER BY m.created_at ` + order + `, m.id ` + order + `
+		LIMIT $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, cursorAt, kq.Limit, cursorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []*Mention{}
+
+	for rows.Next() {
+		m := &Mention{}
+		err := rows.Scan(
+			&m.ID,
+			&m.PostID,
+			&m.CommentID,
+			&m.Content,
+			&m.Author.ID,
+			&m.Author.Username,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		mentions = append(mentions, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if kq.Cursor != nil && kq.Cursor.Backward {
+		slices.Reverse(mentions)
+	}
+
+	return mentions, nil
+}
+
+// GetByPostIDs returns the mention entities of several posts, keyed by post
+// ID.
+func (s *MentionStore) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]Entity, error) {
+	return s.getEntities(ctx, "m.post_id", postIDs)
+}
+
+// GetByCommentIDs returns the mention entities of several comments, keyed by
+// comment ID.
+func (s *MentionStore) GetByCommentIDs(ctx context.Context, commentIDs []int64) (map[int64][]Entity, error) {
+	return s.getEntities(ctx, "m.comment_id", commentIDs)
+}
+
+func (s *MentionStore) getEntities(ctx context.Context, column string, ids []int64) (map[int64][]Entity, error) {
+	query := `
+		SELECT ` + column + `, m.position, m.length, u.id, u.username
+		FROM mentions m
+		JOIN users u ON u.id = m.user_id
+		WHERE ` + column + ` = ANY($1)
+		ORDER BY m.position
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entities := make(map[int64][]Entity)
+
+	for rows.Next() {
+		var id int64
+		e := Entity{Type: EntityMention}
+
+		if err := rows.Scan(&id, &e.Offset, &e.Length, &e.UserID, &e.Username); err != nil {
+			return nil, err
+		}
+
+		entities[id] = append(entities[id], e)
+	}
+
+	return entities, rows.Err()
+}
+
+// saveMentions resolves the parsed mention entities of a post or comment to
+// users and stores them, replacing any mentions stored before. Mentions of
+// unknown users and of users blocking or blocked by the author are dropped
+// from entities.
+func saveMentions(ctx context.Context, tx *sql.Tx, authorID int64, postID, commentID *int64, entities *[]Entity) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `DELETE FROM mentions WHERE post_id = $1 OR comment_id = $2`
+	if _, err := tx.ExecContext(ctx, query, postID, commentID); err != nil {
+		return err
+	}
+
+	var usernames []string
+	var positions, lengths []int64
+
+	for _, e := range *entities {
+		if e.Type != EntityMention {
+			continue
+		}
+
+		usernames = append(usernames, e.Username)
+		positions = append(positions, int64(e.Offset))
+		lengths = append(lengths, int64(e.Length))
+	}
+
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	query = `
+		INSERT INTO mentions (user_id, author_id, post_id, comment_id, position, length)
+		SELECT u.id, $1, $2, $3, m.position, m.length
+		FROM unnest($4::text[], $5::int[], $6::int[]) AS m (username, position, length)
+		JOIN users u ON u.username = m.username
+		WHERE NOT ` + blockedBetween("u.id", "$1") + `
+		RETURNING user_id, position
+	`
+
+	rows, err := tx.QueryContext(
+		ctx,
+		query,
+		authorID,
+		postID,
+		commentID,
+		pq.Array(usernames),
+		pq.Array(positions),
+		pq.Array(lengths),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	resolved := make(map[int]int64)
+	for rows.Next() {
+		var userID int64
+		var position int
+
+		if err := rows.Scan(&userID, &position); err != nil {
+			return err
+		}
+
+		resolved[position] = userID
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	kept := []Entity{}
+	for _, e := range *entities {
+		if e.Type != EntityMention {
+			kept = append(kept, e)
+			continue
+		}
+
+		if userID, ok := resolved[e.Offset]; ok {
+			e.UserID = userID
+			kept = append(kept, e)
+		}
+	}
+
+	*entities = kept
+	return nil
+}
@@ -1,24 +1,54 @@
 package store
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/qwerqy/social-api-go/internal/ranking"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:        &MockUserStore{},
+		Posts:        &MockPostStore{},
+		Comments:     &MockCommentStore{},
+		Roles:        &MockRoleStore{},
+		Bookmarks:    &MockBookmarkStore{},
+		Polls:        &MockPollStore{},
+		Media:        &MockMediaStore{},
+		Followers:    &MockFollowerStore{},
+		Blocks:       &MockBlockStore{},
+		Mentions:     &MockMentionStore{},
+		Tags:         &MockTagStore{},
+		Reactions:    &MockReactionStore{},
+		Trending:     &MockTrendingStore{},
+		LinkPreviews: &MockLinkPreviewStore{},
+		Settings:     &MockSettingsStore{},
+		Pins:         &MockPinStore{},
+		MutedWords:   &MockMutedWordStore{},
+		Lists:        &MockListStore{},
 	}
 }
 
-type MockUserStore struct{}
+// MockUserStore serves the users in Users, and a bare user for any other ID.
+type MockUserStore struct {
+	Users map[int64]*User
+}
 
 func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	return nil
 }
 
 func (m *MockUserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
+	if user, ok := m.Users[userID]; ok {
+		u := *user
+		return &u, nil
+	}
+
 	return &User{ID: userID}, nil
 }
 
@@ -37,3 +67,899 @@ func (m *MockUserStore) Activate(ctx context.Context, token string) error {
 func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
 	return nil
 }
+
+// MockPostStore serves the posts in Posts. Visible decides which of them a
+// viewer can see, all of them when it's nil. Like the database, it keeps one
+// repost of a post per user. Rendered lists the IDs of the posts whose
+// rendering was saved, in order. Profiles list UserPosts after the Pinned
+// ones, keeping the filters asked for in UserPostsQuery, and the ranked feed
+// ranks the Candidates. Other lists are empty.
+type MockPostStore struct {
+	Posts          map[int64]*Post
+	Visible        func(post *Post, viewerID int64) bool
+	Rendered       []int64
+	UserPosts      []*PostWithMetadata
+	Pinned         []*PostWithMetadata
+	UserPostsQuery UserPostsQuery
+	Candidates     []ranking.Candidate
+	// Feed is the feed of every user and list, FeedQuery the last query for
+	// one
+	Feed      []*PostWithMetadata
+	FeedQuery PaginatedFeedQuery
+}
+
+func (m *MockPostStore) GetByID(ctx context.Context, postID int64) (*Post, error) {
+	post, ok := m.Posts[postID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	p := *post
+	return &p, nil
+}
+
+func (m *MockPostStore) GetVisibleByID(ctx context.Context, postID, viewerID int64) (*Post, error) {
+	post, err := m.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Visible != nil && !m.Visible(post, viewerID) {
+		return nil, ErrNotFound
+	}
+
+	return post, nil
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) error {
+	if m.Posts == nil {
+		m.Posts = make(map[int64]*Post)
+	}
+
+	if post.Kind == PostKindRepost {
+		if _, ok := m.findRepost(post.UserID, *post.OriginalID); ok {
+			return ErrConflict
+		}
+	}
+
+	post.ID = int64(len(m.Posts)) + 1
+	for m.Posts[post.ID] != nil {
+		post.ID++
+	}
+
+	p := *post
+	m.Posts[post.ID] = &p
+	return nil
+}
+
+func (m *MockPostStore) DeleteByID(ctx context.Context, postID int64) error {
+	if _, ok := m.Posts[postID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.Posts, postID)
+	return nil
+}
+
+func (m *MockPostStore) DeleteRepost(ctx context.Context, userID, originalID int64) error {
+	id, ok := m.findRepost(userID, originalID)
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(m.Posts, id)
+	return nil
+}
+
+func (m *MockPostStore) findRepost(userID, originalID int64) (int64, bool) {
+	for id, p := range m.Posts {
+		if p.Kind == PostKindRepost && p.UserID == userID && p.OriginalID != nil && *p.OriginalID == originalID {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+func (m *MockPostStore) PatchByID(ctx context.Context, post *Post) error {
+	if _, ok := m.Posts[post.ID]; ok {
+		p := *post
+		m.Posts[post.ID] = &p
+	}
+
+	return nil
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]*PostWithMetadata, error) {
+	m.FeedQuery = fq
+	return append([]*PostWithMetadata{}, m.Feed...), nil
+}
+
+func (m *MockPostStore) GetListFeed(ctx context.Context, listID, viewerID int64, fq PaginatedFeedQuery) ([]*PostWithMetadata, error) {
+	m.FeedQuery = fq
+	return append([]*PostWithMetadata{}, m.Feed...), nil
+}
+
+func (m *MockPostStore) GetRankingCandidates(ctx context.Context, userID int64, fq PaginatedFeedQuery, window time.Duration, limit int) ([]ranking.Candidate, error) {
+	return m.Candidates, nil
+}
+
+func (m *MockPostStore) GetByTag(ctx context.Context, tag string, viewerID int64, kq KeysetPaginatedQuery) ([]*PostWithMetadata, error) {
+	return []*PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetUnrendered(ctx context.Context, afterID int64, limit int) ([]*Post, error) {
+	posts := []*Post{}
+	for _, post := range m.Posts {
+		if post.ID > afterID && post.Content != "" && post.RenderedVersion != post.Version {
+			posts = append(posts, &Post{ID: post.ID, Content: post.Content, ContentFormat: post.ContentFormat, Version: post.Version, RenderedVersion: -1})
+		}
+	}
+
+	slices.SortFunc(posts, func(a, b *Post) int { return cmp.Compare(a.ID, b.ID) })
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	return posts, nil
+}
+
+func (m *MockPostStore) SaveRendered(ctx context.Context, posts []*Post) error {
+	for _, p := range posts {
+		if stored, ok := m.Posts[p.ID]; ok && stored.Version == p.RenderedVersion {
+			stored.ContentHTML = p.ContentHTML
+			stored.RenderedVersion = p.RenderedVersion
+			m.Rendered = append(m.Rendered, p.ID)
+		}
+	}
+
+	return nil
+}
+
+func (m *MockPostStore) GetVisibleByIDs(ctx context.Context, postIDs []int64, viewerID int64, mq MuteQuery) ([]*PostWithMetadata, error) {
+	posts := []*PostWithMetadata{}
+	for _, post := range m.Posts {
+		if !slices.Contains(postIDs, post.ID) || (m.Visible != nil && !m.Visible(post, viewerID)) {
+			continue
+		}
+
+		posts = append(posts, &PostWithMetadata{Post: *post})
+	}
+
+	return posts, nil
+}
+
+func (m *MockPostStore) GetByUserID(ctx context.Context, userID, viewerID int64, uq UserPostsQuery) ([]*PostWithMetadata, error) {
+	m.UserPostsQuery = uq
+	return append([]*PostWithMetadata{}, m.UserPosts...), nil
+}
+
+func (m *MockPostStore) GetPinned(ctx context.Context, userID, viewerID int64) ([]*PostWithMetadata, error) {
+	return append([]*PostWithMetadata{}, m.Pinned...), nil
+}
+
+// MockCommentStore serves the comments in Comments. Like the database, it
+// only updates a comment from its current version.
+type MockCommentStore struct {
+	Comments map[int64]*Comment
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, commentID int64) (*Comment, error) {
+	comment, ok := m.Comments[commentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *comment
+	return &c, nil
+}
+
+// GetByPostID pages through the top-level comments of a post like the
+// database does, newest first with their oldest replies inlined. Comments
+// are created in ID order, so the cursor's ID is enough to find its place.
+func (m *MockCommentStore) GetByPostID(ctx context.Context, postID int64, kq KeysetPaginatedQuery, replies int) ([]*Comment, error) {
+	backward := kq.Cursor != nil && kq.Cursor.Backward
+
+	comments := []*Comment{}
+	for _, c := range m.sorted() {
+		if c.PostID != postID || c.ParentID != nil {
+			continue
+		}
+
+		if kq.Cursor == nil || (backward && c.ID > kq.Cursor.ID) || (!backward && c.ID < kq.Cursor.ID) {
+			comments = append(comments, c)
+		}
+	}
+
+	if !backward {
+		slices.Reverse(comments)
+	}
+
+	if len(comments) > kq.Limit {
+		comments = comments[:kq.Limit]
+	}
+
+	if backward {
+		slices.Reverse(comments)
+	}
+
+	for _, c := range comments {
+		c.Replies = m.children(c.ID)
+		if len(c.Replies) > replies {
+			c.Replies = c.Replies[:replies]
+		}
+	}
+
+	return comments, nil
+}
+
+// GetReplies returns the reply subtree below a comment.
+func (m *MockCommentStore) GetReplies(ctx context.Context, commentID int64) ([]*Comment, error) {
+	replies := m.children(commentID)
+	for _, c := range replies {
+		c.Replies, _ = m.GetReplies(ctx, c.ID)
+	}
+
+	return replies, nil
+}
+
+func (m *MockCommentStore) GetEdits(ctx context.Context, commentID int64) ([]*CommentEdit, error) {
+	return []*CommentEdit{}, nil
+}
+
+func (m *MockCommentStore) PatchByID(ctx context.Context, comment *Comment, editorID int64) error {
+	stored, ok := m.Comments[comment.ID]
+	if !ok || stored.Deleted {
+		return ErrNotFound
+	}
+
+	if stored.Version != comment.Version {
+		return ErrConflict
+	}
+
+	comment.Version++
+	stored.Content = comment.Content
+	stored.Version = comment.Version
+
+	return nil
+}
+
+func (m *MockCommentStore) DeleteByID(ctx context.Context, commentID int64) error {
+	if _, ok := m.Comments[commentID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.Comments, commentID)
+	return nil
+}
+
+func (m *MockCommentStore) CreateByPostID(ctx context.Context, comment *Comment) error {
+	if comment.Depth > MaxCommentDepth {
+		return ErrCommentTooDeep
+	}
+
+	if m.Comments == nil {
+		m.Comments = make(map[int64]*Comment)
+	}
+
+	comment.ID = int64(len(m.Comments)) + 1
+	for m.Comments[comment.ID] != nil {
+		comment.ID++
+	}
+
+	c := *comment
+	m.Comments[comment.ID] = &c
+	return nil
+}
+
+// sorted returns copies of the comments ordered by ID.
+func (m *MockCommentStore) sorted() []*Comment {
+	comments := make([]*Comment, 0, len(m.Comments))
+	for _, c := range m.Comments {
+		cc := *c
+		comments = append(comments, &cc)
+	}
+
+	slices.SortFunc(comments, func(a, b *Comment) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return comments
+}
+
+func (m *MockCommentStore) children(parentID int64) []*Comment {
+	children := []*Comment{}
+	for _, c := range m.sorted() {
+		if c.ParentID != nil && *c.ParentID == parentID {
+			children = append(children, c)
+		}
+	}
+
+	return children
+}
+
+// MockRoleStore serves the roles the migrations create.
+type MockRoleStore struct{}
+
+var mockRoles = map[string]*Role{
+	"user":      {ID: 1, Name: "user", Level: 1},
+	"moderator": {ID: 2, Name: "moderator", Level: 2},
+	"admin":     {ID: 3, Name: "admin", Level: 3},
+}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
+	role, ok := mockRoles[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	r := *role
+	return &r, nil
+}
+
+// MockBookmarkStore keeps the IDs of the posts each user bookmarked in
+// Bookmarks.
+type MockBookmarkStore struct {
+	Bookmarks map[int64]map[int64]bool
+}
+
+func (m *MockBookmarkStore) Save(ctx context.Context, bookmark *Bookmark, collection string) error {
+	if m.Bookmarks == nil {
+		m.Bookmarks = make(map[int64]map[int64]bool)
+	}
+
+	if m.Bookmarks[bookmark.UserID] == nil {
+		m.Bookmarks[bookmark.UserID] = make(map[int64]bool)
+	}
+
+	m.Bookmarks[bookmark.UserID][bookmark.PostID] = true
+	return nil
+}
+
+func (m *MockBookmarkStore) Delete(ctx context.Context, userID, postID int64) error {
+	if !m.Bookmarks[userID][postID] {
+		return ErrNotFound
+	}
+
+	delete(m.Bookmarks[userID], postID)
+	return nil
+}
+
+func (m *MockBookmarkStore) Exists(ctx context.Context, userID, postID int64) (bool, error) {
+	return m.Bookmarks[userID][postID], nil
+}
+
+func (m *MockBookmarkStore) GetByUserID(ctx context.Context, userID int64, bq BookmarkQuery) ([]*Bookmark, error) {
+	return []*Bookmark{}, nil
+}
+
+func (m *MockBookmarkStore) GetCollections(ctx context.Context, userID int64) ([]*BookmarkCollection, error) {
+	return []*BookmarkCollection{}, nil
+}
+
+func (m *MockBookmarkStore) DeleteCollection(ctx context.Context, userID, collectionID int64) error {
+	return ErrNotFound
+}
+
+// MockPollStore serves the polls in Polls by post ID. Vote fails with
+// VoteErr when set.
+type MockPollStore struct {
+	Polls   map[int64]*Poll
+	VoteErr error
+}
+
+func (m *MockPollStore) GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error) {
+	poll, ok := m.Polls[postID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	p := *poll
+	p.Options = make([]*PollOption, len(poll.Options))
+	for i, o := range poll.Options {
+		option := *o
+		p.Options[i] = &option
+	}
+
+	return &p, nil
+}
+
+func (m *MockPollStore) Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error {
+	return m.VoteErr
+}
+
+// MockMediaStore has no media.
+type MockMediaStore struct{}
+
+func (m *MockMediaStore) Create(ctx context.Context, media *Media) error {
+	return nil
+}
+
+func (m *MockMediaStore) GetByID(ctx context.Context, mediaID int64) (*Media, error) {
+	return nil, ErrNotFound
+}
+
+func (m *MockMediaStore) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]*Media, error) {
+	return map[int64][]*Media{}, nil
+}
+
+func (m *MockMediaStore) GetOrphaned(ctx context.Context, age time.Duration, limit int) ([]*Media, error) {
+	return nil, nil
+}
+
+func (m *MockMediaStore) Delete(ctx context.Context, mediaID int64) error {
+	return nil
+}
+
+// MockFollowerStore keeps who follows whom in Following, by follower ID, and
+// the follow requests still Pending the same way. Follows between users
+// Blocked reports are refused like the database does, and follows of users
+// Private reports are pending.
+type MockFollowerStore struct {
+	Following map[int64]map[int64]bool
+	Pending   map[int64]map[int64]bool
+	Blocked   func(userID, otherID int64) bool
+	Private   func(userID int64) bool
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID, userID int64) (bool, error) {
+	if m.Blocked != nil && m.Blocked(followerID, userID) {
+		return false, ErrNotFound
+	}
+
+	if m.Following[followerID][userID] || m.Pending[followerID][userID] {
+		return false, ErrConflict
+	}
+
+	if m.Private != nil && m.Private(userID) {
+		m.Pending = addFollow(m.Pending, followerID, userID)
+		return true, nil
+	}
+
+	m.Following = addFollow(m.Following, followerID, userID)
+	return false, nil
+}
+
+func addFollow(follows map[int64]map[int64]bool, followerID, userID int64) map[int64]map[int64]bool {
+	if follows == nil {
+		follows = make(map[int64]map[int64]bool)
+	}
+
+	if follows[followerID] == nil {
+		follows[followerID] = make(map[int64]bool)
+	}
+
+	follows[followerID][userID] = true
+	return follows
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
+	delete(m.Following[followerID], userID)
+	delete(m.Pending[followerID], userID)
+	return nil
+}
+
+func (m *MockFollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	return m.Following[followerID][userID], nil
+}
+
+// GetRequests pages through the requests like the database does. Requests
+// are taken to be made in follower ID order, a minute apart.
+func (m *MockFollowerStore) GetRequests(ctx context.Context, userID int64, kq KeysetPaginatedQuery) ([]*Follower, error) {
+	backward := kq.Cursor != nil && kq.Cursor.Backward
+
+	var ids []int64
+	for followerID, users := range m.Pending {
+		if users[userID] && (kq.Cursor == nil || (backward && followerID > kq.Cursor.ID) || (!backward && followerID < kq.Cursor.ID)) {
+			ids = append(ids, followerID)
+		}
+	}
+
+	slices.Sort(ids)
+	if !backward {
+		slices.Reverse(ids)
+	}
+
+	if len(ids) > kq.Limit {
+		ids = ids[:kq.Limit]
+	}
+
+	if backward {
+		slices.Reverse(ids)
+	}
+
+	requests := []*Follower{}
+	for _, id := range ids {
+		createdAt := time.Date(2026, 1, 1, 0, int(id), 0, 0, time.UTC).Format(time.RFC3339Nano)
+		requests = append(requests, &Follower{UserID: userID, FollowerID: id, CreatedAt: createdAt})
+	}
+
+	return requests, nil
+}
+
+func (m *MockFollowerStore) Approve(ctx context.Context, userID, followerID int64) error {
+	if !m.Pending[followerID][userID] {
+		return ErrNotFound
+	}
+
+	delete(m.Pending[followerID], userID)
+	m.Following = addFollow(m.Following, followerID, userID)
+	return nil
+}
+
+func (m *MockFollowerStore) Reject(ctx context.Context, userID, followerID int64) error {
+	if !m.Pending[followerID][userID] {
+		return ErrNotFound
+	}
+
+	delete(m.Pending[followerID], userID)
+	return nil
+}
+
+func (m *MockFollowerStore) GetFollowerIDs(ctx context.Context, userID int64) ([]int64, error) {
+	return m.followerIDs(m.Following, userID), nil
+}
+
+func (m *MockFollowerStore) GetRequesterIDs(ctx context.Context, userID int64) ([]int64, error) {
+	return m.followerIDs(m.Pending, userID), nil
+}
+
+func (m *MockFollowerStore) followerIDs(follows map[int64]map[int64]bool, userID int64) []int64 {
+	var ids []int64
+	for followerID, users := range follows {
+		if users[userID] {
+			ids = append(ids, followerID)
+		}
+	}
+
+	slices.Sort(ids)
+	return ids
+}
+
+// MockBlockStore keeps the IDs of the users each user blocked in Blocks.
+type MockBlockStore struct {
+	Blocks map[int64]map[int64]bool
+}
+
+func (m *MockBlockStore) Block(ctx context.Context, userID, blockedID int64) error {
+	if m.Blocks[userID][blockedID] {
+		return ErrConflict
+	}
+
+	if m.Blocks == nil {
+		m.Blocks = make(map[int64]map[int64]bool)
+	}
+
+	if m.Blocks[userID] == nil {
+		m.Blocks[userID] = make(map[int64]bool)
+	}
+
+	m.Blocks[userID][blockedID] = true
+	return nil
+}
+
+func (m *MockBlockStore) Unblock(ctx context.Context, userID, blockedID int64) error {
+	if !m.Blocks[userID][blockedID] {
+		return ErrNotFound
+	}
+
+	delete(m.Blocks[userID], blockedID)
+	return nil
+}
+
+func (m *MockBlockStore) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	return m.Blocks[userID][otherID] || m.Blocks[otherID][userID], nil
+}
+
+// MockMentionStore has no mentions.
+type MockMentionStore struct{}
+
+func (m *MockMentionStore) GetByUserID(ctx context.Context, userID int64, kq KeysetPaginatedQuery) ([]*Mention, error) {
+	return []*Mention{}, nil
+}
+
+func (m *MockMentionStore) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]Entity, error) {
+	return map[int64][]Entity{}, nil
+}
+
+func (m *MockMentionStore) GetByCommentIDs(ctx context.Context, commentIDs []int64) (map[int64][]Entity, error) {
+	return map[int64][]Entity{}, nil
+}
+
+// MockTagStore serves the tags in Tags.
+type MockTagStore struct {
+	Tags []*Tag
+}
+
+func (m *MockTagStore) Search(ctx context.Context, prefix string, limit int) ([]*Tag, error) {
+	tags := []*Tag{}
+	for _, t := range m.Tags {
+		if strings.HasPrefix(t.Name, prefix) && len(tags) < limit {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags, nil
+}
+
+// MockReactionStore has no reactions.
+type MockReactionStore struct{}
+
+func (m *MockReactionStore) Set(ctx context.Context, reaction *Reaction) error {
+	return nil
+}
+
+func (m *MockReactionStore) Delete(ctx context.Context, postID, userID int64) error {
+	return ErrNotFound
+}
+
+func (m *MockReactionStore) GetCountsByPostIDs(ctx context.Context, postIDs []int64) (map[int64]map[string]int, error) {
+	return map[int64]map[string]int{}, nil
+}
+
+// MockTrendingStore computes Trending, or an empty snapshot when it's nil,
+// counting how often it is asked to in Computed.
+type MockTrendingStore struct {
+	Trending *Trending
+	Computed int
+}
+
+func (m *MockTrendingStore) Compute(ctx context.Context, window, halfLife time.Duration, limit int) (*Trending, error) {
+	m.Computed++
+
+	if m.Trending == nil {
+		return &Trending{Posts: []TrendingPost{}, Tags: []TrendingTag{}, ComputedAt: time.Now()}, nil
+	}
+
+	return m.Trending, nil
+}
+
+// MockLinkPreviewStore has no previews and never wants one fetched.
+type MockLinkPreviewStore struct{}
+
+func (m *MockLinkPreviewStore) NeedsFetch(ctx context.Context, url string, maxAge, failedMaxAge time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (m *MockLinkPreviewStore) Save(ctx context.Context, url string, preview *LinkPreview) error {
+	return nil
+}
+
+func (m *MockLinkPreviewStore) GetByURLs(ctx context.Context, urls []string) (map[string]*LinkPreview, error) {
+	return map[string]*LinkPreview{}, nil
+}
+
+// MockSettingsStore keeps the settings saved in Settings, by user ID.
+type MockSettingsStore struct {
+	Settings map[int64]*UserSettings
+}
+
+func (m *MockSettingsStore) Get(ctx context.Context, userID int64) (*UserSettings, error) {
+	if settings, ok := m.Settings[userID]; ok {
+		s := *settings
+		return &s, nil
+	}
+
+	return &UserSettings{UserID: userID}, nil
+}
+
+func (m *MockSettingsStore) Update(ctx context.Context, settings *UserSettings) error {
+	if m.Settings == nil {
+		m.Settings = make(map[int64]*UserSettings)
+	}
+
+	settings.UpdatedAt = time.Now().Format(time.RFC3339)
+	s := *settings
+	m.Settings[settings.UserID] = &s
+	return nil
+}
+
+// MockPinStore keeps the position of each pinned post in Positions, by user
+// ID then post ID, placing new pins the way the database does.
+type MockPinStore struct {
+	Positions map[int64]map[int64]int
+}
+
+func (m *MockPinStore) Pin(ctx context.Context, userID, postID int64) error {
+	if m.Positions == nil {
+		m.Positions = make(map[int64]map[int64]int)
+	}
+
+	pins := m.Positions[userID]
+	if pins == nil {
+		pins = make(map[int64]int)
+		m.Positions[userID] = pins
+	}
+
+	if _, ok := pins[postID]; ok {
+		return nil
+	}
+
+	if len(pins) >= MaxPinnedPosts {
+		return ErrPinLimit
+	}
+
+	position := 0
+	for _, p := range pins {
+		position = max(position, p+1)
+	}
+
+	pins[postID] = position
+	return nil
+}
+
+func (m *MockPinStore) Unpin(ctx context.Context, userID, postID int64) error {
+	if _, ok := m.Positions[userID][postID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.Positions[userID], postID)
+	return nil
+}
+
+func (m *MockPinStore) Reorder(ctx context.Context, userID int64, postIDs []int64) error {
+	pins := m.Positions[userID]
+	if len(postIDs) != len(pins) {
+		return ErrInvalidPins
+	}
+
+	for i, id := range postIDs {
+		if _, ok := pins[id]; !ok {
+			return ErrInvalidPins
+		}
+
+		pins[id] = i
+	}
+
+	return nil
+}
+
+// Pinned returns the posts the user pinned, in order.
+func (m *MockPinStore) Pinned(userID int64) []int64 {
+	ids := []int64{}
+	for id := range m.Positions[userID] {
+		ids = append(ids, id)
+	}
+
+	slices.SortFunc(ids, func(a, b int64) int {
+		return cmp.Compare(m.Positions[userID][a], m.Positions[userID][b])
+	})
+
+	return ids
+}
+
+// MockMutedWordStore keeps the words muted by every user in Words.
+type MockMutedWordStore struct {
+	Words []*MutedWord
+}
+
+func (m *MockMutedWordStore) Create(ctx context.Context, word *MutedWord) error {
+	word.ID = int64(len(m.Words) + 1)
+	m.Words = append(m.Words, word)
+	return nil
+}
+
+func (m *MockMutedWordStore) GetByUserID(ctx context.Context, userID int64) ([]*MutedWord, error) {
+	words := []*MutedWord{}
+	for _, w := range m.Words {
+		if w.UserID == userID {
+			words = append(words, w)
+		}
+	}
+
+	return words, nil
+}
+
+func (m *MockMutedWordStore) GetByUserIDs(ctx context.Context, userIDs []int64) (map[int64][]*MutedWord, error) {
+	words := make(map[int64][]*MutedWord)
+	for _, w := range m.Words {
+		if slices.Contains(userIDs, w.UserID) {
+			words[w.UserID] = append(words[w.UserID], w)
+		}
+	}
+
+	return words, nil
+}
+
+func (m *MockMutedWordStore) Delete(ctx context.Context, userID, wordID int64) error {
+	for i, w := range m.Words {
+		if w.ID == wordID && w.UserID == userID {
+			m.Words = slices.Delete(m.Words, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// MockListStore serves the lists in Lists with the visibility rules of
+// listVisibleTo: a user sees their own lists, and the public lists of users
+// Blocked doesn't report between them. Followers holds who follows each
+// list and Members its members.
+type MockListStore struct {
+	Lists     map[int64]*List
+	Members   map[int64][]*User
+	Followers map[int64]map[int64]bool
+	Blocked   func(ownerID, viewerID int64) bool
+}
+
+func (m *MockListStore) visible(list *List, viewerID int64) bool {
+	if list.UserID == viewerID {
+		return true
+	}
+
+	return !list.IsPrivate && (m.Blocked == nil || !m.Blocked(list.UserID, viewerID))
+}
+
+func (m *MockListStore) Create(ctx context.Context, list *List) error {
+	return nil
+}
+
+func (m *MockListStore) GetByID(ctx context.Context, listID, viewerID int64) (*List, error) {
+	list, ok := m.Lists[listID]
+	if !ok || !m.visible(list, viewerID) {
+		return nil, ErrNotFound
+	}
+
+	l := *list
+	l.Following = m.Followers[listID][viewerID]
+	return &l, nil
+}
+
+func (m *MockListStore) GetByUserID(ctx context.Context, userID, viewerID int64) ([]*List, error) {
+	return []*List{}, nil
+}
+
+func (m *MockListStore) GetFollowed(ctx context.Context, userID int64) ([]*List, error) {
+	return []*List{}, nil
+}
+
+func (m *MockListStore) Update(ctx context.Context, list *List) error {
+	return nil
+}
+
+func (m *MockListStore) Delete(ctx context.Context, listID int64) error {
+	return nil
+}
+
+func (m *MockListStore) AddMember(ctx context.Context, listID, userID int64) error {
+	return nil
+}
+
+func (m *MockListStore) RemoveMember(ctx context.Context, listID, userID int64) error {
+	return nil
+}
+
+func (m *MockListStore) GetMembers(ctx context.Context, listID int64, kq KeysetPaginatedQuery) ([]*User, error) {
+	return append([]*User{}, m.Members[listID]...), nil
+}
+
+func (m *MockListStore) Follow(ctx context.Context, listID, userID int64) error {
+	list, ok := m.Lists[listID]
+	if !ok || list.UserID == userID || !m.visible(list, userID) {
+		return ErrNotFound
+	}
+
+	if m.Followers[listID][userID] {
+		return ErrConflict
+	}
+
+	if m.Followers == nil {
+		m.Followers = map[int64]map[int64]bool{}
+	}
+	if m.Followers[listID] == nil {
+		m.Followers[listID] = map[int64]bool{}
+	}
+
+	m.Followers[listID][userID] = true
+	return nil
+}
+
+func (m *MockListStore) Unfollow(ctx context.Context, listID, userID int64) error {
+	if !m.Followers[listID][userID] {
+		return ErrNotFound
+	}
+
+	delete(m.Followers[listID], userID)
+	return nil
+}
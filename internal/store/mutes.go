@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// MaxMutedWords is how many unexpired muted words a user can have.
+const MaxMutedWords = 100
+
+const (
+	MuteKindKeyword = "keyword"
+	MuteKindRegex   = "regex"
+	MuteKindHashtag = "hashtag"
+)
+
+// The places muted words hide things from.
+const (
+	MuteScopeFeed          = "feed"
+	MuteScopeNotifications = "notifications"
+	MuteScopeComments      = "comments"
+)
+
+var ErrMutedWordLimit = fmt.Errorf("at most %d words can be muted", MaxMutedWords)
+
+// MutedWord hides the posts, comments and notifications matching it from
+// its user, in the places listed in Scopes.
+type MutedWord struct {
+	ID      int64    `json:"id"`
+	UserID  int64    `json:"user_id"`
+	Kind    string   `json:"kind"`
+	Pattern string   `json:"pattern"`
+	Scopes  []string `json:"scopes"`
+	// ExpiresAt is nil for words muted until they are unmuted
+	ExpiresAt *string `json:"expires_at"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// MuteQuery has a feed query leave out the posts matching muted words. Words
+// is a PostgreSQL regular expression matched without regard to case, empty
+// to match nothing, and Tags are muted hashtags.
+type MuteQuery struct {
+	Words string
+	Tags  []string
+}
+
+// notMuted matches the posts aliased as alias that neither match the muted
+// words pattern bound to words nor have one of the tags bound to tags. The
+// viewer's own posts are never muted, and neither is a missing post, like
+// the original of a post sharing nothing.
+func notMuted(alias, viewer, words, tags string) string {
+	return fmt.Sprintf(`(%[1]s.id IS NULL OR %[1]s.user_id = %[2]s OR NOT (
+		(%[3]s <> '' AND concat_ws(E'\n', %[1]s.title, %[1]s.content_warning, %[1]s.content) ~* %[3]s) OR
+		%[1]s.tags && %[4]s::varchar[]
+	))`, alias, viewer, words, tags)
+}
+
+type MutedWordStore struct {
+	db *sql.DB
+}
+
+// Create mutes a word, returning ErrConflict when the user already muted
+// the same pattern and ErrMutedWordLimit when they muted too many.
+func (s *MutedWordStore) Create(ctx context.Context, word *MutedWord) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Lock the user so two words muted at once can't both fit under the
+		// limit
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, word.UserID); err != nil {
+			return err
+		}
+
+		// Expired words are only cleared out here, they would otherwise
+		// count towards the limit and keep their pattern from being muted
+		// again
+		query := `DELETE FROM muted_words WHERE user_id = $1 AND expires_at <= NOW()`
+		if _, err := tx.ExecContext(ctx, query, word.UserID); err != nil {
+			return err
+		}
+
+		var muted int
+		query = `SELECT COUNT(*) FROM muted_words WHERE user_id = $1`
+		if err := tx.QueryRowContext(ctx, query, word.UserID).Scan(&muted); err != nil {
+			return err
+		}
+
+		if muted >= MaxMutedWords {
+			return ErrMutedWordLimit
+		}
+
+		query = `
+			INSERT INTO muted_words (user_id, kind, pattern, scopes, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			word.UserID,
+			word.Kind,
+			word.Pattern,
+			pq.Array(word.Scopes),
+			word.ExpiresAt,
+		).Scan(&word.ID, &word.CreatedAt)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+
+			return err
+		}
+
+		return nil
+	})
+}
+
+// GetByUserID returns the user's unexpired muted words, newest first.
+func (s *MutedWordStore) GetByUserID(ctx context.Context, userID int64) ([]*MutedWord, error) {
+	query := `
+		SELECT id, user_id, kind, pattern, scopes, expires_at, created_at
+		FROM muted_words
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := []*MutedWord{}
+
+	for rows.Next() {
+		w, err := scanMutedWord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		words = append(words, w)
+	}
+
+	return words, rows.Err()
+}
+
+// GetByUserIDs returns the unexpired muted words of each of the users,
+// newest first.
+func (s *MutedWordStore) GetByUserIDs(ctx context.Context, userIDs []int64) (map[int64][]*MutedWord, error) {
+	words := make(map[int64][]*MutedWord)
+	if len(userIDs) == 0 {
+		return words, nil
+	}
+
+	query := `
+		SELECT id, user_id, kind, pattern, scopes, expires_at, created_at
+		FROM muted_words
+		WHERE user_id = ANY($1) AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		w, err := scanMutedWord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		words[w.UserID] = append(words[w.UserID], w)
+	}
+
+	return words, rows.Err()
+}
+
+func scanMutedWord(row rowScanner) (*MutedWord, error) {
+	w := &MutedWord{}
+	var expiresAt sql.NullString
+
+	err := row.Scan(
+		&w.ID,
+		&w.UserID,
+		&w.Kind,
+		&w.Pattern,
+		pq.Array(&w.Scopes),
+		&expiresAt,
+		&w.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		w.ExpiresAt = &expiresAt.String
+	}
+
+	return w, nil
+}
+
+func (s *MutedWordStore) Delete(ctx context.Context, userID, wordID int64) error {
+	query := `DELETE FROM muted_words WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, wordID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	NotificationFollow   = "follow"
+	NotificationMention  = "mention"
+	NotificationComment  = "comment"
+	NotificationReply    = "reply"
+	NotificationReaction = "reaction"
+	NotificationRepost   = "repost"
+	NotificationQuote    = "quote"
+)
+
+// maxNotificationActors is how many of the users behind a grouped
+// notification are listed.
+const maxNotificationActors = 3
+
+// Notification tells a user what others did. Similar notifications from the
+// same day, like reactions to the same post, are grouped into one that
+// lists the latest actors and counts them all.
+type Notification struct {
+	// ID is the ID of the latest notification of the group
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	PostID    *int64 `json:"post_id,omitempty"`
+	CommentID *int64 `json:"comment_id,omitempty"`
+	Actors    []User `json:"actors"`
+	// ActorCount is how many users are behind the notification
+	ActorCount int    `json:"actor_count"`
+	Read       bool   `json:"read"`
+	CreatedAt  string `json:"created_at"`
+	// UserID is who is notified and ActorID who did it, when creating one
+	UserID  int64 `json:"-"`
+	ActorID int64 `json:"-"`
+}
+
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// groupKey returns the key notifications of type about postID are grouped
+// by when created at, empty for the types shown on their own.
+func groupKey(kind string, postID *int64, at time.Time) string {
+	switch kind {
+	case NotificationFollow, NotificationComment, NotificationReaction, NotificationRepost:
+	default:
+		return ""
+	}
+
+	var post int64
+	if postID != nil {
+		post = *postID
+	}
+
+	return fmt.Sprintf("%s:%d:%s", kind, post, at.UTC().Format(time.DateOnly))
+}
+
+// Create notifies n.UserID of what n.ActorID did. Users aren't notified of
+// what they did themselves, by users they blocked or were blocked by, or
+// twice of the same actor in a group. n.ID is left at zero when the user
+// wasn't notified.
+func (s *NotificationStore) Create(ctx context.Context, n *Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id, group_key)
+		SELECT $1::bigint, $2::bigint, $3, $4::bigint, $5::bigint, NULLIF($6, '')
+		WHERE $1::bigint <> $2::bigint AND NOT ` + blockedBetween("$1::bigint", "$2::bigint") + `
+		ON CONFLICT (user_id, group_key, actor_id) WHERE group_key IS NOT NULL DO NOTHING
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		n.UserID,
+		n.ActorID,
+		n.Type,
+		n.PostID,
+		n.CommentID,
+		groupKey(n.Type, n.PostID, time.Now()),
+	).Scan(&n.ID, &n.CreatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// notificationGroup is what makes notifications one when listing them.
+const notificationGroup = `COALESCE(n.group_key, n.id::text)`
+
+// GetByUserID returns a page of the user's grouped notifications, newest
+// first. Groups are placed by their latest notification, so one moves to
+// the front when it grows. Notifications from users blocked since are left
+// out.
+func (s *NotificationStore) GetByUserID(ctx context.Context, userID int64, nq NotificationQuery) ([]*Notification, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", nq.Cursor)
+
+	query := `
+		SELECT
+			MAX(n.id), MIN(n.type), MIN(n.post_id),
+			(array_agg(n.comment_id ORDER BY n.id DESC))[1],
+			(array_agg(n.actor_id ORDER BY n.id DESC))[1:$4],
+			COUNT(DISTINCT n.actor_id),
+			bool_and(n.read_at IS NOT NULL),
+			MAX(n.created_at)
+		FROM notifications n
+		WHERE n.user_id = $1 AND NOT ` + blockedBetween("n.actor_id", "$1") + `
+		GROUP BY ` + notificationGroup + `
+		HAVING
+			($2::timestamptz IS NULL OR (MAX(n.created_at), MAX(n.id)) ` + cmp + ` ($2, $6::bigint)) AND
+			(NOT $3 OR bool_or(n.read_at IS NULL))
+		ORDER BY MAX(n.created_at) ` + order + `, MAX(n.id) ` + order + `
+		LIMIT $5
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, cursorAt, nq.Unread, maxNotificationActors, nq.Limit, cursorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	actorIDs := make(map[*Notification][]int64)
+	var allActors []int64
+
+	for rows.Next() {
+		n := &Notification{}
+		var actors []int64
+
+		err := rows.Scan(
+			&n.ID,
+			&n.Type,
+			&n.PostID,
+			&n.CommentID,
+			pq.Array(&actors),
+			&n.ActorCount,
+			&n.Read,
+			&n.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+		actorIDs[n] = actors
+		allActors = append(allActors, actors...)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if nq.Cursor != nil && nq.Cursor.Backward {
+		slices.Reverse(notifications)
+	}
+
+	if len(allActors) == 0 {
+		return notifications, nil
+	}
+
+	usernames, err := s.usernames(ctx, allActors)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range notifications {
+		for _, id := range actorIDs[n] {
+			n.Actors = append(n.Actors, User{ID: id, Username: usernames[id]})
+		}
+	}
+
+	return notifications, nil
+}
+
+func (s *NotificationStore) usernames(ctx context.Context, userIDs []int64) (map[int64]string, error) {
+	query := `SELECT id, username FROM users WHERE id = ANY($1)`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usernames := make(map[int64]string)
+
+	for rows.Next() {
+		var id int64
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+
+		usernames[id] = username
+	}
+
+	return usernames, rows.Err()
+}
+
+// CountUnread returns how many of the user's grouped notifications have
+// unread notifications.
+func (s *NotificationStore) CountUnread(ctx context.Context, userID int64) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT ` + notificationGroup + `)
+		FROM notifications n
+		WHERE n.user_id = $1 AND n.read_at IS NULL AND NOT ` + blockedBetween("n.actor_id", "$1") + `
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
+
+// MarkRead marks the group of the user's notification with the given ID as
+// read.
+func (s *NotificationStore) MarkRead(ctx context.Context, userID, notificationID int64) error {
+	query := `
+		WITH target AS (
+			SELECT ` + notificationGroup + ` AS key
+			FROM notifications n
+			WHERE n.id = $2 AND n.user_id = $1
+		)
+		UPDATE notifications n
+		SET read_at = NOW()
+		FROM target
+		WHERE n.user_id = $1 AND ` + notificationGroup + ` = target.key AND n.read_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM notifications WHERE id = $2 AND user_id = $1)`,
+		userID,
+		notificationID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNotFound
+	}
+
+	_, err = s.db.ExecContext(ctx, query, userID, notificationID)
+	return err
+}
+
+func (s *NotificationStore) MarkAllRead(ctx context.Context, userID int64) error {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -1,32 +1,57 @@
 package store
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/qwerqy/social-api-go/internal/entities"
+	"github.com/qwerqy/social-api-go/internal/search"
 )
 
 
 
 type PaginatedFeedQuery struct {
-	Limit int `json:"limit" validate:"lte=20"`
+	Limit int `json:"limit" validate:"gte=1,lte=20"`
 	Offset int `json:"offset" validate:"gte=0"`
-	Sort string `json:"sort" validate:"oneof=asc desc"`
+	// Sort is asc or desc for chronological feeds, or ranked
+	Sort string `json:"sort" validate:"oneof=asc desc ranked"`
 	Tags []string `json:"tags" validate:"max=5"`
+	// Search is full-text, in web search syntax: "quoted phrases", or and
+	// -excluded words
 	Search string `json:"search" validate:"max=100"`
 	Since string `json:"since"`
 	Until string `json:"until"`
+	// Cursor continues from a previous page. Offset is only kept for
+	// clients that don't use cursors yet.
+	Cursor *Cursor `json:"-"`
+	// Debug explains how each post of a ranked feed was scored
+	Debug bool `json:"debug"`
+	// Mutes leaves out the posts matching the user's muted words
+	Mutes MuteQuery `json:"-"`
 }
 
-func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
+func (fq PaginatedFeedQuery) Parse(r *http.Request, signer *CursorSigner) (PaginatedFeedQuery, error) {
 	qs := r.URL.Query()
 
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := signer.Decode(cursor)
+		if err != nil {
+			return fq, err
+		}
+
+		fq.Cursor = c
+	}
+
 	limit := qs.Get("limit")
 	if limit != "" {
 		l, err := strconv.Atoi(limit)
 		if err != nil {
-			return fq, nil
+			return fq, errors.New("limit must be a number")
 		}
 
 		fq.Limit = l
@@ -36,12 +61,16 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 	if offset != "" {
 		l, err := strconv.Atoi(offset)
 		if err != nil {
-			return fq, nil
+			return fq, errors.New("offset must be a number")
 		}
 
 		fq.Offset = l
 	}
 
+	if fq.Cursor != nil && fq.Offset != 0 {
+		return fq, errors.New("cursor and offset can't be used together")
+	}
+
 	sort := qs.Get("sort")
 	if sort != "" {
 		fq.Sort = sort
@@ -49,7 +78,7 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	tags := qs.Get("tags")
 	if tags != "" {
-		fq.Tags = strings.Split(tags, ",")
+		fq.Tags = entities.NormalizeTags(strings.Split(tags, ","))
 	}
 
 	search := qs.Get("search")
@@ -59,22 +88,322 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	since := qs.Get("since")
 	if since != "" {
-		fq.Since = parseTime(since)
+		t, err := parseTime(since)
+		if err != nil {
+			return fq, errors.New("since must be a date and time")
+		}
+
+		fq.Since = t
 	}
 
 	until := qs.Get("until")
 	if until != "" {
-		fq.Until = parseTime(until)
+		t, err := parseTime(until)
+		if err != nil {
+			return fq, errors.New("until must be a date and time")
+		}
+
+		fq.Until = t
+	}
+
+	if fq.Since != "" && fq.Until != "" && fq.Since > fq.Until {
+		return fq, errors.New("since must be before until")
+	}
+
+	debug := qs.Get("debug")
+	if debug != "" {
+		d, err := strconv.ParseBool(debug)
+		if err != nil {
+			return fq, errors.New("debug must be true or false")
+		}
+
+		fq.Debug = d
+	}
+
+	// Rankings change between requests, there is no position to continue
+	// from
+	if fq.Sort == "ranked" && fq.Cursor != nil {
+		return fq, errors.New("ranked feeds are paged with offset")
 	}
 
 	return fq, nil
 }
 
-func parseTime(s string) string {
-	t, err := time.Parse(time.DateTime, s)
+// FeedFilters are the parameters of PaginatedFeedQuery that choose which
+// posts a feed has, rather than which page of it is read. Saved searches
+// keep them under a name.
+type FeedFilters struct {
+	Sort   string   `json:"sort,omitempty" validate:"omitempty,oneof=asc desc ranked"`
+	Tags   []string `json:"tags,omitempty" validate:"max=5"`
+	Search string   `json:"search,omitempty" validate:"max=100"`
+	Since  string   `json:"since,omitempty"`
+	Until  string   `json:"until,omitempty"`
+}
+
+// Normalize returns the filters the way Parse would have read them from a
+// request: tags normalised and times in UTC.
+func (f FeedFilters) Normalize() (FeedFilters, error) {
+	if f.Tags != nil {
+		f.Tags = entities.NormalizeTags(f.Tags)
+	}
+
+	if f.Since != "" {
+		t, err := parseTime(f.Since)
+		if err != nil {
+			return f, errors.New("since must be a date and time")
+		}
+
+		f.Since = t
+	}
+
+	if f.Until != "" {
+		t, err := parseTime(f.Until)
+		if err != nil {
+			return f, errors.New("until must be a date and time")
+		}
+
+		f.Until = t
+	}
+
+	if f.Since != "" && f.Until != "" && f.Since > f.Until {
+		return f, errors.New("since must be before until")
+	}
+
+	return f, nil
+}
+
+// Apply sets the filters on fq. Applied before Parse, they are defaults the
+// request's own parameters override.
+func (f FeedFilters) Apply(fq PaginatedFeedQuery) PaginatedFeedQuery {
+	if f.Sort != "" {
+		fq.Sort = f.Sort
+	}
+
+	fq.Tags = f.Tags
+	fq.Search = f.Search
+	fq.Since = f.Since
+	fq.Until = f.Until
+
+	return fq
+}
+
+// KeysetPaginatedQuery pages through a list ordered by creation time with
+// signed cursors. A nil cursor starts from the beginning.
+type KeysetPaginatedQuery struct {
+	Limit  int     `json:"limit" validate:"gte=1,lte=50"`
+	Cursor *Cursor `json:"-"`
+}
+
+func (kq KeysetPaginatedQuery) Parse(r *http.Request, signer *CursorSigner) (KeysetPaginatedQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return kq, errors.New("limit must be a number")
+		}
+
+		kq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		c, err := signer.Decode(cursor)
+		if err != nil {
+			return kq, err
+		}
+
+		kq.Cursor = c
+	}
+
+	return kq, nil
+}
+
+// BookmarkQuery filters a user's bookmarks the same way PaginatedFeedQuery
+// filters the feed, optionally narrowed down to a single collection.
+type BookmarkQuery struct {
+	KeysetPaginatedQuery
+	Tags       []string `json:"tags" validate:"max=5"`
+	Search     string   `json:"search" validate:"max=100"`
+	Collection string   `json:"collection" validate:"max=100"`
+}
+
+func (bq BookmarkQuery) Parse(r *http.Request, signer *CursorSigner) (BookmarkQuery, error) {
+	kq, err := bq.KeysetPaginatedQuery.Parse(r, signer)
+	if err != nil {
+		return bq, err
+	}
+
+	bq.KeysetPaginatedQuery = kq
+
+	qs := r.URL.Query()
+
+	tags := qs.Get("tags")
+	if tags != "" {
+		bq.Tags = entities.NormalizeTags(strings.Split(tags, ","))
+	}
+
+	bq.Search = qs.Get("search")
+	bq.Collection = qs.Get("collection")
+
+	return bq, nil
+}
+
+// NotificationQuery pages through a user's notifications, optionally only
+// the unread ones.
+type NotificationQuery struct {
+	KeysetPaginatedQuery
+	Unread bool `json:"unread"`
+}
+
+func (nq NotificationQuery) Parse(r *http.Request, signer *CursorSigner) (NotificationQuery, error) {
+	kq, err := nq.KeysetPaginatedQuery.Parse(r, signer)
+	if err != nil {
+		return nq, err
+	}
+
+	nq.KeysetPaginatedQuery = kq
+
+	unread := r.URL.Query().Get("unread")
+	if unread != "" {
+		u, err := strconv.ParseBool(unread)
+		if err != nil {
+			return nq, errors.New("unread must be true or false")
+		}
+
+		nq.Unread = u
+	}
+
+	return nq, nil
+}
+
+// UserPostsQuery filters the posts on a user's profile. Tags and Search
+// work as in PaginatedFeedQuery.
+type UserPostsQuery struct {
+	KeysetPaginatedQuery
+	Tags   []string `json:"tags" validate:"max=5"`
+	Search string   `json:"search" validate:"max=100"`
+	// ExcludeReplies leaves out posts that start by mentioning someone
+	ExcludeReplies bool `json:"exclude_replies"`
+	ExcludeReposts bool `json:"exclude_reposts"`
+	// OnlyMedia keeps the posts with media attached
+	OnlyMedia bool `json:"only_media"`
+}
+
+func (uq UserPostsQuery) Parse(r *http.Request, signer *CursorSigner) (UserPostsQuery, error) {
+	kq, err := uq.KeysetPaginatedQuery.Parse(r, signer)
 	if err != nil {
-		return ""
+		return uq, err
+	}
+
+	uq.KeysetPaginatedQuery = kq
+
+	qs := r.URL.Query()
+
+	tags := qs.Get("tags")
+	if tags != "" {
+		uq.Tags = entities.NormalizeTags(strings.Split(tags, ","))
+	}
+
+	uq.Search = qs.Get("search")
+
+	for name, flag := range map[string]*bool{
+		"exclude_replies": &uq.ExcludeReplies,
+		"exclude_reposts": &uq.ExcludeReposts,
+		"only_media":      &uq.OnlyMedia,
+	} {
+		v := qs.Get(name)
+		if v == "" {
+			continue
+		}
+
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return uq, fmt.Errorf("%s must be true or false", name)
+		}
+
+		*flag = b
+	}
+
+	return uq, nil
+}
+
+// maxSearchFilters caps the from: and tag: operators of a search.
+const maxSearchFilters = 5
+
+// MaxSearchOffset is the furthest into search results a page may start.
+const MaxSearchOffset = 500
+
+// SearchQuery searches posts or comments with the syntax of the search
+// package. Results are ranked, so they are paged with offsets.
+type SearchQuery struct {
+	Q string `json:"q" validate:"required,max=200"`
+	// Type is what to search, posts or comments
+	Type   string       `json:"type" validate:"oneof=posts comments"`
+	Limit  int          `json:"limit" validate:"gte=1,lte=20"`
+	Offset int          `json:"offset" validate:"gte=0,lte=500"`
+	Terms  search.Query `json:"-"`
+}
+
+func (sq SearchQuery) Parse(r *http.Request) (SearchQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return sq, errors.New("limit must be a number")
+		}
+
+		sq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return sq, errors.New("offset must be a number")
+		}
+
+		sq.Offset = o
+	}
+
+	if t := qs.Get("type"); t != "" {
+		sq.Type = t
+	}
+
+	// A missing query is reported by validation
+	sq.Q = strings.TrimSpace(qs.Get("q"))
+	if sq.Q == "" {
+		return sq, nil
+	}
+
+	terms, err := search.Parse(sq.Q)
+	if err != nil {
+		return sq, err
+	}
+
+	if len(terms.From) > maxSearchFilters || len(terms.Tags) > maxSearchFilters {
+		return sq, fmt.Errorf("a search can have up to %d from: and %d tag: filters", maxSearchFilters, maxSearchFilters)
+	}
+
+	sq.Terms = terms
+
+	return sq, nil
+}
+
+// parseTime accepts RFC 3339 timestamps, or date and times without a zone
+// taken as UTC. They are returned in UTC as RFC 3339, which sorts by time
+// and Postgres reads without guessing the zone.
+func parseTime(s string) (string, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateTime, s)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	return t.Format(time.DateTime)
+	return t.UTC().Format(time.RFC3339), nil
 }
\ No newline at end of file
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "2024-05-01T12:30:00Z", want: "2024-05-01T12:30:00Z"},
+		{in: "2024-05-01T14:30:00+02:00", want: "2024-05-01T12:30:00Z"},
+		{in: "2024-05-01T12:30:00.123456Z", want: "2024-05-01T12:30:00Z"},
+		{in: "2024-05-01 12:30:00", want: "2024-05-01T12:30:00Z"},
+		{in: "2024-05-01", wantErr: true},
+		{in: "2024-05-01T12:30:00", wantErr: true},
+		{in: "2024-13-01 12:30:00", wantErr: true},
+		{in: "yesterday", wantErr: true},
+		{in: "1714566600", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTime(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestPaginatedFeedQueryParse(t *testing.T) {
+	signer := NewCursorSigner("secret")
+	cursor := signer.Encode(Cursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), ID: 42})
+	defaults := PaginatedFeedQuery{Limit: 20, Sort: "desc"}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    PaginatedFeedQuery
+		wantErr bool
+	}{
+		{name: "defaults", query: "", want: defaults},
+		{
+			name:  "filters",
+			query: "limit=5&sort=asc&tags=Go,%23go,rust&search=gophers",
+			want:  PaginatedFeedQuery{Limit: 5, Sort: "asc", Tags: []string{"go", "rust"}, Search: "gophers"},
+		},
+		{
+			name:  "RFC 3339 and date time",
+			query: "since=2024-05-01T14:30:00%2B02:00&until=2024-05-02+08:00:00",
+			want:  PaginatedFeedQuery{Limit: 20, Sort: "desc", Since: "2024-05-01T12:30:00Z", Until: "2024-05-02T08:00:00Z"},
+		},
+		{
+			name:  "since equal to until",
+			query: "since=2024-05-01T12:30:00Z&until=2024-05-01+12:30:00",
+			want:  PaginatedFeedQuery{Limit: 20, Sort: "desc", Since: "2024-05-01T12:30:00Z", Until: "2024-05-01T12:30:00Z"},
+		},
+		// Compared in UTC, 13:00 at +02:00 is before 12:30 at Z
+		{
+			name:  "ranked in debug mode",
+			query: "sort=ranked&debug=true&offset=20",
+			want:  PaginatedFeedQuery{Limit: 20, Offset: 20, Sort: "ranked", Debug: true},
+		},
+		{name: "since after until", query: "since=2024-05-01T12:30:00Z&until=2024-05-01T13:00:00%2B02:00", wantErr: true},
+		{name: "since after until across formats", query: "since=2024-05-02+00:00:00&until=2024-05-01T23:59:59Z", wantErr: true},
+		{name: "malformed since", query: "since=yesterday", wantErr: true},
+		{name: "malformed until", query: "until=2024-05-01", wantErr: true},
+		{name: "malformed limit", query: "limit=ten", wantErr: true},
+		{name: "malformed offset", query: "offset=-x", wantErr: true},
+		{name: "malformed debug", query: "debug=maybe", wantErr: true},
+		{name: "malformed cursor", query: "cursor=abc", wantErr: true},
+		{name: "cursor and offset", query: "cursor=" + cursor + "&offset=20", wantErr: true},
+		{name: "ranked with a cursor", query: "sort=ranked&cursor=" + cursor, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/users/feed?"+tt.query, nil)
+
+			got, err := defaults.Parse(r, signer)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Limit != tt.want.Limit || got.Offset != tt.want.Offset || got.Sort != tt.want.Sort ||
+				got.Search != tt.want.Search || got.Since != tt.want.Since || got.Until != tt.want.Until ||
+				!slices.Equal(got.Tags, tt.want.Tags) || got.Debug != tt.want.Debug {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedFiltersNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters FeedFilters
+		want    FeedFilters
+		wantErr bool
+	}{
+		{name: "empty", filters: FeedFilters{}, want: FeedFilters{}},
+		{
+			name:    "tags and times",
+			filters: FeedFilters{Tags: []string{"#Go", "go", " Rust "}, Since: "2024-05-01T14:30:00+02:00", Until: "2024-05-02 08:00:00"},
+			want:    FeedFilters{Tags: []string{"go", "rust"}, Since: "2024-05-01T12:30:00Z", Until: "2024-05-02T08:00:00Z"},
+		},
+		{name: "malformed since", filters: FeedFilters{Since: "yesterday"}, wantErr: true},
+		{name: "malformed until", filters: FeedFilters{Until: "2024-05-01"}, wantErr: true},
+		{name: "since after until", filters: FeedFilters{Since: "2024-05-02 00:00:00", Until: "2024-05-01T23:59:59Z"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filters.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v", err)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !slices.Equal(got.Tags, tt.want.Tags) || got.Since != tt.want.Since || got.Until != tt.want.Until {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A saved search is applied to the defaults, then the request's own
+// parameters are parsed over it.
+func TestFeedFiltersApply(t *testing.T) {
+	signer := NewCursorSigner("secret")
+	defaults := PaginatedFeedQuery{Limit: 20, Sort: "desc"}
+	saved := FeedFilters{
+		Sort:   "asc",
+		Tags:   []string{"go"},
+		Search: "generics",
+		Since:  "2024-05-01T00:00:00Z",
+		Until:  "2024-06-01T00:00:00Z",
+	}
+
+	tests := []struct {
+		name    string
+		filters FeedFilters
+		query   string
+		want    PaginatedFeedQuery
+		wantErr bool
+	}{
+		{
+			name:    "saved search alone",
+			filters: saved,
+			want:    PaginatedFeedQuery{Limit: 20, Sort: "asc", Tags: []string{"go"}, Search: "generics", Since: saved.Since, Until: saved.Until},
+		},
+		{
+			name:    "request overrides",
+			filters: saved,
+			query:   "limit=5&sort=desc&tags=rust&search=lifetimes&until=2024-05-15+00:00:00",
+			want:    PaginatedFeedQuery{Limit: 5, Sort: "desc", Tags: []string{"rust"}, Search: "lifetimes", Since: saved.Since, Until: "2024-05-15T00:00:00Z"},
+		},
+		{
+			name:    "saved search without a sort",
+			filters: FeedFilters{Tags: []string{"go"}},
+			query:   "",
+			want:    PaginatedFeedQuery{Limit: 20, Sort: "desc", Tags: []string{"go"}},
+		},
+		{
+			name:    "request until before the saved since",
+			filters: saved,
+			query:   "until=2024-04-01T00:00:00Z",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/users/feed?"+tt.query, nil)
+
+			got, err := tt.filters.Apply(defaults).Parse(r, signer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v", err)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got.Limit != tt.want.Limit || got.Sort != tt.want.Sort || got.Search != tt.want.Search ||
+				got.Since != tt.want.Since || got.Until != tt.want.Until || !slices.Equal(got.Tags, tt.want.Tags) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
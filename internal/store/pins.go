@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// MaxPinnedPosts is how many posts a user can pin to their profile.
+const MaxPinnedPosts = 3
+
+var (
+	ErrPinLimit    = fmt.Errorf("at most %d posts can be pinned", MaxPinnedPosts)
+	ErrInvalidPins = errors.New("pinned posts must be given exactly once each")
+)
+
+type PinStore struct {
+	db *sql.DB
+}
+
+// Pin pins a post to its author's profile after the posts already pinned.
+// Pinning a pinned post does nothing.
+func (s *PinStore) Pin(ctx context.Context, userID, postID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Lock the user so two pins at once can't both fit under the limit
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return err
+		}
+
+		// Unpinning leaves gaps in the positions, so the next one comes after
+		// the last rather than after the count
+		var pinned, alreadyPinned, position int
+		query := `
+			SELECT COUNT(*), COUNT(*) FILTER (WHERE post_id = $2), COALESCE(MAX(position) + 1, 0)
+			FROM pinned_posts
+			WHERE user_id = $1
+		`
+
+		if err := tx.QueryRowContext(ctx, query, userID, postID).Scan(&pinned, &alreadyPinned, &position); err != nil {
+			return err
+		}
+
+		if alreadyPinned > 0 {
+			return nil
+		}
+
+		if pinned >= MaxPinnedPosts {
+			return ErrPinLimit
+		}
+
+		query = `
+			INSERT INTO pinned_posts (user_id, post_id, position)
+			VALUES ($1, $2, $3)
+		`
+
+		_, err := tx.ExecContext(ctx, query, userID, postID, position)
+		return err
+	})
+}
+
+func (s *PinStore) Unpin(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM pinned_posts WHERE user_id = $1 AND post_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Reorder puts the user's pinned posts in the order given, which must list
+// each of them once.
+func (s *PinStore) Reorder(ctx context.Context, userID int64, postIDs []int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		rows, err := tx.QueryContext(ctx, `SELECT post_id FROM pinned_posts WHERE user_id = $1 FOR UPDATE`, userID)
+		if err != nil {
+			return err
+		}
+
+		var pinned []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			pinned = append(pinned, id)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		given := slices.Clone(postIDs)
+		slices.Sort(given)
+		slices.Sort(pinned)
+
+		if !slices.Equal(given, pinned) {
+			return ErrInvalidPins
+		}
+
+		query := `
+			UPDATE pinned_posts p
+			SET position = ids.ord - 1
+			FROM unnest($2::bigint[]) WITH ORDINALITY AS ids (post_id, ord)
+			WHERE p.user_id = $1 AND p.post_id = ids.post_id
+		`
+
+		_, err = tx.ExecContext(ctx, query, userID, pq.Array(postIDs))
+		return err
+	})
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPollClosed        = errors.New("poll is closed")
+	ErrInvalidPollOption = errors.New("option does not belong to this poll")
+)
+
+type Poll struct {
+	ID             int64         `json:"id"`
+	PostID         int64         `json:"post_id"`
+	MultipleChoice bool          `json:"multiple_choice"`
+	HideResults    bool          `json:"hide_results"`
+	ClosesAt       time.Time     `json:"closes_at"`
+	Closed         bool          `json:"closed"`
+	Options        []*PollOption `json:"options"`
+	// TotalVoters and the option votes are nil while results are hidden
+	TotalVoters *int    `json:"total_voters"`
+	Voted       []int64 `json:"voted"`
+}
+
+type PollOption struct {
+	ID       int64  `json:"id"`
+	Position int    `json:"position"`
+	Text     string `json:"text"`
+	Votes    *int   `json:"votes"`
+}
+
+// HideTallies removes the vote counts so they can't be seen before the poll
+// closes.
+func (p *Poll) HideTallies() {
+	p.TotalVoters = nil
+	for _, o := range p.Options {
+		o.Votes = nil
+	}
+}
+
+type PollStore struct {
+	db *sql.DB
+}
+
+// GetByPostID returns the poll attached to a post with its current tallies,
+// and the options viewerID voted for.
+func (s *PollStore) GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error) {
+	query := `
+		SELECT id, post_id, multiple_choice, hide_results, closes_at, closes_at <= NOW(),
+			(SELECT COUNT(*) FROM poll_ballots b WHERE b.poll_id = polls.id)
+		FROM polls
+		WHERE post_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	poll := &Poll{}
+	var voters int
+
+	err := s.db.QueryRowContext(ctx, query, postID).Scan(
+		&poll.ID,
+		&poll.PostID,
+		&poll.MultipleChoice,
+		&poll.HideResults,
+		&poll.ClosesAt,
+		&poll.Closed,
+		&voters,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	poll.TotalVoters = &voters
+
+	if err := s.getOptions(ctx, poll, viewerID); err != nil {
+		return nil, err
+	}
+
+	return poll, nil
+}
+
+// Vote casts the user's ballot. A second ballot on the same poll is rejected
+// by the database with ErrConflict.
+func (s *PollStore) Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.createBallot(ctx, tx, pollID, userID); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO poll_votes (poll_id, option_id, user_id)
+			SELECT $1, id, $2 FROM poll_options
+			WHERE poll_id = $1 AND id = ANY($3)
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		result, err := tx.ExecContext(ctx, query, pollID, userID, pq.Array(optionIDs))
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected != int64(len(optionIDs)) {
+			return ErrInvalidPollOption
+		}
+
+		return nil
+	})
+}
+
+func (s *PollStore) createBallot(ctx context.Context, tx *sql.Tx, pollID, userID int64) error {
+	query := `
+		INSERT INTO poll_ballots (poll_id, user_id)
+		SELECT id, $2 FROM polls
+		WHERE id = $1 AND closes_at > NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, pollID, userID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrPollClosed
+	}
+
+	return nil
+}
+
+func (s *PollStore) getOptions(ctx context.Context, poll *Poll, viewerID int64) error {
+	query := `
+		SELECT o.id, o.position, o.text, COUNT(v.user_id),
+			COALESCE(BOOL_OR(v.user_id = $2), false)
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE o.poll_id = $1
+		GROUP BY o.id
+		ORDER BY o.position
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, poll.ID, viewerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	poll.Options = []*PollOption{}
+	poll.Voted = []int64{}
+
+	for rows.Next() {
+		o := &PollOption{}
+		var votes int
+		var voted bool
+
+		if err := rows.Scan(&o.ID, &o.Position, &o.Text, &votes, &voted); err != nil {
+			return err
+		}
+
+		o.Votes = &votes
+		poll.Options = append(poll.Options, o)
+
+		if voted {
+			poll.Voted = append(poll.Voted, o.ID)
+		}
+	}
+
+	return rows.Err()
+}
+
+func createPoll(ctx context.Context, tx *sql.Tx, poll *Poll) error {
+	query := `
+		INSERT INTO polls (post_id, multiple_choice, hide_results, closes_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		poll.PostID,
+		poll.MultipleChoice,
+		poll.HideResults,
+		poll.ClosesAt,
+	).Scan(&poll.ID)
+	if err != nil {
+		return err
+	}
+
+	optionQuery := `
+		INSERT INTO poll_options (poll_id, position, text)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	voters := 0
+	poll.TotalVoters = &voters
+	poll.Voted = []int64{}
+
+	for i, o := range poll.Options {
+		votes := 0
+		o.Position = i
+		o.Votes = &votes
+
+		if err := tx.QueryRowContext(ctx, optionQuery, poll.ID, o.Position, o.Text).Scan(&o.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/lib/pq"
@@ -12,16 +13,57 @@ import (
 type Post struct {
 	ID int64 `json:"id"`
 	Content string `json:"content"`
+	ContentFormat string `json:"content_format"`
+	ContentHTML string `json:"content_html"`
+	// RenderedVersion is the version ContentHTML was rendered from, -1 when
+	// it hasn't been rendered
+	RenderedVersion int64 `json:"-"`
 	Title string `json:"title"`
 	UserID int64 `json:"user_id"`
 	Tags []string `json:"tags"`
+	// ExplicitTags are the tags given alongside the content, Tags adds the
+	// hashtags found in it
+	ExplicitTags []string `json:"-"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 	Version int64 `json:"version"`
 	Comments []*Comment `json:"comments"`
+	CommentsNextCursor string `json:"comments_next_cursor,omitempty"`
 	User User `json:"user"`
+	Kind string `json:"kind"`
+	Visibility string `json:"visibility"`
+	// ContentWarning is shown in place of the content until the reader
+	// expands the post
+	ContentWarning string `json:"content_warning"`
+	Sensitive bool `json:"sensitive"`
+	// WarnedBy is who set the content warning or sensitive flag, only a
+	// moderator can clear them when it isn't the author
+	WarnedBy *int64 `json:"-"`
+	OriginalID *int64 `json:"original_id,omitempty"`
+	Original *Post `json:"original,omitempty"`
+	Bookmarked bool `json:"bookmarked"`
+	// Pinned is set on the pinned posts at the top of a profile
+	Pinned bool `json:"pinned,omitempty"`
+	Poll *Poll `json:"poll,omitempty"`
+	Media []*Media `json:"media,omitempty"`
+	Entities []Entity `json:"entities,omitempty"`
+	Reactions map[string]int `json:"reactions,omitempty"`
+	Preview *LinkPreview `json:"preview,omitempty"`
+	// MediaIDs are the uploads to attach when the post is created
+	MediaIDs []int64 `json:"-"`
 }
 
+const (
+	ContentFormatPlain    = "plain"
+	ContentFormatMarkdown = "markdown"
+)
+
+const (
+	PostKindPost   = "post"
+	PostKindRepost = "repost"
+	PostKindQuote  = "quote"
+)
+
 type PostWithMetadata struct {
 	Post
 	CommentsCount int `json:"comments_count"`
@@ -31,75 +73,428 @@ type PostStore struct {
 	db *sql.DB
 }
 
-func (s *PostStore) GetUserFeed(ctx context.Context, ID int64, fq PaginatedFeedQuery) ([]*PostWithMetadata, error) {
-	query := `
-		SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, u.username,
-			COUNT(c.id) AS comments_count
-		FROM posts p
+// postListColumns, postListJoins and postListVisible make up queries listing
+// posts to the viewer bound to $1: each post with its author, the post it
+// shares, its comment count and whether the viewer bookmarked it, or the
+// post it reposts. Such queries group by postListGroupBy and
+// are read with queryPostList.
+var (
+	postListColumns = `
+		p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags, u.username,
+		p.kind, p.visibility, p.original_id,
+		p.content_format, COALESCE(p.content_html, ''), COALESCE(p.content_html_version, -1),
+		p.content_warning, p.sensitive,
+		o.user_id, o.title, o.content, o.created_at, o.version, o.tags, o.visibility, ou.username,
+		o.content_format, o.content_html, o.content_html_version,
+		o.content_warning, o.sensitive,
+		EXISTS (
+			SELECT 1 FROM bookmarks b
+			WHERE b.post_id = CASE WHEN p.kind = 'repost' THEN p.original_id ELSE p.id END AND b.user_id = $1
+		) AS bookmarked,
+		COUNT(c.id) AS comments_count
+	`
+	postListJoins = `
 		LEFT JOIN comments c ON c.post_id = p.id
 		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
+		LEFT JOIN posts o ON o.id = p.original_id AND ` + visibleTo("o", "$1") + `
+		LEFT JOIN users ou ON o.user_id = ou.id
+	`
+	// Reposts whose original has been deleted, or can't be seen by the
+	// viewer, have nothing left to show, so they are left out. Quotes keep
+	// their own content and lose the embed.
+	postListVisible = visibleTo("p", "$1") + ` AND NOT (p.kind = 'repost' AND o.id IS NULL)`
+)
+
+const postListGroupBy = `p.id, u.username, o.id, ou.username`
+
+// GetUserFeed lists the posts of the user and of the users they follow
+// that they can see, within the Since and Until window when set. Pages
+// continue from the cursor when there is one, and skip Offset posts
+// otherwise.
+func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]*PostWithMetadata, error) {
+	authors := `(p.user_id = $1 OR EXISTS (
+		SELECT 1 FROM followers f
+		WHERE f.user_id = p.user_id AND f.follower_id = $1 AND NOT f.pending
+	))`
+
+	return s.getFeed(ctx, userID, authors, fq)
+}
+
+// GetListFeed lists the posts of the members of a list that the viewer can
+// see, filtered and paged like GetUserFeed.
+func (s *PostStore) GetListFeed(ctx context.Context, listID, viewerID int64, fq PaginatedFeedQuery) ([]*PostWithMetadata, error) {
+	authors := `EXISTS (
+		SELECT 1 FROM list_members lm
+		WHERE lm.list_id = $12 AND lm.user_id = p.user_id
+	)`
+
+	return s.getFeed(ctx, viewerID, authors, fq, listID)
+}
+
+// getFeed lists the posts the viewer can see whose authors pass the SQL
+// condition authors, which may use args from $12 on.
+func (s *PostStore) getFeed(ctx context.Context, viewerID int64, authors string, fq PaginatedFeedQuery, args ...any) ([]*PostWithMetadata, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs(fq.Sort, fq.Cursor)
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		` + postListJoins + `
 		WHERE 
-			f.user_id = $1 AND
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `	
+			` + authors + ` AND
+			` + postListVisible + ` AND
+			` + textMatch("$4", "p.search_vector", "o.search_vector") + ` AND
+			(p.tags @> $5 OR o.tags @> $5 OR $5 = '{}') AND
+			p.created_at >= COALESCE(NULLIF($6, '')::timestamptz, '-infinity') AND
+			p.created_at <= COALESCE(NULLIF($7, '')::timestamptz, 'infinity') AND
+			($8::timestamptz IS NULL OR (p.created_at, p.id) ` + cmp + ` ($8, $9::bigint)) AND
+			` + notMuted("p", "$1", "$10", "$11") + ` AND
+			` + notMuted("o", "$1", "$10", "$11") + `
+		GROUP BY ` + postListGroupBy + `
+		ORDER BY p.created_at ` + order + `, p.id ` + order + `
 		LIMIT $2 OFFSET $3
 	`
 
+	posts, err := s.queryPostList(
+		ctx,
+		query,
+		append([]any{
+			viewerID,
+			fq.Limit,
+			fq.Offset,
+			fq.Search,
+			pq.Array(fq.Tags),
+			fq.Since,
+			fq.Until,
+			cursorAt,
+			cursorID,
+			fq.Mutes.Words,
+			pq.Array(fq.Mutes.Tags),
+		}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if fq.Cursor != nil && fq.Cursor.Backward {
+		slices.Reverse(posts)
+	}
+
+	return posts, nil
+}
+
+// GetByTag lists the posts with a tag that the viewer can see, newest first.
+func (s *PostStore) GetByTag(ctx context.Context, tag string, viewerID int64, kq KeysetPaginatedQuery) ([]*PostWithMetadata, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", kq.Cursor)
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		` + postListJoins + `
+		WHERE
+			p.tags @> ARRAY[$2]::varchar[] AND
+			($3::timestamptz IS NULL OR (p.created_at, p.id) ` + cmp + ` ($3, $4::bigint)) AND
+			` + postListVisible + `
+		GROUP BY ` + postListGroupBy + `
+		ORDER BY p.created_at ` + order + `, p.id ` + order + `
+		LIMIT $5
+	`
+
+	posts, err := s.queryPostList(ctx, query, viewerID, tag, cursorAt, cursorID, kq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if kq.Cursor != nil && kq.Cursor.Backward {
+		slices.Reverse(posts)
+	}
+
+	return posts, nil
+}
+
+// GetVisibleByIDs returns the posts with the given IDs that the viewer can
+// see and that mq doesn't leave out, in no particular order.
+func (s *PostStore) GetVisibleByIDs(ctx context.Context, IDs []int64, viewerID int64, mq MuteQuery) ([]*PostWithMetadata, error) {
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		` + postListJoins + `
+		WHERE
+			p.id = ANY($2) AND
+			` + postListVisible + ` AND
+			` + notMuted("p", "$1", "$3", "$4") + ` AND
+			` + notMuted("o", "$1", "$3", "$4") + `
+		GROUP BY ` + postListGroupBy + `
+	`
+
+	return s.queryPostList(ctx, query, viewerID, pq.Array(IDs), mq.Words, pq.Array(mq.Tags))
+}
+
+// GetByUserID lists the posts of a user that the viewer can see, newest
+// first. Pinned posts are listed in their place too, callers putting them
+// first with GetPinned have to leave them out of the page.
+func (s *PostStore) GetByUserID(ctx context.Context, userID, viewerID int64, uq UserPostsQuery) ([]*PostWithMetadata, error) {
+	order, cmp, cursorAt, cursorID := keysetArgs("desc", uq.Cursor)
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		` + postListJoins + `
+		WHERE
+			p.user_id = $2 AND
+			($3::timestamptz IS NULL OR (p.created_at, p.id) ` + cmp + ` ($3, $4::bigint)) AND
+			` + postListVisible + ` AND
+			` + textMatch("$6", "p.search_vector", "o.search_vector") + ` AND
+			(p.tags @> $7 OR o.tags @> $7 OR $7 = '{}') AND
+			NOT ($8 AND EXISTS (SELECT 1 FROM mentions m WHERE m.post_id = p.id AND m.position = 0)) AND
+			NOT ($9 AND p.kind = 'repost') AND
+			NOT ($10 AND NOT EXISTS (
+				SELECT 1 FROM media md
+				WHERE md.post_id = CASE WHEN p.kind = 'repost' THEN o.id ELSE p.id END
+			))
+		GROUP BY ` + postListGroupBy + `
+		ORDER BY p.created_at ` + order + `, p.id ` + order + `
+		LIMIT $5
+	`
+
+	posts, err := s.queryPostList(
+		ctx,
+		query,
+		viewerID,
+		userID,
+		cursorAt,
+		cursorID,
+		uq.Limit,
+		uq.Search,
+		pq.Array(uq.Tags),
+		uq.ExcludeReplies,
+		uq.ExcludeReposts,
+		uq.OnlyMedia,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if uq.Cursor != nil && uq.Cursor.Backward {
+		slices.Reverse(posts)
+	}
+
+	return posts, nil
+}
+
+// GetPinned returns the posts a user pinned that the viewer can see, in the
+// order the user put them in.
+func (s *PostStore) GetPinned(ctx context.Context, userID, viewerID int64) ([]*PostWithMetadata, error) {
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		JOIN pinned_posts pp ON pp.post_id = p.id AND pp.user_id = $2
+		` + postListJoins + `
+		WHERE ` + postListVisible + `
+		GROUP BY ` + postListGroupBy + `, pp.position
+		ORDER BY pp.position
+	`
+
+	posts, err := s.queryPostList(ctx, query, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range posts {
+		p.Pinned = true
+	}
+
+	return posts, nil
+}
+
+func (s *PostStore) queryPostList(ctx context.Context, query string, args ...any) ([]*PostWithMetadata, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, ID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var feed []*PostWithMetadata
+	posts := []*PostWithMetadata{}
 
 	for rows.Next() {
 		var p PostWithMetadata
-		err := rows.Scan(
-			&p.ID,
-			&p.UserID,
-			&p.Title,
-			&p.Content,
-			&p.CreatedAt,
-			&p.Version,
-			pq.Array(&p.Tags),
-			&p.User.Username,
-			&p.CommentsCount,
-		)
-
-		if err != nil {
+		if err := scanPostListRow(rows, &p); err != nil {
 			return nil, err
 		}
 
-		feed = append(feed, &p)
+		posts = append(posts, &p)
+	}
+
+	return posts, rows.Err()
+}
+
+// scanPostListRow reads the postListColumns of a row into p.
+func scanPostListRow(row rowScanner, p *PostWithMetadata) error {
+	var original nullablePost
+
+	err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.Title,
+		&p.Content,
+		&p.CreatedAt,
+		&p.Version,
+		pq.Array(&p.Tags),
+		&p.User.Username,
+		&p.Kind,
+		&p.Visibility,
+		&p.OriginalID,
+		&p.ContentFormat,
+		&p.ContentHTML,
+		&p.RenderedVersion,
+		&p.ContentWarning,
+		&p.Sensitive,
+		&original.UserID,
+		&original.Title,
+		&original.Content,
+		&original.CreatedAt,
+		&original.Version,
+		pq.Array(&original.Tags),
+		&original.Visibility,
+		&original.Username,
+		&original.ContentFormat,
+		&original.ContentHTML,
+		&original.RenderedVersion,
+		&original.ContentWarning,
+		&original.Sensitive,
+		&p.Bookmarked,
+		&p.CommentsCount,
+	)
+	if err != nil {
+		return err
+	}
+
+	p.User.ID = p.UserID
+
+	if p.OriginalID != nil && original.UserID.Valid {
+		p.Original = original.toPost(*p.OriginalID)
+	}
+
+	return nil
+}
+
+// nullablePost holds the columns of a LEFT JOINed post, which are all NULL
+// when there is nothing to join against.
+type nullablePost struct {
+	UserID     sql.NullInt64
+	Title      sql.NullString
+	Content    sql.NullString
+	CreatedAt  sql.NullString
+	Version    sql.NullInt64
+	Tags       []string
+	Visibility sql.NullString
+	Username   sql.NullString
+
+	ContentFormat   sql.NullString
+	ContentHTML     sql.NullString
+	RenderedVersion sql.NullInt64
+
+	ContentWarning sql.NullString
+	Sensitive      sql.NullBool
+}
+
+func (n nullablePost) toPost(ID int64) *Post {
+	renderedVersion := int64(-1)
+	if n.RenderedVersion.Valid {
+		renderedVersion = n.RenderedVersion.Int64
 	}
 
-	return feed, nil
+	return &Post{
+		ID:         ID,
+		UserID:     n.UserID.Int64,
+		Title:      n.Title.String,
+		Content:    n.Content.String,
+		CreatedAt:  n.CreatedAt.String,
+		Version:    n.Version.Int64,
+		Tags:       n.Tags,
+		Kind:       PostKindPost,
+		Visibility: n.Visibility.String,
+		ContentFormat:   n.ContentFormat.String,
+		ContentHTML:     n.ContentHTML.String,
+		RenderedVersion: renderedVersion,
+		ContentWarning:  n.ContentWarning.String,
+		Sensitive:       n.Sensitive.Bool,
+		User: User{
+			ID:       n.UserID.Int64,
+			Username: n.Username.String,
+		},
+	}
 }
 
 func (s *PostStore) Create(ctx context.Context, post *Post) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.create(ctx, tx, post); err != nil {
+			return err
+		}
+
+		if err := updateTagCounts(ctx, tx, post.Tags, nil); err != nil {
+			return err
+		}
+
+		if post.Poll != nil {
+			post.Poll.PostID = post.ID
+			if err := createPoll(ctx, tx, post.Poll); err != nil {
+				return err
+			}
+		}
+
+		if len(post.MediaIDs) > 0 {
+			if err := attachMedia(ctx, tx, post); err != nil {
+				return err
+			}
+		}
+
+		return saveMentions(ctx, tx, post.UserID, &post.ID, nil, &post.Entities)
+	})
+}
+
+func (s *PostStore) create(ctx context.Context, tx *sql.Tx, post *Post) error {
 	query := `
-		INSERT INTO posts (content, title, user_id, tags)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+		INSERT INTO posts (content, title, user_id, tags, explicit_tags, kind, original_id, visibility, content_format, content_warning, sensitive, content_warning_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	if post.Kind == "" {
+		post.Kind = PostKindPost
+	}
+
+	if post.Visibility == "" {
+		post.Visibility = VisibilityPublic
+	}
+
+	if post.ContentFormat == "" {
+		post.ContentFormat = ContentFormatPlain
+	}
+
+	post.RenderedVersion = -1
+
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		post.Content, 
 		post.Title, 
 		post.UserID, 
 		pq.Array(post.Tags),
+		pq.Array(post.ExplicitTags),
+		post.Kind,
+		post.OriginalID,
+		post.Visibility,
+		post.ContentFormat,
+		post.ContentWarning,
+		post.Sensitive,
+		post.WarnedBy,
 	).Scan(
 		&post.ID,
 		&post.CreatedAt,
@@ -107,6 +502,10 @@ func (s *PostStore) Create(ctx context.Context, post *Post) error {
 	)
 
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrConflict
+		}
+
 		return err
 	}
 
@@ -114,27 +513,48 @@ func (s *PostStore) Create(ctx context.Context, post *Post) error {
 }
 
 func (s *PostStore) GetByID(ctx context.Context, ID int64) (*Post, error) {
+	return s.get(ctx, "id = $1", ID)
+}
+
+// GetVisibleByID returns the post only when viewerID is allowed to see it,
+// otherwise it is reported as not found.
+func (s *PostStore) GetVisibleByID(ctx context.Context, ID, viewerID int64) (*Post, error) {
+	return s.get(ctx, "id = $1 AND "+visibleTo("posts", "$2"), ID, viewerID)
+}
+
+func (s *PostStore) get(ctx context.Context, where string, args ...any) (*Post, error) {
 	query := `
-		SELECT id, content, title, user_id, tags, created_at, updated_at, version
+		SELECT id, content, title, user_id, tags, explicit_tags, created_at, updated_at, version, kind, original_id, visibility,
+			content_format, COALESCE(content_html, ''), COALESCE(content_html_version, -1),
+			content_warning, sensitive, content_warning_by
 		FROM posts
-		WHERE id = $1
-	`
+		WHERE ` + where
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	post := &Post{}
-	var tags []string
+	var tags, explicitTags []string
 
-	err := s.db.QueryRowContext(ctx, query, ID).Scan(
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
 		&post.ID,
 		&post.Content,
 		&post.Title,
 		&post.UserID,
 		pq.Array(&tags),
+		pq.Array(&explicitTags),
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
+		&post.Kind,
+		&post.OriginalID,
+		&post.Visibility,
+		&post.ContentFormat,
+		&post.ContentHTML,
+		&post.RenderedVersion,
+		&post.ContentWarning,
+		&post.Sensitive,
+		&post.WarnedBy,
 	)
 
 	if err != nil {
@@ -145,26 +565,38 @@ func (s *PostStore) GetByID(ctx context.Context, ID int64) (*Post, error) {
 	}
 
 	post.Tags = tags
+	post.ExplicitTags = explicitTags
 	return post, nil
 }
 
 func (s *PostStore) DeleteByID(ctx context.Context, ID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Pure reposts go away together with the original
+		if err := s.deleteReposts(ctx, tx, ID); err != nil {
+			return err
+		}
+
+		return s.delete(ctx, tx, ID)
+	})
+}
+
+func (s *PostStore) DeleteRepost(ctx context.Context, userID, originalID int64) error {
 	query := `
 		DELETE FROM posts
-		WHERE id = $1
+		WHERE user_id = $1 AND original_id = $2 AND kind = 'repost'
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second * 5)
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	result, err := s.db.ExecContext(ctx, query, ID)
+	result, err := s.db.ExecContext(ctx, query, userID, originalID)
 	if err != nil {
-		return err 
+		return err
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err 
+		return err
 	}
 
 	if rowsAffected == 0 {
@@ -174,25 +606,19 @@ func (s *PostStore) DeleteByID(ctx context.Context, ID int64) error {
 	return nil
 }
 
-func (s *PostStore) PatchByID(ctx context.Context, post *Post) error {
+func (s *PostStore) delete(ctx context.Context, tx *sql.Tx, ID int64) error {
 	query := `
-		UPDATE posts 
-		SET title = $1, content = $2, version = version + 1
-		WHERE id = $3 AND version = $4
-		RETURNING version
+		DELETE FROM posts
+		WHERE id = $1
+		RETURNING tags
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := context.WithTimeout(ctx, time.Second * 5)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx, 
-		query, 
-		post.Title, 
-		post.Content, 
-		post.ID,
-		post.Version,
-	).Scan(&post.Version)
+	var tags []string
+
+	err := tx.QueryRowContext(ctx, query, ID).Scan(pq.Array(&tags))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrNotFound
@@ -200,5 +626,132 @@ func (s *PostStore) PatchByID(ctx context.Context, post *Post) error {
 		return err 
 	}
 
-	return nil
-}
\ No newline at end of file
+	return updateTagCounts(ctx, tx, nil, tags)
+}
+
+func (s *PostStore) deleteReposts(ctx context.Context, tx *sql.Tx, originalID int64) error {
+	query := `
+		DELETE FROM posts
+		WHERE original_id = $1 AND kind = 'repost'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, originalID)
+	return err
+}
+
+func (s *PostStore) PatchByID(ctx context.Context, post *Post) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// The FROM side of the update still holds the row as it was, which
+		// gives the tags before the change
+		query := `
+			UPDATE posts p
+			SET title = $1, content = $2, visibility = $3, tags = $4, explicit_tags = $5, content_format = $6,
+				content_warning = $7, sensitive = $8, content_warning_by = $9, version = p.version + 1
+			FROM posts old
+			WHERE p.id = $10 AND p.version = $11 AND old.id = p.id
+			RETURNING p.version, old.tags
+		`
+
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var oldTags []string
+
+		err := tx.QueryRowContext(
+			ctx, 
+			query, 
+			post.Title, 
+			post.Content, 
+			post.Visibility,
+			pq.Array(post.Tags),
+			pq.Array(post.ExplicitTags),
+			post.ContentFormat,
+			post.ContentWarning,
+			post.Sensitive,
+			post.WarnedBy,
+			post.ID,
+			post.Version,
+		).Scan(&post.Version, pq.Array(&oldTags))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err 
+		}
+
+		added, removed := diffTags(oldTags, post.Tags)
+		if err := updateTagCounts(ctx, tx, added, removed); err != nil {
+			return err
+		}
+
+		return saveMentions(ctx, tx, post.UserID, &post.ID, nil, &post.Entities)
+	})
+}
+
+// GetUnrendered returns the posts after afterID whose current version hasn't
+// been rendered, either written before rendering existed or whose rendering
+// failed to save. Only the fields rendering needs are filled in.
+func (s *PostStore) GetUnrendered(ctx context.Context, afterID int64, limit int) ([]*Post, error) {
+	query := `
+		SELECT id, content, content_format, version
+		FROM posts
+		WHERE id > $1 AND content <> '' AND content_html_version IS DISTINCT FROM version
+		ORDER BY id
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*Post
+	for rows.Next() {
+		post := &Post{RenderedVersion: -1}
+		if err := rows.Scan(&post.ID, &post.Content, &post.ContentFormat, &post.Version); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// SaveRendered stores the rendered content of the posts. A post edited since
+// it was rendered is left alone, its new version is rendered when it's saved.
+func (s *PostStore) SaveRendered(ctx context.Context, posts []*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE posts p
+		SET content_html = r.html, content_html_version = r.version
+		FROM unnest($1::bigint[], $2::int[], $3::text[]) AS r (id, version, html)
+		WHERE p.id = r.id AND p.version = r.version
+	`
+
+	ids := make([]int64, len(posts))
+	versions := make([]int64, len(posts))
+	html := make([]string, len(posts))
+
+	for i, p := range posts {
+		ids[i] = p.ID
+		versions[i] = p.RenderedVersion
+		html[i] = p.ContentHTML
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, pq.Array(ids), pq.Array(versions), pq.Array(html))
+	return err
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qwerqy/social-api-go/internal/ranking"
+)
+
+// AffinityWindow is how far back the viewer's interactions with an author
+// are counted when ranking their posts.
+const AffinityWindow = time.Hour * 24 * 30
+
+// GetRankingCandidates returns the posts made within window that could be
+// ranked into the user's feed: those of the users they follow and of the
+// users those follow, that the user can see and match the feed's filters.
+// The newest limit are returned, reposts are left out in favour of the
+// posts they share.
+func (s *PostStore) GetRankingCandidates(ctx context.Context, userID int64, fq PaginatedFeedQuery, window time.Duration, limit int) ([]ranking.Candidate, error) {
+	query := `
+		WITH followed AS (
+			SELECT f.user_id FROM followers f WHERE f.follower_id = $1 AND NOT f.pending
+		),
+		friends_of_friends AS (
+			SELECT DISTINCT f.user_id FROM followers f
+			WHERE
+				f.follower_id IN (SELECT user_id FROM followed) AND NOT f.pending AND
+				f.user_id <> $1 AND
+				f.user_id NOT IN (SELECT user_id FROM followed)
+		),
+		interactions AS (
+			SELECT ap.user_id, COUNT(*) AS n
+			FROM (
+				SELECT post_id FROM reactions
+				WHERE user_id = $1 AND created_at > NOW() - $3 * INTERVAL '1 second'
+				UNION ALL
+				SELECT post_id FROM comments
+				WHERE user_id = $1 AND deleted_at IS NULL AND created_at > NOW() - $3 * INTERVAL '1 second'
+				UNION ALL
+				SELECT original_id FROM posts
+				WHERE user_id = $1 AND original_id IS NOT NULL AND created_at > NOW() - $3 * INTERVAL '1 second'
+			) i
+			JOIN posts ap ON ap.id = i.post_id
+			GROUP BY ap.user_id
+		)
+		SELECT
+			p.id, p.user_id, p.created_at,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.id),
+			(SELECT COUNT(*) FROM posts s WHERE s.original_id = p.id),
+			COALESCE(a.n, 0),
+			p.user_id IN (SELECT user_id FROM followed)
+		FROM posts p
+		LEFT JOIN posts o ON o.id = p.original_id
+		LEFT JOIN interactions a ON a.user_id = p.user_id
+		WHERE
+			(p.user_id IN (SELECT user_id FROM followed) OR p.user_id IN (SELECT user_id FROM friends_of_friends)) AND
+			p.kind <> 'repost' AND
+			p.created_at > NOW() - $2 * INTERVAL '1 second' AND
+			` + visibleTo("p", "$1") + ` AND
+			` + textMatch("$5", "p.search_vector") + ` AND
+			(p.tags @> $6 OR $6 = '{}') AND
+			p.created_at >= COALESCE(NULLIF($7, '')::timestamptz, '-infinity') AND
+			p.created_at <= COALESCE(NULLIF($8, '')::timestamptz, 'infinity') AND
+			` + notMuted("p", "$1", "$9", "$10") + ` AND
+			` + notMuted("o", "$1", "$9", "$10") + `
+		ORDER BY p.id DESC
+		LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		userID,
+		window.Seconds(),
+		AffinityWindow.Seconds(),
+		limit,
+		fq.Search,
+		pq.Array(fq.Tags),
+		fq.Since,
+		fq.Until,
+		fq.Mutes.Words,
+		pq.Array(fq.Mutes.Tags),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []ranking.Candidate
+
+	for rows.Next() {
+		var c ranking.Candidate
+		if err := rows.Scan(
+			&c.PostID,
+			&c.AuthorID,
+			&c.CreatedAt,
+			&c.Comments,
+			&c.Reactions,
+			&c.Reposts,
+			&c.Interactions,
+			&c.Followed,
+		); err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
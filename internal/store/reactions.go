@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// ReactionKinds are the reactions a user can leave on a post.
+var ReactionKinds = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+type Reaction struct {
+	PostID    int64  `json:"post_id"`
+	UserID    int64  `json:"user_id"`
+	Kind      string `json:"kind"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+// Set leaves a reaction on a post, replacing the user's previous one.
+func (s *ReactionStore) Set(ctx context.Context, reaction *Reaction) error {
+	query := `
+		INSERT INTO reactions (post_id, user_id, kind)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET kind = EXCLUDED.kind, created_at = NOW()
+		RETURNING created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		reaction.PostID,
+		reaction.UserID,
+		reaction.Kind,
+	).Scan(&reaction.CreatedAt)
+}
+
+func (s *ReactionStore) Delete(ctx context.Context, postID, userID int64) error {
+	query := `DELETE FROM reactions WHERE post_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, postID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetCountsByPostIDs returns how many of each reaction the posts have, by
+// post ID.
+func (s *ReactionStore) GetCountsByPostIDs(ctx context.Context, postIDs []int64) (map[int64]map[string]int, error) {
+	query := `
+		SELECT post_id, kind, COUNT(*)
+		FROM reactions
+		WHERE post_id = ANY($1)
+		GROUP BY post_id, kind
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]map[string]int)
+
+	for rows.Next() {
+		var postID int64
+		var kind string
+		var count int
+
+		if err := rows.Scan(&postID, &kind, &count); err != nil {
+			return nil, err
+		}
+
+		if counts[postID] == nil {
+			counts[postID] = make(map[string]int)
+		}
+		counts[postID][kind] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// SavedSearch is a set of feed filters the user named to come back to.
+type SavedSearch struct {
+	ID        int64       `json:"id"`
+	UserID    int64       `json:"user_id"`
+	Name      string      `json:"name"`
+	Query     FeedFilters `json:"query"`
+	CreatedAt string      `json:"created_at"`
+}
+
+type SavedSearchStore struct {
+	db *sql.DB
+}
+
+// Create saves a search, returning ErrConflict when the user already has
+// one with the same name.
+func (s *SavedSearchStore) Create(ctx context.Context, search *SavedSearch) error {
+	query := `
+		INSERT INTO saved_searches (user_id, name, query)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	filters, err := json.Marshal(search.Query)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err = s.db.QueryRowContext(
+		ctx,
+		query,
+		search.UserID,
+		search.Name,
+		filters,
+	).Scan(&search.ID, &search.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// GetByID returns one of the user's saved searches.
+func (s *SavedSearchStore) GetByID(ctx context.Context, userID, searchID int64) (*SavedSearch, error) {
+	query := `
+		SELECT id, user_id, name, query, created_at
+		FROM saved_searches
+		WHERE id = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	search := &SavedSearch{}
+	if err := scanSavedSearch(s.db.QueryRowContext(ctx, query, searchID, userID), search); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return search, nil
+}
+
+// GetByUserID returns the user's saved searches by name.
+func (s *SavedSearchStore) GetByUserID(ctx context.Context, userID int64) ([]*SavedSearch, error) {
+	query := `
+		SELECT id, user_id, name, query, created_at
+		FROM saved_searches
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []*SavedSearch{}
+
+	for rows.Next() {
+		search := &SavedSearch{}
+		if err := scanSavedSearch(rows, search); err != nil {
+			return nil, err
+		}
+
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
+
+func (s *SavedSearchStore) Delete(ctx context.Context, userID, searchID int64) error {
+	query := `DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, searchID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func scanSavedSearch(row rowScanner, search *SavedSearch) error {
+	var filters []byte
+
+	err := row.Scan(&search.ID, &search.UserID, &search.Name, &filters, &search.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(filters, &search.Query)
+}
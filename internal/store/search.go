@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/qwerqy/social-api-go/internal/search"
+)
+
+type PostSearchResult struct {
+	PostWithMetadata
+	Rank float64 `json:"rank"`
+	// Snippet is HTML, the parts of the post matching the query in <mark>
+	// elements
+	Snippet string `json:"snippet"`
+}
+
+type CommentSearchResult struct {
+	Comment
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+// searchMatch, searchRank and searchHeadline match, rank and summarise a
+// document against the text bound to $2, with the ts_headline options bound
+// to $3. A search of operators alone has no text: everything matches, ranks
+// the same and has no snippet.
+func searchMatch(vector string) string {
+	return textMatch("$2", vector)
+}
+
+// textMatch matches the search text bound to param, in web search syntax,
+// against any of the vectors. An empty text matches everything.
+func textMatch(param string, vectors ...string) string {
+	conditions := []string{param + " = ''"}
+	for _, v := range vectors {
+		conditions = append(conditions, fmt.Sprintf("%s @@ websearch_to_tsquery('english', %s)", v, param))
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+func searchRank(vector string) string {
+	return fmt.Sprintf(`CASE WHEN $2 = '' THEN 0
+		ELSE ts_rank_cd(%s, websearch_to_tsquery('english', $2)) END`, vector)
+}
+
+func searchHeadline(document string) string {
+	return fmt.Sprintf(`CASE WHEN $2 = '' THEN ''
+		ELSE ts_headline('english', %s, websearch_to_tsquery('english', $2), $3) END`, document)
+}
+
+// Posts returns the posts matching the search that the viewer can see,
+// best match first. Reposts are left out, they would only repeat the post
+// they share.
+func (s *SearchStore) Posts(ctx context.Context, viewerID int64, sq SearchQuery) ([]*PostSearchResult, error) {
+	// Matches are ranked and paged first, so only the page is summarised
+	query := `
+		WITH matches AS (
+			SELECT p.id, ` + searchRank("p.search_vector") + ` AS rank
+			FROM posts p
+			JOIN users au ON au.id = p.user_id
+			WHERE
+				p.kind <> 'repost' AND
+				` + searchMatch("p.search_vector") + ` AND
+				(COALESCE(cardinality($4::varchar[]), 0) = 0 OR au.username = ANY($4)) AND
+				p.tags @> COALESCE($5::varchar[], '{}') AND
+				p.created_at >= COALESCE($6::timestamptz, '-infinity') AND
+				p.created_at < COALESCE($7::timestamptz, 'infinity') AND
+				` + visibleTo("p", "$1") + `
+			ORDER BY rank DESC, p.created_at DESC, p.id DESC
+			LIMIT $8 OFFSET $9
+		)
+		SELECT ` + postListColumns + `, m.rank,
+			` + searchHeadline("concat_ws(E'\\n', NULLIF(p.title, ''), p.content)") + `
+		FROM matches m
+		JOIN posts p ON p.id = m.id
+		` + postListJoins + `
+		GROUP BY ` + postListGroupBy + `, m.rank
+		ORDER BY m.rank DESC, p.created_at DESC, p.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, searchArgs(viewerID, sq)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*PostSearchResult{}
+
+	for rows.Next() {
+		r := &PostSearchResult{}
+		if err := scanPostListRow(extraColumns{rows, []any{&r.Rank, &r.Snippet}}, &r.PostWithMetadata); err != nil {
+			return nil, err
+		}
+
+		r.Snippet = search.Highlight(r.Snippet)
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// Comments returns the comments matching the search that the viewer can
+// see, best match first. Comments are seen along with their post, and never
+// by users their author blocked or was blocked by. tag: filters on the tags
+// of the post.
+func (s *SearchStore) Comments(ctx context.Context, viewerID int64, sq SearchQuery) ([]*CommentSearchResult, error) {
+	query := `
+		WITH matches AS (
+			SELECT c.id, ` + searchRank("c.search_vector") + ` AS rank
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			JOIN users au ON au.id = c.user_id
+			WHERE
+				c.deleted_at IS NULL AND
+				` + searchMatch("c.search_vector") + ` AND
+				(COALESCE(cardinality($4::varchar[]), 0) = 0 OR au.username = ANY($4)) AND
+				p.tags @> COALESCE($5::varchar[], '{}') AND
+				c.created_at >= COALESCE($6::timestamptz, '-infinity') AND
+				c.created_at < COALESCE($7::timestamptz, 'infinity') AND
+				` + visibleTo("p", "$1") + ` AND
+				NOT ` + blockedBetween("c.user_id", "$1") + `
+			ORDER BY rank DESC, c.created_at DESC, c.id DESC
+			LIMIT $8 OFFSET $9
+		)
+		SELECT ` + commentColumns + `, m.rank, ` + searchHeadline("c.content") + `
+		FROM matches m
+		JOIN comments c ON c.id = m.id
+		JOIN users ON users.id = c.user_id
+		ORDER BY m.rank DESC, c.created_at DESC, c.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, searchArgs(viewerID, sq)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*CommentSearchResult{}
+
+	for rows.Next() {
+		r := &CommentSearchResult{}
+		if err := scanComment(extraColumns{rows, []any{&r.Rank, &r.Snippet}}, &r.Comment); err != nil {
+			return nil, err
+		}
+
+		r.Snippet = search.Highlight(r.Snippet)
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+func searchArgs(viewerID int64, sq SearchQuery) []any {
+	return []any{
+		viewerID,
+		sq.Terms.Text,
+		search.HeadlineOptions,
+		pq.Array(sq.Terms.From),
+		pq.Array(sq.Terms.Tags),
+		nullTime(sq.Terms.Since),
+		nullTime(sq.Terms.Until),
+		sq.Limit,
+		sq.Offset,
+	}
+}
+
+// nullTime binds the zero time as NULL.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
+
+// extraColumns reads the columns following the ones a scan function knows
+// about into extra.
+type extraColumns struct {
+	rowScanner
+	extra []any
+}
+
+func (e extraColumns) Scan(dest ...any) error {
+	return e.rowScanner.Scan(append(dest, e.extra...)...)
+}
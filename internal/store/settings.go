@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type UserSettings struct {
+	UserID int64 `json:"-"`
+	// ExpandSensitive shows posts with a content warning or sensitive media
+	// expanded instead of collapsed
+	ExpandSensitive bool `json:"expand_sensitive"`
+	// IsPrivate limits the user's posts to their followers
+	IsPrivate bool   `json:"is_private"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+type UserSettingsStore struct {
+	db *sql.DB
+}
+
+// Get returns the settings of a user, or the defaults when they never
+// changed any.
+func (s *UserSettingsStore) Get(ctx context.Context, userID int64) (*UserSettings, error) {
+	query := `
+		SELECT COALESCE(us.expand_sensitive, false), u.is_private, us.updated_at
+		FROM users u
+		LEFT JOIN user_settings us ON us.user_id = u.id
+		WHERE u.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	settings := &UserSettings{UserID: userID}
+	var updatedAt sql.NullString
+
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&settings.ExpandSensitive,
+		&settings.IsPrivate,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	settings.UpdatedAt = updatedAt.String
+
+	return settings, nil
+}
+
+func (s *UserSettingsStore) Update(ctx context.Context, settings *UserSettings) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		// Privacy lives on users so visibility checks don't need a join
+		query := `UPDATE users SET is_private = $2 WHERE id = $1`
+
+		if _, err := tx.ExecContext(ctx, query, settings.UserID, settings.IsPrivate); err != nil {
+			return err
+		}
+
+		// Going public lets everyone waiting follow straight away
+		if !settings.IsPrivate {
+			query = `UPDATE followers SET pending = false WHERE user_id = $1 AND pending`
+
+			if _, err := tx.ExecContext(ctx, query, settings.UserID); err != nil {
+				return err
+			}
+		}
+
+		query = `
+			INSERT INTO user_settings (user_id, expand_sensitive)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE SET
+				expand_sensitive = EXCLUDED.expand_sensitive,
+				updated_at = NOW()
+			RETURNING updated_at
+		`
+
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			settings.UserID,
+			settings.ExpandSensitive,
+		).Scan(&settings.UpdatedAt)
+	})
+}
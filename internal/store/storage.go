@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/qwerqy/social-api-go/internal/ranking"
 )
 
 var (
@@ -16,10 +18,20 @@ var (
 type Storage struct {
 	Posts interface {
 		GetByID(context.Context, int64) (*Post, error)
+		GetVisibleByID(ctx context.Context, ID, viewerID int64) (*Post, error)
 		Create(context.Context, *Post) error
 		DeleteByID(context.Context, int64) error
+		DeleteRepost(ctx context.Context, userID, originalID int64) error
 		PatchByID(context.Context, *Post) error
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]*PostWithMetadata, error)
+		GetListFeed(ctx context.Context, listID, viewerID int64, fq PaginatedFeedQuery) ([]*PostWithMetadata, error)
+		GetRankingCandidates(ctx context.Context, userID int64, fq PaginatedFeedQuery, window time.Duration, limit int) ([]ranking.Candidate, error)
+		GetByTag(ctx context.Context, tag string, viewerID int64, kq KeysetPaginatedQuery) ([]*PostWithMetadata, error)
+		GetVisibleByIDs(ctx context.Context, IDs []int64, viewerID int64, mq MuteQuery) ([]*PostWithMetadata, error)
+		GetUnrendered(ctx context.Context, afterID int64, limit int) ([]*Post, error)
+		SaveRendered(context.Context, []*Post) error
+		GetByUserID(ctx context.Context, userID, viewerID int64, uq UserPostsQuery) ([]*PostWithMetadata, error)
+		GetPinned(ctx context.Context, userID, viewerID int64) ([]*PostWithMetadata, error)
 	}
 	Users interface {
 		GetByID(context.Context, int64) (*User, error)
@@ -30,12 +42,117 @@ type Storage struct {
 		Delete(context.Context, int64) error
 	}
 	Comments interface {
-		GetByPostID(context.Context, int64) ([]*Comment, error)
+		GetByID(context.Context, int64) (*Comment, error)
+		GetByPostID(ctx context.Context, postID int64, kq KeysetPaginatedQuery, replies int) ([]*Comment, error)
+		GetReplies(context.Context, int64) ([]*Comment, error)
+		GetEdits(context.Context, int64) ([]*CommentEdit, error)
+		PatchByID(ctx context.Context, comment *Comment, editorID int64) error
+		DeleteByID(context.Context, int64) error
 		CreateByPostID(context.Context, *Comment) error
 	}
 	Followers interface {
-		Follow(ctx context.Context, followerID, userID int64) error
+		Follow(ctx context.Context, followerID, userID int64) (pending bool, err error)
 		Unfollow(ctx context.Context, followerID, userID int64) error
+		IsFollowing(ctx context.Context, followerID, userID int64) (bool, error)
+		GetRequests(ctx context.Context, userID int64, kq KeysetPaginatedQuery) ([]*Follower, error)
+		Approve(ctx context.Context, userID, followerID int64) error
+		Reject(ctx context.Context, userID, followerID int64) error
+		GetFollowerIDs(ctx context.Context, userID int64) ([]int64, error)
+		GetRequesterIDs(ctx context.Context, userID int64) ([]int64, error)
+	}
+	Bookmarks interface {
+		Save(ctx context.Context, bookmark *Bookmark, collection string) error
+		Delete(ctx context.Context, userID, postID int64) error
+		Exists(ctx context.Context, userID, postID int64) (bool, error)
+		GetByUserID(context.Context, int64, BookmarkQuery) ([]*Bookmark, error)
+		GetCollections(context.Context, int64) ([]*BookmarkCollection, error)
+		DeleteCollection(ctx context.Context, userID, collectionID int64) error
+	}
+	Polls interface {
+		GetByPostID(ctx context.Context, postID, viewerID int64) (*Poll, error)
+		Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error
+	}
+	Media interface {
+		Create(context.Context, *Media) error
+		GetByID(context.Context, int64) (*Media, error)
+		GetByPostIDs(context.Context, []int64) (map[int64][]*Media, error)
+		GetOrphaned(ctx context.Context, age time.Duration, limit int) ([]*Media, error)
+		Delete(context.Context, int64) error
+	}
+	Blocks interface {
+		Block(ctx context.Context, userID, blockedID int64) error
+		Unblock(ctx context.Context, userID, blockedID int64) error
+		IsBlocked(ctx context.Context, userID, otherID int64) (bool, error)
+	}
+	Mentions interface {
+		GetByUserID(context.Context, int64, KeysetPaginatedQuery) ([]*Mention, error)
+		GetByPostIDs(context.Context, []int64) (map[int64][]Entity, error)
+		GetByCommentIDs(context.Context, []int64) (map[int64][]Entity, error)
+	}
+	Tags interface {
+		Search(ctx context.Context, prefix string, limit int) ([]*Tag, error)
+	}
+	Reactions interface {
+		Set(context.Context, *Reaction) error
+		Delete(ctx context.Context, postID, userID int64) error
+		GetCountsByPostIDs(context.Context, []int64) (map[int64]map[string]int, error)
+	}
+	Trending interface {
+		Compute(ctx context.Context, window, halfLife time.Duration, limit int) (*Trending, error)
+	}
+	LinkPreviews interface {
+		NeedsFetch(ctx context.Context, url string, maxAge, failedMaxAge time.Duration) (bool, error)
+		Save(ctx context.Context, url string, preview *LinkPreview) error
+		GetByURLs(context.Context, []string) (map[string]*LinkPreview, error)
+	}
+	Settings interface {
+		Get(context.Context, int64) (*UserSettings, error)
+		Update(context.Context, *UserSettings) error
+	}
+	Pins interface {
+		Pin(ctx context.Context, userID, postID int64) error
+		Unpin(ctx context.Context, userID, postID int64) error
+		Reorder(ctx context.Context, userID int64, postIDs []int64) error
+	}
+	MutedWords interface {
+		Create(context.Context, *MutedWord) error
+		GetByUserID(context.Context, int64) ([]*MutedWord, error)
+		GetByUserIDs(context.Context, []int64) (map[int64][]*MutedWord, error)
+		Delete(ctx context.Context, userID, wordID int64) error
+	}
+	Lists interface {
+		Create(context.Context, *List) error
+		GetByID(ctx context.Context, listID, viewerID int64) (*List, error)
+		GetByUserID(ctx context.Context, userID, viewerID int64) ([]*List, error)
+		GetFollowed(ctx context.Context, userID int64) ([]*List, error)
+		Update(context.Context, *List) error
+		Delete(context.Context, int64) error
+		AddMember(ctx context.Context, listID, userID int64) error
+		RemoveMember(ctx context.Context, listID, userID int64) error
+		GetMembers(ctx context.Context, listID int64, kq KeysetPaginatedQuery) ([]*User, error)
+		Follow(ctx context.Context, listID, userID int64) error
+		Unfollow(ctx context.Context, listID, userID int64) error
+	}
+	SavedSearches interface {
+		Create(context.Context, *SavedSearch) error
+		GetByID(ctx context.Context, userID, searchID int64) (*SavedSearch, error)
+		GetByUserID(context.Context, int64) ([]*SavedSearch, error)
+		Delete(ctx context.Context, userID, searchID int64) error
+	}
+	Notifications interface {
+		Create(context.Context, *Notification) error
+		GetByUserID(ctx context.Context, userID int64, nq NotificationQuery) ([]*Notification, error)
+		CountUnread(context.Context, int64) (int, error)
+		MarkRead(ctx context.Context, userID, notificationID int64) error
+		MarkAllRead(context.Context, int64) error
+	}
+	Search interface {
+		Posts(ctx context.Context, viewerID int64, sq SearchQuery) ([]*PostSearchResult, error)
+		Comments(ctx context.Context, viewerID int64, sq SearchQuery) ([]*CommentSearchResult, error)
+	}
+	Timelines interface {
+		GetEntries(ctx context.Context, userID int64, maxFollowers, limit int) ([]TimelineEntry, error)
+		GetPopularEntries(ctx context.Context, userID int64, minFollowers int, before int64, limit int) ([]TimelineEntry, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
@@ -44,11 +161,28 @@ type Storage struct {
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UserStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:         &PostStore{db},
+		Users:         &UserStore{db},
+		Comments:      &CommentStore{db},
+		Followers:     &FollowerStore{db},
+		Roles:         &RoleStore{db},
+		Bookmarks:     &BookmarkStore{db},
+		Polls:         &PollStore{db},
+		Media:         &MediaStore{db},
+		Blocks:        &BlockStore{db},
+		Mentions:      &MentionStore{db},
+		Tags:          &TagStore{db},
+		Reactions:     &ReactionStore{db},
+		Trending:      &TrendingStore{db},
+		LinkPreviews:  &LinkPreviewStore{db},
+		Settings:      &UserSettingsStore{db},
+		Pins:          &PinStore{db},
+		Timelines:     &TimelineStore{db},
+		Search:        &SearchStore{db},
+		MutedWords:    &MutedWordStore{db},
+		Lists:         &ListStore{db},
+		SavedSearches: &SavedSearchStore{db},
+		Notifications: &NotificationStore{db},
 	}
 }
 
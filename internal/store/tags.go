@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type Tag struct {
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
+
+type TagStore struct {
+	db *sql.DB
+}
+
+// Search returns the tags starting with prefix, most used first.
+func (s *TagStore) Search(ctx context.Context, prefix string, limit int) ([]*Tag, error) {
+	query := `
+		SELECT name, post_count
+		FROM tags
+		WHERE name LIKE $1 || '%' AND post_count > 0
+		ORDER BY post_count DESC, name
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, escapeLike(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		t := &Tag{}
+		if err := rows.Scan(&t.Name, &t.PostCount); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// updateTagCounts keeps the usage counts of the tags table in step with the
+// tags added to and removed from a post.
+func updateTagCounts(ctx context.Context, tx *sql.Tx, added, removed []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if len(added) > 0 {
+		query := `
+			INSERT INTO tags (name, post_count)
+			SELECT unnest($1::varchar[]), 1
+			ON CONFLICT (name) DO UPDATE SET post_count = tags.post_count + 1
+		`
+
+		if _, err := tx.ExecContext(ctx, query, pq.Array(added)); err != nil {
+			return err
+		}
+	}
+
+	if len(removed) > 0 {
+		query := `UPDATE tags SET post_count = post_count - 1 WHERE name = ANY($1)`
+
+		if _, err := tx.ExecContext(ctx, query, pq.Array(removed)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffTags returns the tags in after but not in before, and the other way
+// round.
+func diffTags(before, after []string) (added, removed []string) {
+	for _, t := range after {
+		if !slices.Contains(before, t) {
+			added = append(added, t)
+		}
+	}
+
+	for _, t := range before {
+		if !slices.Contains(after, t) {
+			removed = append(removed, t)
+		}
+	}
+
+	return added, removed
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TimelineEntry is a post as kept in a home timeline: enough to order it,
+// find it again by author and page from it.
+type TimelineEntry struct {
+	PostID    int64
+	AuthorID  int64
+	CreatedAt time.Time
+}
+
+type TimelineStore struct {
+	db *sql.DB
+}
+
+// followedFollowers counts the followers of f.user_id, a user followed by
+// the timeline's owner. Posts of users with fewer than the celebrity
+// threshold are pushed to their followers' timelines, the others are read
+// along with the timeline.
+const followedFollowers = `(SELECT COUNT(*) FROM followers ff WHERE ff.user_id = f.user_id AND NOT ff.pending)`
+
+// GetEntries returns the newest posts of the user and of the users they
+// follow with fewer than maxFollowers followers, newest first, to build
+// their timeline from.
+func (s *TimelineStore) GetEntries(ctx context.Context, userID int64, maxFollowers, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.user_id, p.created_at
+		FROM posts p
+		WHERE p.user_id = $1 OR p.user_id IN (
+			SELECT f.user_id FROM followers f
+			WHERE f.follower_id = $1 AND NOT f.pending AND ` + followedFollowers + ` < $2
+		)
+		ORDER BY p.id DESC
+		LIMIT $3
+	`
+
+	return s.query(ctx, query, userID, maxFollowers, limit)
+}
+
+// GetPopularEntries returns the posts older than the before post ID (or the
+// newest when it's 0) of the users the user follows who have at least
+// minFollowers followers, newest first.
+func (s *TimelineStore) GetPopularEntries(ctx context.Context, userID int64, minFollowers int, before int64, limit int) ([]TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.user_id, p.created_at
+		FROM posts p
+		WHERE
+			($3 = 0 OR p.id < $3) AND
+			p.user_id IN (
+				SELECT f.user_id FROM followers f
+				WHERE f.follower_id = $1 AND NOT f.pending AND ` + followedFollowers + ` >= $2
+			)
+		ORDER BY p.id DESC
+		LIMIT $4
+	`
+
+	return s.query(ctx, query, userID, minFollowers, before, limit)
+}
+
+func (s *TimelineStore) query(ctx context.Context, query string, args ...any) ([]TimelineEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []TimelineEntry
+
+	for rows.Next() {
+		var e TimelineEntry
+		if err := rows.Scan(&e.PostID, &e.AuthorID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Trending is a snapshot of what is being engaged with the most over a
+// window. It only covers public posts of public accounts so the same
+// snapshot can be shared by every viewer, but the posts a viewer can't see still have to be left out.
+type Trending struct {
+	Posts      []TrendingPost `json:"posts"`
+	Tags       []TrendingTag  `json:"tags"`
+	ComputedAt time.Time      `json:"computed_at"`
+}
+
+type TrendingPost struct {
+	PostID int64   `json:"post_id"`
+	Score  float64 `json:"score"`
+}
+
+type TrendingTag struct {
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"`
+	PostCount int     `json:"post_count"`
+}
+
+type TrendingStore struct {
+	db *sql.DB
+}
+
+// trendingEngagement selects the comments, reactions and reposts made on
+// public posts of public accounts within the last $1 seconds, each
+// weighted by how much it says about interest in the post. Authors engaging
+// with their own posts don't count.
+const trendingEngagement = `
+	engagement AS (
+		SELECT e.post_id, e.created_at, e.weight
+		FROM (
+			SELECT c.post_id, c.user_id, c.created_at, 2.0 AS weight
+			FROM comments c
+			WHERE c.deleted_at IS NULL AND c.created_at > NOW() - $1 * INTERVAL '1 second'
+			UNION ALL
+			SELECT r.post_id, r.user_id, r.created_at, 1.0
+			FROM reactions r
+			WHERE r.created_at > NOW() - $1 * INTERVAL '1 second'
+			UNION ALL
+			SELECT s.original_id, s.user_id, s.created_at, 3.0
+			FROM posts s
+			WHERE s.original_id IS NOT NULL AND s.created_at > NOW() - $1 * INTERVAL '1 second'
+		) e
+		JOIN posts p ON p.id = e.post_id
+		JOIN users u ON u.id = p.user_id AND NOT u.is_private
+		WHERE p.visibility = 'public' AND p.kind <> 'repost' AND e.user_id <> p.user_id
+	)
+`
+
+// trendingScore adds up the weights of the events, halving them every $2
+// seconds so recent activity counts for more than an earlier burst.
+const trendingScore = `SUM(e.weight * EXP(-LN(2) * EXTRACT(EPOCH FROM NOW() - e.created_at) / $2))`
+
+// Compute scores the posts and tags engaged with over the last window,
+// decaying older engagement with the given half life, and returns the top
+// limit of each.
+func (s *TrendingStore) Compute(ctx context.Context, window, halfLife time.Duration, limit int) (*Trending, error) {
+	trending := &Trending{
+		Posts:      []TrendingPost{},
+		Tags:       []TrendingTag{},
+		ComputedAt: time.Now(),
+	}
+
+	args := []any{window.Seconds(), halfLife.Seconds(), limit}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	postsQuery := `
+		WITH ` + trendingEngagement + `
+		SELECT e.post_id, ` + trendingScore + ` AS score
+		FROM engagement e
+		GROUP BY e.post_id
+		ORDER BY score DESC, e.post_id DESC
+		LIMIT $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, postsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p TrendingPost
+		if err := rows.Scan(&p.PostID, &p.Score); err != nil {
+			return nil, err
+		}
+
+		trending.Posts = append(trending.Posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// New posts count towards their tags too, so a tag everyone starts using
+	// trends before the posts get any engagement
+	tagsQuery := `
+		WITH ` + trendingEngagement + `,
+		events AS (
+			SELECT post_id, created_at, weight FROM engagement
+			UNION ALL
+			SELECT p.id, p.created_at, 1.0
+			FROM posts p
+			JOIN users u ON u.id = p.user_id AND NOT u.is_private
+			WHERE p.visibility = 'public' AND p.kind <> 'repost' AND p.created_at > NOW() - $1 * INTERVAL '1 second'
+		)
+		SELECT t.name, ` + trendingScore + ` AS score, COUNT(DISTINCT e.post_id)
+		FROM events e
+		JOIN posts p ON p.id = e.post_id
+		CROSS JOIN unnest(p.tags) AS t(name)
+		GROUP BY t.name
+		ORDER BY score DESC, t.name
+		LIMIT $3
+	`
+
+	tagRows, err := s.db.QueryContext(ctx, tagsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var t TrendingTag
+		if err := tagRows.Scan(&t.Name, &t.Score, &t.PostCount); err != nil {
+			return nil, err
+		}
+
+		trending.Tags = append(trending.Tags, t)
+	}
+
+	return trending, tagRows.Err()
+}
@@ -27,7 +27,8 @@ type User struct {
 	Email     string   `json:"email"`
 	Password  password `json:"-"`
 	CreatedAt string   `json:"created_at"`
-	IsActive  bool     `json:"is_active`
+	IsActive  bool     `json:"is_active"`
+	IsPrivate bool     `json:"is_private"`
 	RoleID    int64    `json:"role_id"`
 	Role      Role     `json:"role"`
 }
@@ -97,7 +98,7 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 
 func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	query := `
-		SELECT users.id, username, email, password, created_at, roles.*
+		SELECT users.id, username, email, password, created_at, is_private, roles.*
 		FROM users
 		JOIN roles ON (users.role_id = roles.id)
 		WHERE users.id = $1 AND is_active = true
@@ -118,6 +119,7 @@ func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.IsPrivate,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
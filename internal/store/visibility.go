@@ -0,0 +1,39 @@
+package store
+
+import "fmt"
+
+const (
+	VisibilityPublic = "public"
+	// VisibilityFollowers posts are seen by the author's followers
+	VisibilityFollowers = "followers"
+	// VisibilityMentioned posts are seen by the users they mention
+	VisibilityMentioned = "mentioned"
+)
+
+// visibleTo returns the SQL condition under which the post aliased as post
+// can be seen by the user bound to the viewer placeholder. Every query
+// returning posts to a user goes through it so the rules live in one place.
+// Authors always see their own posts, users who blocked each other never see
+// each other's, and users mentioned in a post can see it whatever its
+// visibility. The public posts of private accounts are only seen by their
+// followers, and follow requests that are still pending don't count.
+func visibleTo(post, viewer string) string {
+	return fmt.Sprintf(`(
+		%[1]s.user_id = %[2]s OR (
+			NOT %[3]s AND (
+				(%[1]s.visibility = 'public' AND NOT EXISTS (
+					SELECT 1 FROM users vu
+					WHERE vu.id = %[1]s.user_id AND vu.is_private
+				)) OR
+				(%[1]s.visibility IN ('public', 'followers') AND EXISTS (
+					SELECT 1 FROM followers vf
+					WHERE vf.user_id = %[1]s.user_id AND vf.follower_id = %[2]s AND NOT vf.pending
+				)) OR
+				EXISTS (
+					SELECT 1 FROM mentions vm
+					WHERE vm.post_id = %[1]s.id AND vm.user_id = %[2]s
+				)
+			)
+		)
+	)`, post, viewer, blockedBetween(post+".user_id", viewer))
+}
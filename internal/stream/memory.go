@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker delivers messages to the streams open on this instance only.
+// It serves a single instance and tests, and the Redis broker relays the
+// messages of other instances through it.
+type MemoryBroker struct {
+	mu      sync.RWMutex
+	streams map[int64]map[chan Message]struct{}
+	closed  bool
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		streams: make(map[int64]map[chan Message]struct{}),
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, userIDs []int64, m Message) error {
+	b.deliver(userIDs, m)
+	return nil
+}
+
+// deliver hands m to the streams of the users without blocking, streams
+// whose buffer is full miss it.
+func (b *MemoryBroker) deliver(userIDs []int64, m Message) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, id := range userIDs {
+		for ch := range b.streams[id] {
+			select {
+			case ch <- m:
+			default:
+			}
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe(userID int64) (<-chan Message, func()) {
+	ch := make(chan Message, BufferSize)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.streams[userID] == nil {
+		b.streams[userID] = make(map[chan Message]struct{})
+	}
+	b.streams[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			// Close already closed it
+			if _, ok := b.streams[userID][ch]; !ok {
+				return
+			}
+
+			delete(b.streams[userID], ch)
+			if len(b.streams[userID]) == 0 {
+				delete(b.streams, userID)
+			}
+
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+func (b *MemoryBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for _, streams := range b.streams {
+		for ch := range streams {
+			close(ch)
+		}
+	}
+
+	b.streams = make(map[int64]map[chan Message]struct{})
+}
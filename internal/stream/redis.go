@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChannel is where every instance publishes its messages and listens
+// for the others'.
+const redisChannel = "stream"
+
+type envelope struct {
+	UserIDs []int64 `json:"user_ids"`
+	Message Message `json:"message"`
+}
+
+// RedisBroker fans messages out to every instance through Redis pub/sub.
+// Each instance delivers them to the streams open on it, Run must be
+// running for them to get any.
+type RedisBroker struct {
+	rdb   *redis.Client
+	local *MemoryBroker
+}
+
+func NewRedisBroker(rdb *redis.Client) *RedisBroker {
+	return &RedisBroker{rdb: rdb, local: NewMemoryBroker()}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, userIDs []int64, m Message) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(envelope{UserIDs: userIDs, Message: m})
+	if err != nil {
+		return err
+	}
+
+	return b.rdb.Publish(ctx, redisChannel, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(userID int64) (<-chan Message, func()) {
+	return b.local.Subscribe(userID)
+}
+
+func (b *RedisBroker) Close() {
+	b.local.Close()
+}
+
+// Run delivers the messages published by every instance to the streams open
+// on this one until ctx is done. Messages published while Redis is
+// unreachable are lost, the subscription resumes once it's back.
+func (b *RedisBroker) Run(ctx context.Context) {
+	sub := b.rdb.Subscribe(ctx, redisChannel)
+	defer sub.Close()
+
+	messages := sub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var e envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				// Not published by Publish
+				continue
+			}
+
+			b.local.deliver(e.UserIDs, e.Message)
+		}
+	}
+}
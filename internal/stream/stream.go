@@ -0,0 +1,69 @@
+// Package stream pushes what happens to users' connected clients, so they
+// don't have to poll for it. Messages are addressed to users, and a broker
+// delivers them to every stream those users have open on any instance.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// FeedItem is a new post in the user's feed
+	FeedItem     = "feed.item"
+	PostUpdated  = "post.updated"
+	PostDeleted  = "post.deleted"
+	Notification = "notification"
+	// Ping is sent to idle WebSocket streams so proxies don't close them
+	Ping = "ping"
+)
+
+// BufferSize is how many messages a stream holds for a client that is slow
+// to read them. Messages past it are dropped, clients catch up by reading
+// the feed or their notifications again.
+const BufferSize = 32
+
+type Message struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func NewMessage(event string, data any) (Message, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Event: event, Data: raw}, nil
+}
+
+// WriteSSE writes m as a Server-Sent Events message.
+func (m Message) WriteSSE(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", m.Event)
+
+	// JSON has no raw newlines but indented data would, each line of data
+	// needs its own field
+	for _, line := range strings.Split(string(m.Data), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type Broker interface {
+	// Publish delivers m to the open streams of the users
+	Publish(ctx context.Context, userIDs []int64, m Message) error
+	// Subscribe opens a stream of the messages for the user. Cancel closes
+	// it, it must be called once the client is gone.
+	Subscribe(userID int64) (messages <-chan Message, cancel func())
+	// Close ends the streams open on this instance and the ones opened
+	// after, when it shuts down
+	Close()
+}
@@ -0,0 +1,132 @@
+package stream
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBroker()
+
+	first, cancelFirst := b.Subscribe(1)
+	second, cancelSecond := b.Subscribe(1)
+	other, cancelOther := b.Subscribe(2)
+	defer cancelSecond()
+	defer cancelOther()
+
+	m, err := NewMessage(FeedItem, map[string]int{"id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish(ctx, []int64{1}, m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ch := range []<-chan Message{first, second} {
+		select {
+		case got := <-ch:
+			if got.Event != FeedItem || string(got.Data) != `{"id":7}` {
+				t.Errorf("got %s %s", got.Event, got.Data)
+			}
+		default:
+			t.Error("every stream of the user should get the message")
+		}
+	}
+
+	select {
+	case <-other:
+		t.Error("other users shouldn't get the message")
+	default:
+	}
+
+	cancelFirst()
+	cancelFirst()
+
+	if _, ok := <-first; ok {
+		t.Error("cancelled streams should be closed")
+	}
+
+	if err := b.Publish(ctx, []int64{1}, m); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-second; got.Event != FeedItem {
+		t.Errorf("streams still open should get messages, got %s", got.Event)
+	}
+}
+
+func TestMemoryBrokerSlowStream(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBroker()
+
+	ch, cancel := b.Subscribe(1)
+	defer cancel()
+
+	for range BufferSize + 5 {
+		if err := b.Publish(ctx, []int64{1}, Message{Event: PostUpdated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(ch) != BufferSize {
+		t.Errorf("expected a full buffer of %d messages, got %d", BufferSize, len(ch))
+	}
+}
+
+func TestMemoryBrokerClose(t *testing.T) {
+	b := NewMemoryBroker()
+
+	open, cancel := b.Subscribe(1)
+	b.Close()
+	cancel()
+
+	if _, ok := <-open; ok {
+		t.Error("open streams should be closed")
+	}
+
+	late, cancel := b.Subscribe(1)
+	defer cancel()
+
+	if _, ok := <-late; ok {
+		t.Error("streams opened after closing should be closed")
+	}
+
+	if err := b.Publish(context.Background(), []int64{1}, Message{Event: FeedItem}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteSSE(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		want string
+	}{
+		{
+			name: "compact",
+			m:    Message{Event: Notification, Data: []byte(`{"id":1}`)},
+			want: "event: notification\ndata: {\"id\":1}\n\n",
+		},
+		{
+			name: "multiline",
+			m:    Message{Event: PostDeleted, Data: []byte("{\n\"id\":1\n}")},
+			want: "event: post.deleted\ndata: {\ndata: \"id\":1\ndata: }\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.m.WriteSSE(&b); err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != tt.want {
+				t.Errorf("got %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package syndication renders lists of posts as RSS, Atom and JSON Feed
+// documents for feed readers.
+package syndication
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = errors.New("feed format must be rss, atom or json")
+
+// MaxAge is how long readers and proxies may cache a feed without asking
+// whether it changed.
+const MaxAge = time.Minute * 5
+
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed is about, FeedURL the feed itself
+	Link    string
+	FeedURL string
+	Items   []Item
+}
+
+type Item struct {
+	// ID is a URL that stays the same for the life of the item
+	ID          string
+	URL         string
+	Title       string
+	ContentHTML string
+	AuthorName  string
+	AuthorURL   string
+	Published   time.Time
+	Tags        []string
+}
+
+// Updated returns when the newest item was published, or the zero time for
+// an empty feed.
+func (f *Feed) Updated() time.Time {
+	var updated time.Time
+	for _, item := range f.Items {
+		if item.Published.After(updated) {
+			updated = item.Published
+		}
+	}
+
+	return updated
+}
+
+// Encode renders the feed in format, returning the document and its
+// content type.
+func Encode(format string, f *Feed) ([]byte, string, error) {
+	switch format {
+	case FormatRSS:
+		body, err := encodeXML(rss(f))
+		return body, "application/rss+xml; charset=utf-8", err
+	case FormatAtom:
+		body, err := encodeXML(atom(f))
+		return body, "application/atom+xml; charset=utf-8", err
+	case FormatJSON:
+		body, err := json.Marshal(jsonFeed(f))
+		return body, "application/feed+json; charset=utf-8", err
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+}
+
+// Write renders the feed in format to w with caching headers, or only
+// tells the reader its copy is still current when its ETag matches. There is
+// no Last-Modified: posts can be edited or deleted without the feed getting
+// any newer, but never without changing the document.
+func Write(w http.ResponseWriter, r *http.Request, format string, f *Feed) error {
+	body, contentType, err := Encode(format, f)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(MaxAge.Seconds())))
+
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	h.Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(body)
+	return err
+}
+
+// notModified reports whether the reader's copy, named in If-None-Match, is
+// current.
+func notModified(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encodeXML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,224 @@
+package syndication
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var published = time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+func testFeed() *Feed {
+	return &Feed{
+		Title:       "gopher's posts",
+		Description: "Public posts of gopher",
+		Link:        "https://example.com/users/1",
+		FeedURL:     "https://api.example.com/v1/users/1/feed.atom",
+		Items: []Item{
+			{
+				ID:          "https://example.com/posts/2",
+				URL:         "https://example.com/posts/2",
+				Title:       "Generics & you",
+				ContentHTML: `<p>Type <strong>parameters</strong></p>`,
+				AuthorName:  "gopher",
+				AuthorURL:   "https://example.com/users/1",
+				Published:   published,
+				Tags:        []string{"go"},
+			},
+			{
+				ID:          "https://example.com/posts/1",
+				URL:         "https://example.com/posts/1",
+				ContentHTML: "<p>Hello</p>",
+				AuthorName:  "gopher",
+				Published:   published.Add(-time.Hour),
+			},
+		},
+	}
+}
+
+func TestEncode(t *testing.T) {
+	f := testFeed()
+
+	t.Run("rss", func(t *testing.T) {
+		body, contentType, err := Encode(FormatRSS, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(contentType, "application/rss+xml") {
+			t.Errorf("content type = %q", contentType)
+		}
+
+		var doc struct {
+			Channel struct {
+				Title string `xml:"title"`
+				Items []struct {
+					Title       string `xml:"title"`
+					GUID        string `xml:"guid"`
+					PubDate     string `xml:"pubDate"`
+					Description string `xml:"description"`
+				} `xml:"item"`
+			} `xml:"channel"`
+		}
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(doc.Channel.Items) != 2 {
+			t.Fatalf("got %d items, want 2", len(doc.Channel.Items))
+		}
+
+		item := doc.Channel.Items[0]
+		if item.Title != "Generics & you" || item.GUID != f.Items[0].ID ||
+			item.Description != f.Items[0].ContentHTML || item.PubDate != "Wed, 01 May 2024 12:30:00 +0000" {
+			t.Errorf("got item %+v", item)
+		}
+	})
+
+	t.Run("atom", func(t *testing.T) {
+		body, _, err := Encode(FormatAtom, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var doc struct {
+			XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+			ID      string   `xml:"id"`
+			Updated string   `xml:"updated"`
+			Entries []struct {
+				ID      string `xml:"id"`
+				Content struct {
+					Type  string `xml:"type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"content"`
+				Author struct {
+					Name string `xml:"name"`
+				} `xml:"author"`
+			} `xml:"entry"`
+		}
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.ID != f.FeedURL || doc.Updated != "2024-05-01T12:30:00Z" || len(doc.Entries) != 2 {
+			t.Fatalf("got %+v", doc)
+		}
+
+		entry := doc.Entries[0]
+		if entry.Content.Type != "html" || entry.Content.Value != f.Items[0].ContentHTML || entry.Author.Name != "gopher" {
+			t.Errorf("got entry %+v", entry)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		body, contentType, err := Encode(FormatJSON, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(contentType, "application/feed+json") {
+			t.Errorf("content type = %q", contentType)
+		}
+
+		var doc struct {
+			Version string `json:"version"`
+			Items   []struct {
+				ID            string   `json:"id"`
+				ContentHTML   string   `json:"content_html"`
+				DatePublished string   `json:"date_published"`
+				Tags          []string `json:"tags"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.Version != "https://jsonfeed.org/version/1.1" || len(doc.Items) != 2 {
+			t.Fatalf("got %+v", doc)
+		}
+
+		if item := doc.Items[0]; item.ContentHTML != f.Items[0].ContentHTML || item.DatePublished != "2024-05-01T12:30:00Z" || len(item.Tags) != 1 {
+			t.Errorf("got item %+v", item)
+		}
+	})
+
+	t.Run("empty json feed has an item list", func(t *testing.T) {
+		body, _, err := Encode(FormatJSON, &Feed{Title: "empty"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(body), `"items":[]`) {
+			t.Errorf("got %s", body)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, _, err := Encode("html", f); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("got %v, want %v", err, ErrUnknownFormat)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	f := testFeed()
+
+	rr := httptest.NewRecorder()
+	if err := Write(rr, httptest.NewRequest(http.MethodGet, "/feed.rss", nil), FormatRSS, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr.Code != http.StatusOK || rr.Body.Len() == 0 {
+		t.Fatalf("got %d with %d bytes", rr.Code, rr.Body.Len())
+	}
+
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	if got := rr.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("Last-Modified = %q, want none", got)
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"same etag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"etag among others", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"any etag", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"changed etag", map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"no conditions", nil, http.StatusOK},
+		// Deleting the newest post makes the feed older, dates can't tell
+		{"dates are ignored", map[string]string{"If-Modified-Since": "Thu, 02 May 2099 00:00:00 GMT"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/feed.rss", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			if err := Write(rr, r, FormatRSS, f); err != nil {
+				t.Fatal(err)
+			}
+
+			if rr.Code != tt.want {
+				t.Errorf("got %d, want %d", rr.Code, tt.want)
+			}
+
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Error("304 has a body")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package timeline
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// MemoryBackend keeps the timelines in process, standing in for Redis in
+// tests. Timelines don't expire.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	timelines map[string][]store.TimelineEntry
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		timelines: make(map[string][]store.TimelineEntry),
+	}
+}
+
+func (b *MemoryBackend) Seed(ctx context.Context, key string, entries []store.TimelineEntry, max int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timelines[key] = trim(slices.Clone(entries), max)
+	return nil
+}
+
+func (b *MemoryBackend) Push(ctx context.Context, keys []string, entry store.TimelineEntry, max int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		entries, ok := b.timelines[key]
+		if !ok {
+			continue
+		}
+
+		entries = slices.DeleteFunc(entries, func(e store.TimelineEntry) bool {
+			return e.PostID == entry.PostID
+		})
+
+		b.timelines[key] = trim(append(entries, entry), max)
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Remove(ctx context.Context, keys []string, entries ...store.TimelineEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		timeline, ok := b.timelines[key]
+		if !ok {
+			continue
+		}
+
+		b.timelines[key] = slices.DeleteFunc(timeline, func(e store.TimelineEntry) bool {
+			return slices.ContainsFunc(entries, func(r store.TimelineEntry) bool {
+				return r.PostID == e.PostID
+			})
+		})
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Before(ctx context.Context, key string, before int64, limit int) ([]store.TimelineEntry, int, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	timeline, ok := b.timelines[key]
+	if !ok {
+		return nil, 0, false, nil
+	}
+
+	var entries []store.TimelineEntry
+	for _, e := range timeline {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+
+		if before == 0 || e.PostID < before {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, len(timeline), true, nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.timelines, key)
+	return nil
+}
+
+// trim sorts entries newest first and keeps max of them.
+func trim(entries []store.TimelineEntry, max int) []store.TimelineEntry {
+	slices.SortFunc(entries, func(a, b store.TimelineEntry) int {
+		return cmp.Compare(b.PostID, a.PostID)
+	})
+
+	if len(entries) > max {
+		entries = entries[:max]
+	}
+
+	return entries
+}
@@ -0,0 +1,157 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// builtMember marks a timeline as built. Its score of 0 sorts it below every
+// post, so it is never trimmed nor read as one.
+const builtMember = "built"
+
+// pushScript adds a post to a timeline only if it's built, so timelines
+// that expired aren't brought back with just the newest posts in them.
+var pushScript = redis.NewScript(`
+	if redis.call('EXISTS', KEYS[1]) == 0 then
+		return 0
+	end
+	redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+	redis.call('ZREMRANGEBYRANK', KEYS[1], 1, -(tonumber(ARGV[3]) + 2))
+	return 1
+`)
+
+// RedisBackend keeps each timeline in a sorted set scored by post ID.
+type RedisBackend struct {
+	rdb *redis.Client
+}
+
+func NewRedisBackend(rdb *redis.Client) *RedisBackend {
+	return &RedisBackend{rdb: rdb}
+}
+
+func (b *RedisBackend) Seed(ctx context.Context, key string, entries []store.TimelineEntry, max int) error {
+	members := []redis.Z{{Score: 0, Member: builtMember}}
+	for _, e := range entries {
+		members = append(members, redis.Z{Score: float64(e.PostID), Member: encodeEntry(e)})
+	}
+
+	_, err := b.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByRank(ctx, key, 1, int64(-(max + 1)))
+		pipe.Expire(ctx, key, ExpTime)
+		return nil
+	})
+
+	return err
+}
+
+func (b *RedisBackend) Push(ctx context.Context, keys []string, entry store.TimelineEntry, max int) error {
+	if err := pushScript.Load(ctx, b.rdb).Err(); err != nil {
+		return err
+	}
+
+	_, err := b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pushScript.EvalSha(ctx, pipe, []string{key}, entry.PostID, encodeEntry(entry), max)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (b *RedisBackend) Remove(ctx context.Context, keys []string, entries ...store.TimelineEntry) error {
+	members := make([]any, len(entries))
+	for i, e := range entries {
+		members[i] = encodeEntry(e)
+	}
+
+	_, err := b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.ZRem(ctx, key, members...)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (b *RedisBackend) Before(ctx context.Context, key string, before int64, limit int) ([]store.TimelineEntry, int, bool, error) {
+	max := "+inf"
+	if before > 0 {
+		max = "(" + strconv.FormatInt(before, 10)
+	}
+
+	var members *redis.StringSliceCmd
+	var size *redis.IntCmd
+
+	_, err := b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		size = pipe.ZCard(ctx, key)
+		members = pipe.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{
+			Min:   "(0",
+			Max:   max,
+			Count: int64(limit),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	// The marker is counted along with the entries
+	if size.Val() == 0 {
+		return nil, 0, false, nil
+	}
+
+	entries := make([]store.TimelineEntry, 0, len(members.Val()))
+	for _, m := range members.Val() {
+		e, err := decodeEntry(m)
+		if err != nil {
+			return nil, 0, false, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, int(size.Val()) - 1, true, nil
+}
+
+func (b *RedisBackend) Delete(ctx context.Context, key string) error {
+	return b.rdb.Del(ctx, key).Err()
+}
+
+// encodeEntry turns an entry into a sorted set member. The whole entry is
+// kept in it so pages can be read without going back to the database.
+func encodeEntry(e store.TimelineEntry) string {
+	return fmt.Sprintf("%d:%d:%d", e.PostID, e.AuthorID, e.CreatedAt.Unix())
+}
+
+func decodeEntry(member string) (store.TimelineEntry, error) {
+	parts := strings.Split(member, ":")
+	if len(parts) != 3 {
+		return store.TimelineEntry{}, fmt.Errorf("malformed timeline entry %q", member)
+	}
+
+	var values [3]int64
+	for i, p := range parts {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return store.TimelineEntry{}, fmt.Errorf("malformed timeline entry %q", member)
+		}
+
+		values[i] = v
+	}
+
+	return store.TimelineEntry{
+		PostID:    values[0],
+		AuthorID:  values[1],
+		CreatedAt: time.Unix(values[2], 0).UTC(),
+	}, nil
+}
@@ -0,0 +1,170 @@
+// Package timeline keeps home timelines built on write: each post is pushed
+// to the timelines of its author's followers when it's created, so reading
+// a timeline doesn't have to gather posts from everyone the reader follows.
+//
+// Users with many followers are the exception. Pushing their posts would
+// mean thousands of writes per post, so they are left out of the timelines
+// and their posts are merged in when a timeline is read instead.
+package timeline
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+// ExpTime is how long a timeline is kept after it's built. Timelines of
+// users who stop coming back expire instead of being pushed to forever.
+const ExpTime = time.Hour * 24 * 7
+
+// Backend holds the timelines, each a list of entries ordered by post ID.
+// A timeline is built once it has been seeded, even with no entries, and
+// stops being built when it is deleted or expires.
+type Backend interface {
+	// Seed replaces the timeline at key with the newest max entries
+	Seed(ctx context.Context, key string, entries []store.TimelineEntry, max int) error
+	// Push adds entry to the timelines at keys that are built, dropping the
+	// oldest entries past max
+	Push(ctx context.Context, keys []string, entry store.TimelineEntry, max int) error
+	Remove(ctx context.Context, keys []string, entries ...store.TimelineEntry) error
+	// Before returns up to limit entries of the timeline at key with post
+	// IDs lower than before, newest first. A zero before starts from the
+	// newest, a zero limit returns them all. Size is how many entries the
+	// timeline holds.
+	Before(ctx context.Context, key string, before int64, limit int) (entries []store.TimelineEntry, size int, built bool, err error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	// MaxEntries is how many posts a timeline keeps, pages past them are
+	// read from the database
+	MaxEntries int
+	// CelebrityFollowers is how many followers a user needs for their posts
+	// to be read along with timelines instead of being pushed to them
+	CelebrityFollowers int
+}
+
+type Service struct {
+	backend Backend
+	cfg     Config
+}
+
+func New(backend Backend, cfg Config) *Service {
+	return &Service{backend: backend, cfg: cfg}
+}
+
+func (s *Service) Config() Config {
+	return s.cfg
+}
+
+// Push adds a new post to its author's timeline, and to their followers'
+// unless the author has too many.
+func (s *Service) Push(ctx context.Context, entry store.TimelineEntry, followerIDs []int64) error {
+	keys := []string{key(entry.AuthorID)}
+
+	if len(followerIDs) < s.cfg.CelebrityFollowers {
+		for _, id := range followerIDs {
+			keys = append(keys, key(id))
+		}
+	}
+
+	return s.backend.Push(ctx, keys, entry, s.cfg.MaxEntries)
+}
+
+// Remove takes a deleted post out of the timelines it was pushed to.
+func (s *Service) Remove(ctx context.Context, entry store.TimelineEntry, followerIDs []int64) error {
+	keys := []string{key(entry.AuthorID)}
+
+	for _, id := range followerIDs {
+		keys = append(keys, key(id))
+	}
+
+	return s.backend.Remove(ctx, keys, entry)
+}
+
+// RemoveAuthor takes the posts of authorID out of the user's timeline, when
+// the user stops following them or either blocks the other.
+func (s *Service) RemoveAuthor(ctx context.Context, userID, authorID int64) error {
+	entries, _, built, err := s.backend.Before(ctx, key(userID), 0, 0)
+	if err != nil || !built {
+		return err
+	}
+
+	var stale []store.TimelineEntry
+	for _, e := range entries {
+		if e.AuthorID == authorID {
+			stale = append(stale, e)
+		}
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	return s.backend.Remove(ctx, []string{key(userID)}, stale...)
+}
+
+// Invalidate drops the user's timeline so it is built again on the next
+// read, when who they follow changes in a way that adds posts to it.
+func (s *Service) Invalidate(ctx context.Context, userID int64) error {
+	return s.backend.Delete(ctx, key(userID))
+}
+
+// Seed builds the user's timeline from entries, newest first.
+func (s *Service) Seed(ctx context.Context, userID int64, entries []store.TimelineEntry) error {
+	return s.backend.Seed(ctx, key(userID), entries, s.cfg.MaxEntries)
+}
+
+// Page returns up to limit entries of the user's timeline older than the
+// before post ID, newest first. It isn't ok when the timeline isn't built,
+// or when the page runs past the entries it keeps.
+func (s *Service) Page(ctx context.Context, userID, before int64, limit int) ([]store.TimelineEntry, bool, error) {
+	entries, size, built, err := s.backend.Before(ctx, key(userID), before, limit)
+	if err != nil || !built {
+		return nil, false, err
+	}
+
+	// A short page from a full timeline may be missing posts that were
+	// dropped to make room
+	if len(entries) < limit && size >= s.cfg.MaxEntries {
+		return nil, false, nil
+	}
+
+	return entries, true, nil
+}
+
+// Merge combines lists of entries into one, newest first and without
+// duplicates, keeping up to limit of them.
+func Merge(limit int, lists ...[]store.TimelineEntry) []store.TimelineEntry {
+	seen := make(map[int64]bool)
+	var merged []store.TimelineEntry
+
+	for _, list := range lists {
+		for _, e := range list {
+			if seen[e.PostID] {
+				continue
+			}
+
+			seen[e.PostID] = true
+			merged = append(merged, e)
+		}
+	}
+
+	slices.SortFunc(merged, func(a, b store.TimelineEntry) int {
+		return cmp.Compare(b.PostID, a.PostID)
+	})
+
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+
+	return merged
+}
+
+func key(userID int64) string {
+	return fmt.Sprintf("timeline-%d", userID)
+}
@@ -0,0 +1,166 @@
+package timeline
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/qwerqy/social-api-go/internal/store"
+)
+
+func entry(postID, authorID int64) store.TimelineEntry {
+	return store.TimelineEntry{PostID: postID, AuthorID: authorID, CreatedAt: time.Unix(postID, 0)}
+}
+
+func postIDs(entries []store.TimelineEntry) []int64 {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.PostID
+	}
+
+	return ids
+}
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	return New(NewMemoryBackend(), Config{MaxEntries: 3, CelebrityFollowers: 3})
+}
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	for _, id := range []int64{1, 2, 3} {
+		if err := s.Seed(ctx, id, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// User 4 has no timeline yet, it gets built with the post when read
+	if err := s.Push(ctx, entry(10, 1), []int64{2, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Users with as many followers as the threshold aren't pushed to them
+	if err := s.Push(ctx, entry(11, 2), []int64{1, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	for userID, want := range map[int64][]int64{
+		1: {10},
+		2: {11, 10},
+		3: {},
+	} {
+		entries, ok, err := s.Page(ctx, userID, 0, 10)
+		if err != nil || !ok {
+			t.Fatalf("Page(%d) = %v, %v", userID, ok, err)
+		}
+
+		if got := postIDs(entries); !slices.Equal(got, want) {
+			t.Errorf("timeline of %d = %v, want %v", userID, got, want)
+		}
+	}
+
+	if _, ok, _ := s.Page(ctx, 4, 0, 10); ok {
+		t.Error("timeline of 4 was built by a push")
+	}
+}
+
+func TestPage(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	if err := s.Seed(ctx, 1, []store.TimelineEntry{entry(5, 2), entry(4, 2), entry(3, 2), entry(2, 2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, ok, err := s.Page(ctx, 1, 0, 2)
+	if err != nil || !ok {
+		t.Fatalf("Page = %v, %v", ok, err)
+	}
+
+	if got := postIDs(entries); !slices.Equal(got, []int64{5, 4}) {
+		t.Errorf("first page = %v, want [5 4]", got)
+	}
+
+	// Only the newest 3 are kept, post 2 has to be read from the database
+	if _, ok, _ := s.Page(ctx, 1, 4, 2); ok {
+		t.Error("page past the kept entries was ok")
+	}
+
+	if err := s.Push(ctx, entry(6, 2), []int64{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, _ = s.Page(ctx, 1, 0, 3)
+	if got := postIDs(entries); !slices.Equal(got, []int64{6, 5, 4}) {
+		t.Errorf("after push = %v, want [6 5 4]", got)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	if err := s.Seed(ctx, 1, []store.TimelineEntry{entry(3, 2), entry(2, 3), entry(1, 2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Remove(ctx, entry(2, 3), []int64{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, _ := s.Page(ctx, 1, 0, 10)
+	if got := postIDs(entries); !slices.Equal(got, []int64{3, 1}) {
+		t.Errorf("after remove = %v, want [3 1]", got)
+	}
+
+	if err := s.RemoveAuthor(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, ok, _ := s.Page(ctx, 1, 0, 10)
+	if len(entries) != 0 || !ok {
+		t.Errorf("after removing the author = %v, %v, want an empty built timeline", postIDs(entries), ok)
+	}
+
+	if err := s.Invalidate(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := s.Page(ctx, 1, 0, 10); ok {
+		t.Error("invalidated timeline is still built")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	got := Merge(4,
+		[]store.TimelineEntry{entry(9, 1), entry(5, 1), entry(2, 1)},
+		[]store.TimelineEntry{entry(7, 2), entry(5, 1), entry(1, 2)},
+	)
+
+	if ids := postIDs(got); !slices.Equal(ids, []int64{9, 7, 5, 2}) {
+		t.Errorf("Merge = %v, want [9 7 5 2]", ids)
+	}
+}
+
+func TestEncodeEntry(t *testing.T) {
+	want := store.TimelineEntry{PostID: 42, AuthorID: 7, CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	got, err := decodeEntry(encodeEntry(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, member := range []string{builtMember, "1:2", "a:b:c"} {
+		if _, err := decodeEntry(member); err == nil {
+			t.Errorf("decodeEntry(%q) didn't fail", member)
+		}
+	}
+}